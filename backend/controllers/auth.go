@@ -3,6 +3,7 @@ package controllers
 import (
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"net/http"
 	"os"
 	"pet-food-ecommerce/config"
@@ -12,6 +13,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 // RegisterRequest represents the request body for user registration
@@ -21,6 +23,8 @@ type RegisterRequest struct {
 	Name     string `json:"name" binding:"required" example:"John Doe"`
 	Phone    string `json:"phone" example:"0812345678"`
 	Address  string `json:"address" example:"123 Main St, Bangkok"`
+	// Optional friend's referral code for a first-order discount
+	ReferralCode string `json:"referral_code" example:"K7QX2MPA"`
 }
 
 // LoginRequest represents the request body for user login
@@ -62,7 +66,7 @@ type ResetPasswordRequest struct {
 // @Produce json
 // @Param request body RegisterRequest true "Registration details"
 // @Success 201 {object} map[string]interface{} "User registered successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request - validation error"
+// @Failure 400 {object} map[string]interface{} "Bad request - validation error or invalid referral code"
 // @Failure 409 {object} map[string]interface{} "Email already registered"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /auth/register [post]
@@ -97,19 +101,40 @@ func Register(c *gin.Context) {
 		Role:         "customer",
 	}
 
-	if err := config.GetDB().Create(&user).Error; err != nil {
+	var referral *models.Referral
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&user).Error; err != nil {
+			return err
+		}
+		if req.ReferralCode == "" {
+			return nil
+		}
+		referral, err = attachReferral(tx, &user, req.ReferralCode)
+		return err
+	})
+	if errors.Is(err, errReferralNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"message": "User registered successfully",
 		"user": gin.H{
-			"id":    user.ID,
-			"email": user.Email,
-			"name":  user.Name,
+			"id":            user.ID,
+			"email":         user.Email,
+			"name":          user.Name,
+			"referral_code": user.ReferralCode,
 		},
-	})
+	}
+	if referral != nil {
+		response["referee_discount"] = loadReferralSettings().RefereeDiscount
+	}
+
+	c.JSON(http.StatusCreated, response)
 }
 
 // Login godoc
@@ -196,12 +221,13 @@ func GetProfile(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"user": gin.H{
-			"id":      user.ID,
-			"email":   user.Email,
-			"name":    user.Name,
-			"phone":   user.Phone,
-			"address": user.Address,
-			"role":    user.Role,
+			"id":            user.ID,
+			"email":         user.Email,
+			"name":          user.Name,
+			"phone":         user.Phone,
+			"address":       user.Address,
+			"role":          user.Role,
+			"referral_code": user.ReferralCode,
 		},
 	})
 }
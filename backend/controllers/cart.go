@@ -1,20 +1,32 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"pet-food-ecommerce/config"
 	"pet-food-ecommerce/models"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
+var errCartStock = errors.New("Insufficient stock")
+
 // AddToCartRequest represents the request body for adding an item to cart
 type AddToCartRequest struct {
 	ProductID string `json:"product_id" binding:"required" example:"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"`
+	VariantID string `json:"variant_id" example:"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"` // optional for single-variant products
 	Quantity  int    `json:"quantity" binding:"required,min=1" example:"2"`
 }
 
+// ApplyCouponRequest represents the request body for applying a coupon to the cart
+type ApplyCouponRequest struct {
+	Code string `json:"code" binding:"required" example:"WELCOME10"`
+}
+
 // UpdateCartRequest represents the request body for updating cart item quantity
 type UpdateCartRequest struct {
 	Quantity int `json:"quantity" binding:"required,min=1" example:"3"`
@@ -22,11 +34,12 @@ type UpdateCartRequest struct {
 
 // GetCart godoc
 // @Summary Get user's cart
-// @Description Get all items in the authenticated user's shopping cart with total
+// @Description Get all items in the authenticated user's shopping cart with total. Pass redeem_points to preview a points discount.
 // @Tags Cart
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param redeem_points query int false "Loyalty points to preview as a discount"
 // @Success 200 {object} map[string]interface{} "Cart items and total"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
@@ -35,26 +48,74 @@ func GetCart(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	var cartItems []models.Cart
-	if err := config.GetDB().Preload("Product").Preload("Product.Category").Where("user_id = ?", userID).Find(&cartItems).Error; err != nil {
+	if err := config.GetDB().Preload("Product").Preload("Product.Category").Preload("Variant").Where("user_id = ?", userID).Find(&cartItems).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
 		return
 	}
 
 	// Calculate total
-	var total float64
-	for _, item := range cartItems {
-		total += item.Product.Price * float64(item.Quantity)
+	var opts pricingOptions
+	if points := c.Query("redeem_points"); points != "" {
+		redeem, err := strconv.Atoi(points)
+		if err != nil || redeem < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "redeem_points must be a non-negative integer"})
+			return
+		}
+		opts.RedeemPoints = redeem
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"cart_items": cartItems,
-		"total":      total,
-	})
+	totals, err := priceCart(config.GetDB(), userID, cartItems, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := applySalePricesToCart(config.GetDB(), cartItems); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
+		return
+	}
+
+	c.JSON(http.StatusOK, cartResponse(cartItems, totals))
+}
+
+// cartResponse renders cart items together with their price breakdown
+func cartResponse(cartItems []models.Cart, totals cartTotals) gin.H {
+	response := gin.H{
+		"cart_items":         cartItems,
+		"lines":              totals.Lines,
+		"currency":           totals.Currency,
+		"subtotal":           totals.Subtotal,
+		"promotion_discount": totals.PromotionDiscount,
+		"discount":           totals.Discount,
+		"shipping_fee":       totals.ShippingFee,
+		"tax_included":       totals.Tax,
+		"total":              totals.Total,
+		"member_tier":        totals.MemberTier,
+	}
+
+	if totals.Coupon != nil {
+		response["coupon"] = gin.H{
+			"code":     totals.Coupon.Code,
+			"type":     totals.Coupon.Type,
+			"discount": totals.CouponDiscount,
+		}
+	}
+	if totals.CouponError != "" {
+		response["coupon_error"] = totals.CouponError
+	}
+	if totals.ReferralDiscount > 0 {
+		response["referral_discount"] = totals.ReferralDiscount
+	}
+	if totals.PointsRedeemed > 0 {
+		response["points_redeemed"] = totals.PointsRedeemed
+		response["points_discount"] = totals.PointsDiscount
+	}
+
+	return response
 }
 
 // AddToCart godoc
 // @Summary Add item to cart
-// @Description Add a product to the authenticated user's shopping cart
+// @Description Add a product variant to the authenticated user's shopping cart. variant_id may be left out for products with a single variant.
 // @Tags Cart
 // @Accept json
 // @Produce json
@@ -64,11 +125,12 @@ func GetCart(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "Cart updated (item already exists)"
 // @Failure 400 {object} map[string]interface{} "Bad request or insufficient stock"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Prescription diet without an approved prescription"
 // @Failure 404 {object} map[string]interface{} "Product not found"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /cart [post]
 func AddToCart(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID, _ := uuid.Parse(c.GetString("user_id"))
 
 	var req AddToCartRequest
 
@@ -77,59 +139,99 @@ func AddToCart(c *gin.Context) {
 		return
 	}
 
-	// Check if product exists and has enough stock
-	var product models.Product
-	if err := config.GetDB().Where("id = ?", req.ProductID).First(&product).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
-		return
-	}
-
-	if product.Stock < req.Quantity {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient stock"})
+	cartItem, created, err := addCartItem(config.GetDB(), userID, req.ProductID, req.VariantID, req.Quantity)
+	if err != nil {
+		cartItemError(c, err)
 		return
 	}
 
-	// Check if item already in cart
-	var existingCart models.Cart
-	err := config.GetDB().Where("user_id = ? AND product_id = ?", userID, req.ProductID).First(&existingCart).Error
-
-	if err == nil {
-		// Update quantity
-		existingCart.Quantity += req.Quantity
-		if err := config.GetDB().Save(&existingCart).Error; err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart"})
-			return
-		}
-
-		config.GetDB().Preload("Product").Preload("Product.Category").First(&existingCart, existingCart.ID)
+	if !created {
 		c.JSON(http.StatusOK, gin.H{
 			"message":   "Cart updated successfully",
-			"cart_item": existingCart,
+			"cart_item": cartItem,
 		})
 		return
 	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Added to cart successfully",
+		"cart_item": cartItem,
+	})
+}
 
-	// Create new cart item
-	userUUID, _ := uuid.Parse(userID)
-	productUUID, _ := uuid.Parse(req.ProductID)
+// addCartItem puts a quantity of a product variant in the user's cart, adding
+// to the line already there; created reports whether a new line was made
+func addCartItem(db *gorm.DB, userID uuid.UUID, productID, variantID string, quantity int) (models.Cart, bool, error) {
+	var cartItem models.Cart
 
-	cartItem := models.Cart{
-		UserID:    userUUID,
-		ProductID: productUUID,
-		Quantity:  req.Quantity,
+	product, err := cartProduct(db, userID, productID)
+	if err != nil {
+		return cartItem, false, err
 	}
 
-	if err := config.GetDB().Create(&cartItem).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add to cart"})
-		return
+	variant, err := resolveVariant(db, product.ID, variantID)
+	if err != nil {
+		return cartItem, false, err
 	}
 
-	config.GetDB().Preload("Product").Preload("Product.Category").First(&cartItem, cartItem.ID)
+	if variant.Stock < quantity {
+		return cartItem, false, errCartStock
+	}
 
-	c.JSON(http.StatusCreated, gin.H{
-		"message":   "Added to cart successfully",
-		"cart_item": cartItem,
-	})
+	// Check if item already in cart
+	err = db.Where("user_id = ? AND variant_id = ?", userID, variant.ID).First(&cartItem).Error
+	created := errors.Is(err, gorm.ErrRecordNotFound)
+	switch {
+	case created:
+		cartItem = models.Cart{
+			UserID:    userID,
+			ProductID: product.ID,
+			VariantID: &variant.ID,
+			Quantity:  quantity,
+		}
+		err = db.Create(&cartItem).Error
+	case err == nil:
+		if variant.Stock < cartItem.Quantity+quantity {
+			return cartItem, false, errCartStock
+		}
+		cartItem.Quantity += quantity
+		err = db.Save(&cartItem).Error
+	}
+	if err != nil {
+		return cartItem, false, err
+	}
+
+	db.Preload("Product").Preload("Product.Category").Preload("Variant").First(&cartItem, "id = ?", cartItem.ID)
+	return cartItem, created, nil
+}
+
+// cartProduct loads a product the user may put in or keep adding to their cart:
+// on sale now, and for veterinary diets covered by an approved prescription
+func cartProduct(db *gorm.DB, userID uuid.UUID, productID string) (models.Product, error) {
+	var product models.Product
+	if _, err := uuid.Parse(productID); err != nil {
+		return product, errProductNotFound
+	}
+	if err := db.Scopes(purchasableProducts).Where("id = ?", productID).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return product, errProductNotFound
+		}
+		return product, err
+	}
+	return product, checkPrescription(db, userID, &product)
+}
+
+// cartItemError answers a failed cart change
+func cartItemError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errProductNotFound), errors.Is(err, errVariantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errVariantRequired), errors.Is(err, errCartStock):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errPrescriptionRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update cart"})
+	}
 }
 
 // UpdateCartItem godoc
@@ -144,7 +246,8 @@ func AddToCart(c *gin.Context) {
 // @Success 200 {object} map[string]interface{} "Cart updated successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request or insufficient stock"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
-// @Failure 404 {object} map[string]interface{} "Cart item not found"
+// @Failure 403 {object} map[string]interface{} "Prescription diet without an approved prescription"
+// @Failure 404 {object} map[string]interface{} "Cart item or product not found"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /cart/{id} [put]
 func UpdateCartItem(c *gin.Context) {
@@ -164,14 +267,20 @@ func UpdateCartItem(c *gin.Context) {
 		return
 	}
 
+	// The product may have left the catalog or its prescription lapsed since it was added
+	if _, err := cartProduct(config.GetDB(), cart.UserID, cart.ProductID.String()); err != nil {
+		cartItemError(c, err)
+		return
+	}
+
 	// Check stock
-	var product models.Product
-	if err := config.GetDB().Where("id = ?", cart.ProductID).First(&product).Error; err != nil {
+	var variant models.ProductVariant
+	if err := config.GetDB().Where("id = ?", cart.VariantID).First(&variant).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
 
-	if product.Stock < req.Quantity {
+	if variant.Stock < req.Quantity {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient stock"})
 		return
 	}
@@ -182,7 +291,7 @@ func UpdateCartItem(c *gin.Context) {
 		return
 	}
 
-	config.GetDB().Preload("Product").Preload("Product.Category").First(&cart, cart.ID)
+	config.GetDB().Preload("Product").Preload("Product.Category").Preload("Variant").First(&cart, cart.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message":   "Cart updated successfully",
@@ -239,6 +348,107 @@ func ClearCart(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
 		return
 	}
+	config.GetDB().Where("user_id = ?", userID).Delete(&models.CartCoupon{})
 
 	c.JSON(http.StatusOK, gin.H{"message": "Cart cleared successfully"})
 }
+
+// ApplyCoupon godoc
+// @Summary Apply a coupon to the cart
+// @Description Validate a promo code against the current cart and attach it. The discount is re-checked at checkout.
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ApplyCouponRequest true "Coupon code"
+// @Success 200 {object} map[string]interface{} "Coupon applied with updated cart totals"
+// @Failure 400 {object} map[string]interface{} "Coupon not applicable"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Coupon not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /cart/coupon [post]
+func ApplyCoupon(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req ApplyCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var coupon models.Coupon
+	if err := config.GetDB().Where("code = ?", strings.ToUpper(strings.TrimSpace(req.Code))).First(&coupon).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	var cartItems []models.Cart
+	if err := config.GetDB().Preload("Product").Preload("Product.Category").Preload("Variant").Where("user_id = ?", userID).Find(&cartItems).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
+		return
+	}
+
+	if len(cartItems) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cart is empty"})
+		return
+	}
+
+	userUUID, _ := uuid.Parse(userID)
+	applied := models.CartCoupon{UserID: userUUID, CouponID: coupon.ID}
+
+	tx := config.GetDB().Begin()
+	if err := tx.Save(&applied).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply coupon"})
+		return
+	}
+
+	totals, err := priceCart(tx, userID, cartItems, pricingOptions{})
+	if err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if totals.CouponError != "" {
+		tx.Rollback()
+		c.JSON(http.StatusBadRequest, gin.H{"error": totals.CouponError})
+		return
+	}
+
+	if err := applySalePricesToCart(tx, cartItems); err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply coupon"})
+		return
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply coupon"})
+		return
+	}
+
+	response := cartResponse(cartItems, totals)
+	response["message"] = "Coupon applied successfully"
+	c.JSON(http.StatusOK, response)
+}
+
+// RemoveCoupon godoc
+// @Summary Remove the coupon from the cart
+// @Description Detach the currently applied coupon from the authenticated user's cart
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Coupon removed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /cart/coupon [delete]
+func RemoveCoupon(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	if err := config.GetDB().Where("user_id = ?", userID).Delete(&models.CartCoupon{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon removed successfully"})
+}
@@ -0,0 +1,49 @@
+//go:build cgo
+
+package controllers
+
+import (
+	"net/http"
+	"pet-food-ecommerce/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestUpdateCartItemRechecksPrescriptionAndCatalog(t *testing.T) {
+	db := newTestDB(t)
+	customer := createTestUser(t, db)
+	product := createTestProduct(t, db, 120000)
+	db.Model(&product).Update("prescription", true)
+	validUntil := time.Now().AddDate(0, 1, 0)
+	prescription := models.Prescription{
+		UserID: customer.ID, PetID: uuid.New(), VetName: "Dr. Vet", FileName: "rx.pdf", FilePath: "rx.pdf",
+		ContentType: "application/pdf", Status: models.PrescriptionApproved, ValidUntil: &validUntil,
+	}
+	db.Create(&prescription)
+
+	item, _, err := addCartItem(db, customer.ID, product.ID.String(), "", 1)
+	if err != nil {
+		t.Fatalf("add with a valid prescription: %v", err)
+	}
+	path := "/cart/" + item.ID.String()
+	update := func() (int, map[string]interface{}) {
+		return serveTest(t, UpdateCartItem, http.MethodPut, "/cart/:id", path, customer, map[string]int{"quantity": 2})
+	}
+
+	if code, out := update(); code != http.StatusOK {
+		t.Fatalf("raise with a valid prescription: status %d %v, want 200", code, out)
+	}
+
+	db.Model(&prescription).Update("status", models.PrescriptionRejected)
+	if code, out := update(); code != http.StatusForbidden {
+		t.Errorf("raise after the prescription was revoked: status %d %v, want 403", code, out)
+	}
+
+	db.Model(&prescription).Update("status", models.PrescriptionApproved)
+	db.Model(&product).Update("status", models.ProductDraft)
+	if code, out := update(); code != http.StatusNotFound {
+		t.Errorf("raise after the product was unpublished: status %d %v, want 404", code, out)
+	}
+}
@@ -1,23 +1,241 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"pet-food-ecommerce/config"
 	"pet-food-ecommerce/models"
+	"pet-food-ecommerce/search"
+	"slices"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// CategoryInput represents the request body for creating/updating a category
+var (
+	errCategoryParent = errors.New("Parent category not found")
+	errCategoryCycle  = errors.New("parent_id cannot be the category itself or one of its subcategories")
+	errCategorySlug   = errors.New("slug may only contain lower-case letters, digits and hyphens")
+	errSlugTaken      = errors.New("slug is already used by another category")
+	errCategoryName   = errors.New("name cannot be empty")
+)
+
+// CategoryInput represents the request body for creating a category
 type CategoryInput struct {
 	Name        string `json:"name" binding:"required" example:"อาหารสุนัข"`
+	ParentID    string `json:"parent_id" binding:"omitempty,uuid" example:"11111111-1111-1111-1111-111111111111"` // empty for a top-level category
+	Slug        string `json:"slug" example:"dog-food"`                                                           // made from the name when empty
+	Position    int    `json:"position" binding:"min=0" example:"0"`                                              // order among siblings, lowest first
 	Description string `json:"description" example:"อาหารคุณภาพสูงสำหรับสุนัขทุกวัย"`
 	ImageURL    string `json:"image_url" example:"https://example.com/category.jpg"`
 }
 
+// CategoryPatch represents the request body for updating a category. Fields
+// left out keep their current values.
+type CategoryPatch struct {
+	Name        *string `json:"name" binding:"omitnil,min=1" example:"อาหารสุนัข"`
+	ParentID    *string `json:"parent_id" example:"11111111-1111-1111-1111-111111111111"` // "" moves it to the top level
+	Slug        *string `json:"slug" example:"dog-food"`                                  // "" makes one from the name
+	Position    *int    `json:"position" binding:"omitnil,min=0" example:"0"`
+	Description *string `json:"description" example:"อาหารคุณภาพสูงสำหรับสุนัขทุกวัย"`
+	ImageURL    *string `json:"image_url" example:"https://example.com/category.jpg"`
+}
+
+// toCategory builds the category the input describes
+func (input *CategoryInput) toCategory() models.Category {
+	category := models.Category{
+		Name:        input.Name,
+		Slug:        input.Slug,
+		Position:    input.Position,
+		Description: input.Description,
+		ImageURL:    input.ImageURL,
+	}
+	if parentID, err := uuid.Parse(input.ParentID); err == nil {
+		category.ParentID = &parentID
+	}
+	return category
+}
+
+// applyTo copies the fields that were sent onto category
+func (patch *CategoryPatch) applyTo(category *models.Category) error {
+	setIfSent(&category.Name, patch.Name)
+	setIfSent(&category.Slug, patch.Slug)
+	setIfSent(&category.Position, patch.Position)
+	setIfSent(&category.Description, patch.Description)
+	setIfSent(&category.ImageURL, patch.ImageURL)
+	if patch.ParentID != nil {
+		category.ParentID = nil
+		if *patch.ParentID != "" {
+			parentID, err := uuid.Parse(*patch.ParentID)
+			if err != nil {
+				return fieldErrors{"parent_id": "parent_id must be a UUID"}
+			}
+			category.ParentID = &parentID
+		}
+	}
+	return nil
+}
+
+// categoryTree holds every category, indexed by ID and by parent
+type categoryTree struct {
+	byID     map[uuid.UUID]*models.Category
+	children map[uuid.UUID][]*models.Category // top-level categories under uuid.Nil
+}
+
+// categoryNode is a category with its subcategories, for the tree endpoint
+type categoryNode struct {
+	models.Category
+	Children []categoryNode `json:"children"`
+}
+
+// loadCategoryTree reads all categories, siblings in display order. Shops have
+// few enough categories that walking the tree in memory beats recursive SQL.
+func loadCategoryTree(db *gorm.DB) (*categoryTree, error) {
+	var categories []models.Category
+	if err := db.Order("position ASC, name ASC").Find(&categories).Error; err != nil {
+		return nil, err
+	}
+
+	tree := &categoryTree{
+		byID:     make(map[uuid.UUID]*models.Category, len(categories)),
+		children: make(map[uuid.UUID][]*models.Category),
+	}
+	for i := range categories {
+		tree.byID[categories[i].ID] = &categories[i]
+	}
+	for i := range categories {
+		parent := uuid.Nil
+		if categories[i].ParentID != nil && tree.byID[*categories[i].ParentID] != nil {
+			parent = *categories[i].ParentID
+		}
+		tree.children[parent] = append(tree.children[parent], &categories[i])
+	}
+	return tree, nil
+}
+
+// breadcrumbs is the path from the top-level category down to id
+func (t *categoryTree) breadcrumbs(id uuid.UUID) []models.Category {
+	path := []models.Category{}
+	seen := make(map[uuid.UUID]bool)
+	for category := t.byID[id]; category != nil && !seen[category.ID]; {
+		seen[category.ID] = true
+		path = append(path, *category)
+		if category.ParentID == nil {
+			break
+		}
+		category = t.byID[*category.ParentID]
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// ancestors lists id and the IDs of every category above it
+func (t *categoryTree) ancestors(id uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{id}
+	for _, category := range t.breadcrumbs(id) {
+		if category.ID != id {
+			ids = append(ids, category.ID)
+		}
+	}
+	return ids
+}
+
+// subtree lists id and the IDs of every category below it
+func (t *categoryTree) subtree(id uuid.UUID) []uuid.UUID {
+	ids := []uuid.UUID{id}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range t.children[ids[i]] {
+			if !slices.Contains(ids, child.ID) {
+				ids = append(ids, child.ID)
+			}
+		}
+	}
+	return ids
+}
+
+// nodes builds the subtrees of the categories under parent
+func (t *categoryTree) nodes(parent uuid.UUID) []categoryNode {
+	nodes := make([]categoryNode, 0, len(t.children[parent]))
+	for _, category := range t.children[parent] {
+		nodes = append(nodes, categoryNode{Category: *category, Children: t.nodes(category.ID)})
+	}
+	return nodes
+}
+
+// categorySubtree lists a category and all its descendants, for filtering products
+func categorySubtree(db *gorm.DB, id uuid.UUID) ([]uuid.UUID, error) {
+	tree, err := loadCategoryTree(db)
+	if err != nil {
+		return nil, err
+	}
+	return tree.subtree(id), nil
+}
+
+// normalizeCategory checks the parent and slug an admin sent, making a slug
+// from the name when none was given
+func normalizeCategory(db *gorm.DB, category *models.Category) error {
+	category.Name = strings.TrimSpace(category.Name)
+	if category.Name == "" {
+		return errCategoryName
+	}
+
+	if category.ParentID != nil {
+		tree, err := loadCategoryTree(db)
+		if err != nil {
+			return err
+		}
+		if tree.byID[*category.ParentID] == nil {
+			return errCategoryParent
+		}
+		if category.ID != uuid.Nil && slices.Contains(tree.subtree(category.ID), *category.ParentID) {
+			return errCategoryCycle
+		}
+	}
+
+	slug := strings.ToLower(strings.TrimSpace(category.Slug))
+	if slug == "" {
+		unique, err := models.UniqueCategorySlug(db, models.Slugify(category.Name), category.ID)
+		if err != nil {
+			return err
+		}
+		category.Slug = unique
+		return nil
+	}
+	if models.Slugify(slug) != slug {
+		return errCategorySlug
+	}
+	var taken int64
+	if err := db.Unscoped().Model(&models.Category{}).Where("slug = ? AND id <> ?", slug, category.ID).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return errSlugTaken
+	}
+	category.Slug = slug
+	return nil
+}
+
+// categoryInputError answers a failed normalizeCategory, naming the field at fault
+func categoryInputError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errCategoryName):
+		respondInvalid(c, fieldErrors{"name": err.Error()})
+	case errors.Is(err, errCategoryParent), errors.Is(err, errCategoryCycle):
+		respondInvalid(c, fieldErrors{"parent_id": err.Error()})
+	case errors.Is(err, errCategorySlug):
+		respondInvalid(c, fieldErrors{"slug": err.Error()})
+	case errors.Is(err, errSlugTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "fields": fieldErrors{"slug": err.Error()}})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save category"})
+	}
+}
+
 // GetCategories godoc
 // @Summary Get all categories
-// @Description Get a list of all product categories
+// @Description Get a flat list of all product categories, siblings in display order. Use parent_id to rebuild the tree, or GET /categories/tree.
 // @Tags Categories
 // @Accept json
 // @Produce json
@@ -26,7 +244,7 @@ type CategoryInput struct {
 // @Router /categories [get]
 func GetCategories(c *gin.Context) {
 	var categories []models.Category
-	if err := config.GetDB().Find(&categories).Error; err != nil {
+	if err := config.GetDB().Order("position ASC, name ASC").Find(&categories).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
 		return
 	}
@@ -34,50 +252,133 @@ func GetCategories(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"categories": categories})
 }
 
+// GetCategoryTree godoc
+// @Summary Get the category tree
+// @Description Get all categories nested under their parents, e.g. Dog > Dry Food > Puppy
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "Top-level categories with their children"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /categories/tree [get]
+func GetCategoryTree(c *gin.Context) {
+	tree, err := loadCategoryTree(config.GetDB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": tree.nodes(uuid.Nil)})
+}
+
+// findCategory looks a category up by ID or by slug
+func findCategory(tree *categoryTree, idOrSlug string) *models.Category {
+	if id, err := uuid.Parse(idOrSlug); err == nil {
+		return tree.byID[id]
+	}
+	for _, category := range tree.byID {
+		if category.Slug == idOrSlug {
+			return category
+		}
+	}
+	return nil
+}
+
 // GetCategory godoc
-// @Summary Get a category by ID
-// @Description Get detailed information about a specific category
+// @Summary Get a category by ID or slug
+// @Description Get a category with its breadcrumb path and direct subcategories
 // @Tags Categories
 // @Accept json
 // @Produce json
-// @Param id path string true "Category ID"
+// @Param id path string true "Category ID or slug"
 // @Success 200 {object} map[string]interface{} "Category details"
 // @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /categories/{id} [get]
 func GetCategory(c *gin.Context) {
-	categoryID := c.Param("id")
+	tree, err := loadCategoryTree(config.GetDB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return
+	}
 
-	var category models.Category
-	if err := config.GetDB().Where("id = ?", categoryID).First(&category).Error; err != nil {
+	category := findCategory(tree, c.Param("id"))
+	if category == nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
 		return
 	}
+	if category.Images, err = categoryImages.gallery(config.GetDB(), category.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"category": category})
+	children := make([]models.Category, 0, len(tree.children[category.ID]))
+	for _, child := range tree.children[category.ID] {
+		children = append(children, *child)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"category":    category,
+		"breadcrumbs": tree.breadcrumbs(category.ID),
+		"children":    children,
+	})
+}
+
+// GetCategoryBreadcrumbs godoc
+// @Summary Get a category's breadcrumbs
+// @Description Get the path from the top-level category down to this one
+// @Tags Categories
+// @Accept json
+// @Produce json
+// @Param id path string true "Category ID or slug"
+// @Success 200 {object} map[string]interface{} "Breadcrumb path, top-level first"
+// @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /categories/{id}/breadcrumbs [get]
+func GetCategoryBreadcrumbs(c *gin.Context) {
+	tree, err := loadCategoryTree(config.GetDB())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch category"})
+		return
+	}
+
+	category := findCategory(tree, c.Param("id"))
+	if category == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"breadcrumbs": tree.breadcrumbs(category.ID)})
 }
 
 // CreateCategory godoc
 // @Summary Create a new category (Admin only)
-// @Description Create a new product category
+// @Description Create a new product category, optionally under a parent. Unknown fields are rejected, and validation errors list each bad field under fields.
 // @Tags Admin - Categories
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param category body CategoryInput true "Category data"
 // @Success 201 {object} map[string]interface{} "Category created successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 400 {object} map[string]interface{} "Validation failed, with an error per field"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 409 {object} map[string]interface{} "Slug already in use"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /admin/categories [post]
 func CreateCategory(c *gin.Context) {
-	var category models.Category
-	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var input CategoryInput
+	if err := bindStrictJSON(c, &input); err != nil {
+		respondInvalid(c, err)
+		return
+	}
+	category := input.toCategory()
+	if err := normalizeCategory(config.GetDB(), &category); err != nil {
+		categoryInputError(c, err)
 		return
 	}
 
-	if err := config.GetDB().Create(&category).Error; err != nil {
+	if err := config.GetDB().Omit("Images").Create(&category).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create category"})
 		return
 	}
@@ -90,20 +391,21 @@ func CreateCategory(c *gin.Context) {
 
 // UpdateCategory godoc
 // @Summary Update a category (Admin only)
-// @Description Update an existing category's information
+// @Description Update some of a category's fields; fields left out keep their values. Moving it under another parent takes its subcategories along. Unknown fields are rejected, and validation errors list each bad field under fields. PUT is accepted as well as PATCH.
 // @Tags Admin - Categories
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Category ID"
-// @Param category body CategoryInput true "Category data"
+// @Param category body CategoryPatch true "Fields to change"
 // @Success 200 {object} map[string]interface{} "Category updated successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 400 {object} map[string]interface{} "Validation failed, with an error per field"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
 // @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 409 {object} map[string]interface{} "Slug already in use"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
-// @Router /admin/categories/{id} [put]
+// @Router /admin/categories/{id} [patch]
 func UpdateCategory(c *gin.Context) {
 	categoryID := c.Param("id")
 
@@ -113,12 +415,28 @@ func UpdateCategory(c *gin.Context) {
 		return
 	}
 
-	if err := c.ShouldBindJSON(&category); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var patch CategoryPatch
+	if err := bindStrictJSON(c, &patch); err != nil {
+		respondInvalid(c, err)
+		return
+	}
+	if err := patch.applyTo(&category); err != nil {
+		respondInvalid(c, err)
+		return
+	}
+	if err := normalizeCategory(config.GetDB(), &category); err != nil {
+		categoryInputError(c, err)
 		return
 	}
 
-	if err := config.GetDB().Save(&category).Error; err != nil {
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Images").Save(&category).Error; err != nil {
+			return err
+		}
+		// Products are searchable by their category's name
+		return search.IndexCategory(tx, category.ID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update category"})
 		return
 	}
@@ -130,25 +448,163 @@ func UpdateCategory(c *gin.Context) {
 }
 
 // DeleteCategory godoc
-// @Summary Delete a category (Admin only)
-// @Description Remove a category from the system
+// @Summary Archive a category (Admin only)
+// @Description Archive a category. One that still has products or subcategories is only archived when reassign_to names the category to move them to; archived products move along so they can be restored.
 // @Tags Admin - Categories
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Category ID"
-// @Success 200 {object} map[string]interface{} "Category deleted successfully"
+// @Param reassign_to query string false "Category that takes over the products and subcategories"
+// @Success 200 {object} map[string]interface{} "Category archived successfully"
+// @Failure 400 {object} map[string]interface{} "Invalid reassign_to category"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 409 {object} map[string]interface{} "Category still has products or subcategories"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /admin/categories/{id} [delete]
 func DeleteCategory(c *gin.Context) {
-	categoryID := c.Param("id")
+	db := config.GetDB()
 
-	if err := config.GetDB().Delete(&models.Category{}, "id = ?", categoryID).Error; err != nil {
+	tree, err := loadCategoryTree(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil || tree.byID[categoryID] == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Category not found"})
+		return
+	}
+
+	var products int64
+	if err := db.Model(&models.Product{}).Where("category_id = ?", categoryID).Count(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
+		return
+	}
+	subcategories := len(tree.children[categoryID])
+
+	var target *models.Category
+	if reassignTo := c.Query("reassign_to"); reassignTo != "" {
+		targetID, err := uuid.Parse(reassignTo)
+		if err == nil {
+			target = tree.byID[targetID]
+		}
+		if target == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reassign_to category not found"})
+			return
+		}
+		if slices.Contains(tree.subtree(categoryID), target.ID) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reassign_to cannot be the category itself or one of its subcategories"})
+			return
+		}
+	} else if products > 0 || subcategories > 0 {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":         "Category still has products or subcategories; pass reassign_to to move them to another category",
+			"products":      products,
+			"subcategories": subcategories,
+		})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if target != nil {
+			if err := tx.Unscoped().Model(&models.Product{}).Where("category_id = ?", categoryID).
+				Update("category_id", target.ID).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&models.Category{}).Where("parent_id = ?", categoryID).
+				Update("parent_id", target.ID).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&models.Category{}, "id = ?", categoryID).Error; err != nil {
+			return err
+		}
+		if target != nil {
+			// The moved products are now found under the new category's name
+			return search.IndexCategory(tx, target.ID)
+		}
+		return nil
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete category"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Category deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":                  "Category archived successfully",
+		"products_reassigned":      products,
+		"subcategories_reassigned": subcategories,
+	})
+}
+
+// GetArchivedCategories godoc
+// @Summary Get archived categories (Admin only)
+// @Description Get the categories that were deleted, most recently archived first
+// @Tags Admin - Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of archived categories"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/categories/archived [get]
+func GetArchivedCategories(c *gin.Context) {
+	var categories []models.Category
+	if err := config.GetDB().Unscoped().Where("archived_at IS NOT NULL").
+		Order("archived_at DESC").Find(&categories).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch categories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+// RestoreCategory godoc
+// @Summary Restore an archived category (Admin only)
+// @Description Put an archived category back in the tree under its old parent, which must not be archived. Its archived products stay archived.
+// @Tags Admin - Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Success 200 {object} map[string]interface{} "Category restored"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Archived category not found"
+// @Failure 409 {object} map[string]interface{} "The parent category is archived"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/categories/{id}/restore [post]
+func RestoreCategory(c *gin.Context) {
+	db := config.GetDB()
+
+	var category models.Category
+	if err := db.Unscoped().Where("id = ? AND archived_at IS NOT NULL", c.Param("id")).First(&category).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Archived category not found"})
+		return
+	}
+	if category.ParentID != nil {
+		var parents int64
+		if err := db.Model(&models.Category{}).Where("id = ?", *category.ParentID).Count(&parents).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore category"})
+			return
+		}
+		if parents == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The parent category is archived; restore it first"})
+			return
+		}
+	}
+
+	if err := db.Unscoped().Model(&category).Update("archived_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore category"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Category restored",
+		"category": category,
+	})
 }
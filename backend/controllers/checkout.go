@@ -0,0 +1,197 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"pet-food-ecommerce/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errProductUnavailable = errors.New("Product is not available")
+	errOptionUnavailable  = errors.New("Product option is no longer available")
+	errInsufficientStock  = errors.New("Insufficient stock for product")
+)
+
+// checkoutError is a checkout failure the customer can act on, carrying the
+// HTTP status CreateOrder answers with. Any other error from placeOrder is internal.
+type checkoutError struct {
+	Status int
+	Err    error
+}
+
+func (e *checkoutError) Error() string { return e.Err.Error() }
+func (e *checkoutError) Unwrap() error { return e.Err }
+
+func badCheckout(err error) error { return &checkoutError{Status: http.StatusBadRequest, Err: err} }
+
+// placeOrder turns priced items (with Product and Variant preloaded) into an order
+// inside tx: it checks stock and prescriptions, prices the items, claims flash sale
+// stock, writes the order and its items, takes the stock, uses up the coupon,
+// referral discount and points, and settles the payment. order arrives with UserID set and any fields
+// the caller owns; the rest is filled in. The caller commits and clears the cart.
+func placeOrder(tx *gorm.DB, order *models.Order, items []models.Cart, req *CreateOrderRequest, opts pricingOptions) error {
+	now := time.Now()
+	prescriptions := make(map[uuid.UUID]*uuid.UUID)
+	for _, item := range items {
+		// Products can be taken back to draft while in a cart or subscription
+		if !item.Product.Purchasable(now) {
+			return badCheckout(fmt.Errorf("%w: %s", errProductUnavailable, item.Product.Name))
+		}
+		if item.VariantID == nil || item.Variant.ID == uuid.Nil {
+			return badCheckout(fmt.Errorf("%w: %s", errOptionUnavailable, item.Product.Name))
+		}
+		if item.Variant.Stock < item.Quantity {
+			return badCheckout(fmt.Errorf("%w: %s %s", errInsufficientStock, item.Product.Name, item.Variant.Name))
+		}
+
+		// Approvals can expire or be replaced while the item sits in the cart
+		if item.Product.Prescription {
+			prescription, err := validPrescription(tx, order.UserID, item.ProductID, now)
+			if errors.Is(err, errPrescriptionRequired) {
+				return &checkoutError{Status: http.StatusForbidden, Err: fmt.Errorf("%s %w", item.Product.Name, err)}
+			}
+			if err != nil {
+				return fmt.Errorf("check prescription: %w", err)
+			}
+			prescriptions[item.ProductID] = &prescription.ID
+		}
+	}
+
+	// Calculate totals, re-evaluating promotions and the coupon inside the transaction
+	totals, err := priceCart(tx, order.UserID.String(), items, opts)
+	if err != nil {
+		return badCheckout(err)
+	}
+	if totals.CouponError != "" {
+		return badCheckout(errors.New(totals.CouponError))
+	}
+
+	// Prepare order items
+	var orderItems []models.OrderItem
+	for _, line := range totals.Lines {
+		// Sale-priced units get their own item so each item has a single price
+		if line.SaleQuantity > 0 {
+			if err := claimFlashSaleStock(tx, *line.SaleID, line.SaleQuantity); err != nil {
+				if errors.Is(err, errFlashSaleSoldOut) {
+					return &checkoutError{Status: http.StatusConflict, Err: err}
+				}
+				return fmt.Errorf("reserve flash sale stock: %w", err)
+			}
+			orderItems = append(orderItems, models.OrderItem{
+				ProductID:      line.ProductID,
+				VariantID:      line.VariantID,
+				SKU:            line.SKU,
+				VariantName:    line.VariantName,
+				Quantity:       line.SaleQuantity,
+				Price:          line.SalePrice,
+				SaleID:         line.SaleID,
+				PrescriptionID: prescriptions[line.ProductID],
+			})
+		}
+		if regular := line.Quantity - line.SaleQuantity; regular > 0 {
+			orderItems = append(orderItems, models.OrderItem{
+				ProductID:      line.ProductID,
+				VariantID:      line.VariantID,
+				SKU:            line.SKU,
+				VariantName:    line.VariantName,
+				Quantity:       regular,
+				Price:          line.UnitPrice,
+				Discount:       line.Discount,
+				Promotion:      line.promotionNames(),
+				PrescriptionID: prescriptions[line.ProductID],
+			})
+		}
+	}
+
+	// Create order
+	order.SubtotalAmount = totals.Subtotal
+	order.DiscountAmount = totals.Discount
+	order.PromotionDiscount = totals.PromotionDiscount
+	order.CouponDiscount = totals.CouponDiscount
+	order.ReferralDiscount = totals.ReferralDiscount
+	order.SubscriberDiscount = totals.SubscriberDiscount
+	order.PointsRedeemed = totals.PointsRedeemed
+	order.PointsDiscount = totals.PointsDiscount
+	order.ShippingFee = totals.ShippingFee
+	order.TaxAmount = totals.Tax
+	order.TotalAmount = totals.Total
+	order.Currency = totals.Currency
+	order.Status = "pending"
+	order.ShippingAddress = req.ShippingAddress
+	if totals.Coupon != nil {
+		order.CouponID = &totals.Coupon.ID
+		order.CouponCode = totals.Coupon.Code
+	}
+
+	if err := tx.Create(order).Error; err != nil {
+		return fmt.Errorf("create order: %w", err)
+	}
+
+	// Create order items and update stock
+	for i := range orderItems {
+		orderItems[i].OrderID = order.ID
+		if err := tx.Create(&orderItems[i]).Error; err != nil {
+			return fmt.Errorf("create order items: %w", err)
+		}
+
+		// Update variant and product stock
+		if err := tx.Model(&models.ProductVariant{}).Where("id = ?", orderItems[i].VariantID).
+			Update("stock", gorm.Expr("stock - ?", orderItems[i].Quantity)).Error; err != nil {
+			return fmt.Errorf("update stock: %w", err)
+		}
+		if err := tx.Model(&models.Product{}).Where("id = ?", orderItems[i].ProductID).
+			Update("stock", gorm.Expr("stock - ?", orderItems[i].Quantity)).Error; err != nil {
+			return fmt.Errorf("update stock: %w", err)
+		}
+	}
+
+	// Record coupon redemption
+	if totals.Coupon != nil {
+		if err := redeemCoupon(tx, totals.Coupon, order, totals.CouponDiscount); err != nil {
+			if errors.Is(err, errCouponUsageLimit) {
+				return badCheckout(err)
+			}
+			return fmt.Errorf("redeem coupon: %w", err)
+		}
+	}
+
+	// Use up the referee first-order discount
+	if totals.Referral != nil {
+		if err := claimReferralDiscount(tx, totals.Referral, order.ID); err != nil {
+			if errors.Is(err, errReferralUsed) {
+				return &checkoutError{Status: http.StatusConflict, Err: err}
+			}
+			return fmt.Errorf("apply referral discount: %w", err)
+		}
+	}
+
+	// Burn the loyalty points used for the discount
+	if totals.PointsRedeemed > 0 {
+		if err := spendPoints(tx, &models.LoyaltyEntry{
+			UserID:  order.UserID,
+			Type:    models.LoyaltyEntryBurn,
+			OrderID: &order.ID,
+			Reason:  "Points redeemed at checkout",
+		}, totals.PointsRedeemed); err != nil {
+			if errors.Is(err, errInsufficientPoints) {
+				return badCheckout(err)
+			}
+			return fmt.Errorf("redeem loyalty points: %w", err)
+		}
+	}
+
+	// Settle gift cards and store credit, leaving the rest to the payment method
+	if err := payOrder(tx, order, req); err != nil {
+		switch {
+		case errors.Is(err, errGiftCardNotFound), errors.Is(err, errGiftCardUnusable), errors.Is(err, errInsufficientStoreCredit):
+			return badCheckout(err)
+		}
+		return fmt.Errorf("process payment: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,296 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CouponInput represents the request body for creating/updating a coupon
+type CouponInput struct {
+	Code         string       `json:"code" binding:"required" example:"WELCOME10"`
+	Description  string       `json:"description" example:"10% off your first order"`
+	Type         string       `json:"type" binding:"required,oneof=percentage fixed free_shipping" example:"percentage"`
+	PercentOff   int          `json:"percent_off" binding:"min=0,max=100" example:"10"`
+	AmountOff    models.Money `json:"amount_off" swaggertype:"number" example:"0"`
+	MaxDiscount  models.Money `json:"max_discount" swaggertype:"number" example:"200"`
+	MinSpend     models.Money `json:"min_spend" swaggertype:"number" example:"500"`
+	UsageLimit   int          `json:"usage_limit" binding:"min=0" example:"1000"`
+	PerUserLimit int          `json:"per_user_limit" binding:"min=0" example:"1"`
+	StartsAt     *time.Time   `json:"starts_at" example:"2026-01-01T00:00:00+07:00"`
+	EndsAt       *time.Time   `json:"ends_at" example:"2026-01-31T23:59:59+07:00"`
+	Active       *bool        `json:"active" example:"true"`
+	CategoryIDs  []string     `json:"category_ids"` // subcategories are covered too
+	ProductIDs   []string     `json:"product_ids"`
+}
+
+// validate checks the rules that binding tags cannot express
+func (input *CouponInput) validate() error {
+	switch input.Type {
+	case models.CouponTypePercentage:
+		if input.PercentOff < 1 {
+			return errors.New("percent_off must be between 1 and 100 for percentage coupons")
+		}
+	case models.CouponTypeFixed:
+		if input.AmountOff <= 0 {
+			return errors.New("amount_off must be greater than 0 for fixed coupons")
+		}
+	}
+	if input.AmountOff < 0 || input.MaxDiscount < 0 || input.MinSpend < 0 {
+		return errors.New("amounts must not be negative")
+	}
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// applyTo copies the input onto a coupon and resolves its restrictions
+func (input *CouponInput) applyTo(db *gorm.DB, coupon *models.Coupon) error {
+	coupon.Code = strings.ToUpper(strings.TrimSpace(input.Code))
+	coupon.Description = input.Description
+	coupon.Type = input.Type
+	coupon.PercentOff = input.PercentOff
+	coupon.AmountOff = input.AmountOff
+	coupon.MaxDiscount = input.MaxDiscount
+	coupon.MinSpend = input.MinSpend
+	coupon.UsageLimit = input.UsageLimit
+	coupon.PerUserLimit = input.PerUserLimit
+	coupon.StartsAt = input.StartsAt
+	coupon.EndsAt = input.EndsAt
+	coupon.Active = input.Active == nil || *input.Active
+
+	coupon.Categories = nil
+	if len(input.CategoryIDs) > 0 {
+		if err := db.Where("id IN ?", input.CategoryIDs).Find(&coupon.Categories).Error; err != nil {
+			return err
+		}
+		if len(coupon.Categories) != len(input.CategoryIDs) {
+			return errors.New("one or more category_ids do not exist")
+		}
+	}
+
+	coupon.Products = nil
+	if len(input.ProductIDs) > 0 {
+		if err := db.Where("id IN ?", input.ProductIDs).Find(&coupon.Products).Error; err != nil {
+			return err
+		}
+		if len(coupon.Products) != len(input.ProductIDs) {
+			return errors.New("one or more product_ids do not exist")
+		}
+	}
+
+	return nil
+}
+
+// GetCoupons godoc
+// @Summary Get all coupons (Admin only)
+// @Description Get all coupons with their restrictions and usage counts
+// @Tags Admin - Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of coupons"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/coupons [get]
+func GetCoupons(c *gin.Context) {
+	var coupons []models.Coupon
+	if err := config.GetDB().Preload("Categories").Preload("Products").
+		Order("created_at DESC").Find(&coupons).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coupons"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"coupons": coupons})
+}
+
+// CreateCoupon godoc
+// @Summary Create a coupon (Admin only)
+// @Description Create a percentage, fixed or free-shipping promo code
+// @Tags Admin - Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param coupon body CouponInput true "Coupon data"
+// @Success 201 {object} map[string]interface{} "Coupon created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 409 {object} map[string]interface{} "Coupon code already exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/coupons [post]
+func CreateCoupon(c *gin.Context) {
+	var input CouponInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var coupon models.Coupon
+	if err := input.applyTo(config.GetDB(), &coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing models.Coupon
+	if err := config.GetDB().Where("code = ?", coupon.Code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coupon code already exists"})
+		return
+	}
+
+	if err := config.GetDB().Create(&coupon).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create coupon"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Coupon created successfully",
+		"coupon":  coupon,
+	})
+}
+
+// UpdateCoupon godoc
+// @Summary Update a coupon (Admin only)
+// @Description Update an existing coupon's rules and restrictions
+// @Tags Admin - Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Coupon ID"
+// @Param coupon body CouponInput true "Coupon data"
+// @Success 200 {object} map[string]interface{} "Coupon updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Coupon not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/coupons/{id} [put]
+func UpdateCoupon(c *gin.Context) {
+	couponID := c.Param("id")
+
+	var coupon models.Coupon
+	if err := config.GetDB().Where("id = ?", couponID).First(&coupon).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	var input CouponInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.applyTo(config.GetDB(), &coupon); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing models.Coupon
+	if err := config.GetDB().Where("code = ? AND id <> ?", coupon.Code, coupon.ID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Coupon code already exists"})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Categories", "Products").Save(&coupon).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&coupon).Association("Categories").Replace(coupon.Categories); err != nil {
+			return err
+		}
+		return tx.Model(&coupon).Association("Products").Replace(coupon.Products)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Coupon updated successfully",
+		"coupon":  coupon,
+	})
+}
+
+// DeleteCoupon godoc
+// @Summary Delete a coupon (Admin only)
+// @Description Remove a coupon. Past redemptions stay on their orders.
+// @Tags Admin - Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Coupon ID"
+// @Success 200 {object} map[string]interface{} "Coupon deleted successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Coupon not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/coupons/{id} [delete]
+func DeleteCoupon(c *gin.Context) {
+	couponID := c.Param("id")
+
+	var coupon models.Coupon
+	if err := config.GetDB().Where("id = ?", couponID).First(&coupon).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coupon not found"})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&coupon).Association("Categories").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&coupon).Association("Products").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Where("coupon_id = ?", coupon.ID).Delete(&models.CartCoupon{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&coupon).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete coupon"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Coupon deleted successfully"})
+}
+
+// GetCouponRedemptions godoc
+// @Summary Get coupon redemptions (Admin only)
+// @Description Get every order that redeemed the given coupon
+// @Tags Admin - Coupons
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Coupon ID"
+// @Success 200 {object} map[string]interface{} "List of redemptions"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/coupons/{id}/redemptions [get]
+func GetCouponRedemptions(c *gin.Context) {
+	couponID := c.Param("id")
+
+	var redemptions []models.CouponRedemption
+	if err := config.GetDB().Where("coupon_id = ?", couponID).
+		Order("created_at DESC").Find(&redemptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch redemptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"redemptions": redemptions})
+}
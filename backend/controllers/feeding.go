@@ -0,0 +1,403 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultReorderWithinDays = 14
+	maxReorderWithinDays     = 90
+	// Food that ran out longer ago than this was probably replaced by something else
+	reorderStaleDays = 30
+	// How far back the reminder job looks for customers with deliveries
+	reorderLookbackDays = 365
+)
+
+// Reorder prediction bases
+const (
+	predictionFromHistory = "purchase_history" // the customer's own reorder rhythm
+	predictionFromFeeding = "feeding_guide"    // bag size against their pets' daily portions
+)
+
+// mealsPerDay is the usual number of meals for the pet's life stage
+func mealsPerDay(stage string) int {
+	if stage == models.LifeStagePuppy || stage == models.LifeStageKitten {
+		return 3
+	}
+	return 2
+}
+
+// dailyGrams is how much of a product the pet needs each day
+func dailyGrams(pet *models.Pet, product *models.Product, now time.Time) float64 {
+	if product.KcalPerKg <= 0 {
+		return 0
+	}
+	return pet.DailyCalories(now) / product.KcalPerKg * 1000
+}
+
+// variantWeightGrams is the pack weight of a variant, falling back to the product's
+func variantWeightGrams(variant *models.ProductVariant, product *models.Product) (float64, bool) {
+	if variant != nil {
+		if grams, ok := models.WeightGrams(variant.Weight); ok {
+			return grams, true
+		}
+	}
+	return models.WeightGrams(product.Weight)
+}
+
+// GetFeedingGuide godoc
+// @Summary Daily feeding guide for a pet and product
+// @Description Work out the pet's daily calories from its weight, age and activity level, the daily portion of the product from its kcal/kg, and how many days each pack size lasts
+// @Tags Pets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Pet ID"
+// @Param productId path string true "Product ID"
+// @Success 200 {object} map[string]interface{} "Feeding guide"
+// @Failure 400 {object} map[string]interface{} "Pet has no weight, product has no calorie data or is for another species"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Pet or product not found"
+// @Router /pets/{id}/feeding/{productId} [get]
+func GetFeedingGuide(c *gin.Context) {
+	db := config.GetDB()
+
+	var pet models.Pet
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetString("user_id")).First(&pet).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+		return
+	}
+
+	var product models.Product
+	if err := db.Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC, price ASC") }).
+		Where("id = ?", c.Param("productId")).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	if product.Species != "" && product.Species != pet.Species {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This product is not made for " + pet.Species + "s"})
+		return
+	}
+	if product.KcalPerKg <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This product has no calorie information"})
+		return
+	}
+
+	now := time.Now()
+	calories := pet.DailyCalories(now)
+	if calories <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A feeding guide needs the pet's weight and is only available for dogs and cats"})
+		return
+	}
+	grams := dailyGrams(&pet, &product, now)
+	stage := pet.LifeStage(now)
+	meals := mealsPerDay(stage)
+
+	packs := []gin.H{}
+	for i := range product.Variants {
+		variant := &product.Variants[i]
+		packGrams, ok := variantWeightGrams(variant, &product)
+		if !ok {
+			continue
+		}
+		packs = append(packs, gin.H{
+			"variant_id": variant.ID,
+			"name":       variant.Name,
+			"weight":     variant.Weight,
+			"grams":      packGrams,
+			"days":       math.Floor(packGrams / grams),
+			"price":      variant.Price,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"pet_id":         pet.ID,
+		"product_id":     product.ID,
+		"life_stage":     stage,
+		"activity_level": pet.Activity,
+		"weight_kg":      pet.WeightKg,
+		"kcal_per_kg":    product.KcalPerKg,
+		"daily_kcal":     math.Round(calories),
+		"daily_grams":    math.Round(grams),
+		"meals_per_day":  meals,
+		"grams_per_meal": math.Round(grams / float64(meals)),
+		"packs":          packs,
+	})
+}
+
+// runOutPrediction is when a customer is expected to finish a product they bought
+type runOutPrediction struct {
+	ProductID       uuid.UUID  `json:"product_id"`
+	VariantID       *uuid.UUID `json:"variant_id,omitempty"`
+	Name            string     `json:"name"`
+	VariantName     string     `json:"variant_name,omitempty"`
+	SKU             string     `json:"sku,omitempty"`
+	ImageURL        string     `json:"image_url"`
+	InStock         bool       `json:"in_stock"`
+	Quantity        int        `json:"quantity"` // units in the last delivery
+	LastDeliveredAt time.Time  `json:"last_delivered_at"`
+	RunsOutAt       time.Time  `json:"runs_out_at"`
+	DaysLeft        int        `json:"days_left"` // negative once it has run out
+	Basis           string     `json:"basis"`     // purchase_history or feeding_guide
+	Reordered       bool       `json:"reordered"` // already in an open order
+}
+
+type purchase struct {
+	at       time.Time
+	quantity int
+}
+
+// predictRunOuts estimates when a customer runs out of each product from their
+// delivered orders. With two or more deliveries the customer's own pace is
+// used; after the first, the pack weight against their pets' daily portions.
+func predictRunOuts(db *gorm.DB, userID string, now time.Time) ([]runOutPrediction, error) {
+	var orders []models.Order
+	if err := db.Preload("OrderItems").
+		Where("user_id = ? AND status IN ?", userID, []string{"delivered", "pending", "processing", "shipped"}).
+		Order("created_at ASC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		product uuid.UUID
+		variant uuid.UUID
+	}
+	purchases := make(map[key][]purchase)
+	lastItem := make(map[key]models.OrderItem)
+	openSince := make(map[key]time.Time) // latest open order containing the item
+	for _, order := range orders {
+		for _, item := range order.OrderItems {
+			k := key{product: item.ProductID}
+			if item.VariantID != nil {
+				k.variant = *item.VariantID
+			}
+			if order.Status != "delivered" {
+				openSince[k] = order.CreatedAt
+				continue
+			}
+			deliveredAt := order.UpdatedAt
+			if order.DeliveredAt != nil {
+				deliveredAt = *order.DeliveredAt
+			}
+			purchases[k] = append(purchases[k], purchase{at: deliveredAt, quantity: item.Quantity})
+			lastItem[k] = item
+		}
+	}
+	if len(purchases) == 0 {
+		return []runOutPrediction{}, nil
+	}
+
+	productIDs := make([]uuid.UUID, 0, len(purchases))
+	for k := range purchases {
+		productIDs = append(productIDs, k.product)
+	}
+	var products []models.Product
+	if err := db.Scopes(purchasableProducts).Preload("Variants").Where("id IN ?", productIDs).Find(&products).Error; err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]*models.Product, len(products))
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+	}
+
+	var pets []models.Pet
+	if err := db.Where("user_id = ?", userID).Find(&pets).Error; err != nil {
+		return nil, err
+	}
+
+	predictions := []runOutPrediction{}
+	for k, bought := range purchases {
+		product, ok := byID[k.product]
+		if !ok {
+			continue
+		}
+		sort.Slice(bought, func(i, j int) bool { return bought[i].at.Before(bought[j].at) })
+		last := bought[len(bought)-1]
+		item := lastItem[k]
+
+		var variant *models.ProductVariant
+		for i := range product.Variants {
+			if item.VariantID != nil && product.Variants[i].ID == *item.VariantID {
+				variant = &product.Variants[i]
+			}
+		}
+
+		var days float64
+		basis := predictionFromHistory
+		if len(bought) > 1 && last.at.After(bought[0].at) {
+			// Everything but the last delivery was eaten between the first and the last
+			eaten := 0
+			for _, p := range bought[:len(bought)-1] {
+				eaten += p.quantity
+			}
+			daysPerUnit := last.at.Sub(bought[0].at).Hours() / 24 / float64(eaten)
+			days = daysPerUnit * float64(last.quantity)
+		} else {
+			basis = predictionFromFeeding
+			packGrams, ok := variantWeightGrams(variant, product)
+			if !ok {
+				continue
+			}
+			var daily float64
+			for i := range pets {
+				if pets[i].Species == product.Species {
+					daily += dailyGrams(&pets[i], product, now)
+				}
+			}
+			if daily <= 0 {
+				continue
+			}
+			days = packGrams * float64(last.quantity) / daily
+		}
+
+		runsOutAt := last.at.Add(time.Duration(days * 24 * float64(time.Hour)))
+		opened, reordered := openSince[k]
+		prediction := runOutPrediction{
+			ProductID:       product.ID,
+			VariantID:       item.VariantID,
+			Name:            product.Name,
+			VariantName:     item.VariantName,
+			SKU:             item.SKU,
+			ImageURL:        product.ImageURL,
+			InStock:         product.Stock > 0,
+			Quantity:        last.quantity,
+			LastDeliveredAt: last.at,
+			RunsOutAt:       runsOutAt,
+			DaysLeft:        int(math.Round(runsOutAt.Sub(now).Hours() / 24)),
+			Basis:           basis,
+			Reordered:       reordered && opened.After(last.at),
+		}
+		if variant != nil {
+			prediction.InStock = variant.Stock > 0
+		}
+		predictions = append(predictions, prediction)
+	}
+
+	sort.Slice(predictions, func(i, j int) bool { return predictions[i].RunsOutAt.Before(predictions[j].RunsOutAt) })
+	return predictions, nil
+}
+
+// reorderSoon keeps the predictions running out within the window that have not
+// been reordered yet
+func reorderSoon(predictions []runOutPrediction, withinDays int) []runOutPrediction {
+	soon := []runOutPrediction{}
+	for _, prediction := range predictions {
+		if !prediction.Reordered && prediction.DaysLeft <= withinDays && prediction.DaysLeft >= -reorderStaleDays {
+			soon = append(soon, prediction)
+		}
+	}
+	return soon
+}
+
+func withinDaysParam(c *gin.Context) (int, bool) {
+	within := defaultReorderWithinDays
+	if raw := c.Query("within_days"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 || parsed > maxReorderWithinDays {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "within_days must be between 0 and 90"})
+			return 0, false
+		}
+		within = parsed
+	}
+	return within, true
+}
+
+// GetReorderPredictions godoc
+// @Summary Products I will run out of soon
+// @Description Predict when the current user runs out of each product from their delivered orders, and list those to reorder within the window. Products already in an open order are not listed to reorder.
+// @Tags Pets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param within_days query int false "Reorder window in days" default(14) minimum(0) maximum(90)
+// @Success 200 {object} map[string]interface{} "Reorder soon list and all predictions"
+// @Failure 400 {object} map[string]interface{} "Invalid window"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /reorders [get]
+func GetReorderPredictions(c *gin.Context) {
+	within, ok := withinDaysParam(c)
+	if !ok {
+		return
+	}
+
+	predictions, err := predictRunOuts(config.GetDB(), c.GetString("user_id"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to predict reorders"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reorder_soon": reorderSoon(predictions, within),
+		"predictions":  predictions,
+	})
+}
+
+// GetReorderReminders godoc
+// @Summary Customers to remind to reorder (Admin only)
+// @Description List customers with products running out within the window and not yet reordered, for reminder emails or notifications
+// @Tags Admin - Orders
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param within_days query int false "Reorder window in days" default(14) minimum(0) maximum(90)
+// @Success 200 {object} map[string]interface{} "Customers and their products to reorder"
+// @Failure 400 {object} map[string]interface{} "Invalid window"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/reorder-reminders [get]
+func GetReorderReminders(c *gin.Context) {
+	within, ok := withinDaysParam(c)
+	if !ok {
+		return
+	}
+	db := config.GetDB()
+	now := time.Now()
+
+	var userIDs []uuid.UUID
+	if err := db.Model(&models.Order{}).
+		Where("status = ? AND COALESCE(delivered_at, updated_at) >= ?", "delivered", now.AddDate(0, 0, -reorderLookbackDays)).
+		Distinct().Pluck("user_id", &userIDs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+		return
+	}
+
+	var users []models.User
+	if len(userIDs) > 0 {
+		if err := db.Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+			return
+		}
+	}
+
+	reminders := []gin.H{}
+	for _, user := range users {
+		predictions, err := predictRunOuts(db, user.ID.String(), now)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reminders"})
+			return
+		}
+		if soon := reorderSoon(predictions, within); len(soon) > 0 {
+			reminders = append(reminders, gin.H{
+				"user_id": user.ID,
+				"name":    user.Name,
+				"email":   user.Email,
+				"items":   soon,
+			})
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reminders": reminders})
+}
@@ -0,0 +1,242 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FlashSaleInput represents the request body for creating/updating a flash sale
+type FlashSaleInput struct {
+	ProductID        string       `json:"product_id" binding:"required" example:"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"`
+	VariantID        string       `json:"variant_id" example:"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"` // required when the product has several variants
+	Name             string       `json:"name" example:"Midnight Sale"`
+	SalePrice        models.Money `json:"sale_price" binding:"required" swaggertype:"number" example:"1299.00"`
+	StartsAt         time.Time    `json:"starts_at" binding:"required" example:"2026-11-11T00:00:00+07:00"`
+	EndsAt           time.Time    `json:"ends_at" binding:"required" example:"2026-11-12T00:00:00+07:00"`
+	PerCustomerLimit int          `json:"per_customer_limit" binding:"min=0" example:"2"`
+	StockLimit       int          `json:"stock_limit" binding:"min=0" example:"100"`
+}
+
+// applyTo validates the input against the product and copies it onto a flash sale
+func (input *FlashSaleInput) applyTo(db *gorm.DB, sale *models.FlashSale) error {
+	if !input.EndsAt.After(input.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+
+	productID, err := uuid.Parse(input.ProductID)
+	if err != nil {
+		return errors.New("invalid product_id")
+	}
+
+	var product models.Product
+	if err := db.Preload("Variants").Where("id = ?", productID).First(&product).Error; err != nil {
+		return errors.New("product_id does not exist")
+	}
+	// A sale price is for one pack size: a product-wide sale is only allowed
+	// while the product has a single variant, so its price is that variant's
+	regularPrice := product.Price
+	sale.VariantID = nil
+	if input.VariantID == "" && len(product.Variants) > 1 {
+		return errVariantRequired
+	}
+	if input.VariantID != "" {
+		var variant models.ProductVariant
+		if err := db.Where("id = ? AND product_id = ?", input.VariantID, productID).First(&variant).Error; err != nil {
+			return errors.New("variant_id does not belong to the product")
+		}
+		regularPrice = variant.Price
+		sale.VariantID = &variant.ID
+	}
+	if input.SalePrice <= 0 || input.SalePrice >= regularPrice {
+		return errors.New("sale_price must be greater than 0 and lower than the regular price")
+	}
+
+	sale.ProductID = productID
+	sale.Name = input.Name
+	sale.SalePrice = input.SalePrice
+	sale.StartsAt = input.StartsAt
+	sale.EndsAt = input.EndsAt
+	sale.PerCustomerLimit = input.PerCustomerLimit
+	sale.StockLimit = input.StockLimit
+	return nil
+}
+
+// GetActiveFlashSales godoc
+// @Summary Get running flash sales
+// @Description Get flash sales that are currently running and still have sale stock
+// @Tags Products
+// @Accept json
+// @Produce json
+// @Success 200 {object} map[string]interface{} "List of running flash sales"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /flash-sales [get]
+func GetActiveFlashSales(c *gin.Context) {
+	now := time.Now()
+
+	var sales []models.FlashSale
+	if err := config.GetDB().Preload("Product").Preload("Product.Category").
+		Where("starts_at <= ? AND ends_at > ?", now, now).
+		Where("stock_limit = 0 OR sold_count < stock_limit").
+		Where("product_id IN (?)", config.GetDB().Model(&models.Product{}).Scopes(listedProducts).Select("id")).
+		Order("ends_at ASC").Find(&sales).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch flash sales"})
+		return
+	}
+
+	for i := range sales {
+		salePrice := sales[i].SalePrice
+		endsAt := sales[i].EndsAt
+		sales[i].Product.SalePrice = &salePrice
+		sales[i].Product.SaleEndsAt = &endsAt
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flash_sales": sales})
+}
+
+// GetFlashSales godoc
+// @Summary Get all flash sales (Admin only)
+// @Description Get past, running and scheduled flash sales
+// @Tags Admin - Flash Sales
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of flash sales"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/flash-sales [get]
+func GetFlashSales(c *gin.Context) {
+	var sales []models.FlashSale
+	if err := config.GetDB().Preload("Product").Order("starts_at DESC").Find(&sales).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch flash sales"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"flash_sales": sales})
+}
+
+// CreateFlashSale godoc
+// @Summary Schedule a flash sale (Admin only)
+// @Description Schedule a time-boxed sale price for a product, with optional per-customer and stock caps. Products with several variants need a variant_id.
+// @Tags Admin - Flash Sales
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param sale body FlashSaleInput true "Flash sale data"
+// @Success 201 {object} map[string]interface{} "Flash sale created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/flash-sales [post]
+func CreateFlashSale(c *gin.Context) {
+	var input FlashSaleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var sale models.FlashSale
+	if err := input.applyTo(config.GetDB(), &sale); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.GetDB().Create(&sale).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create flash sale"})
+		return
+	}
+
+	config.GetDB().Preload("Product").First(&sale, "id = ?", sale.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    "Flash sale created successfully",
+		"flash_sale": sale,
+	})
+}
+
+// UpdateFlashSale godoc
+// @Summary Update a flash sale (Admin only)
+// @Description Update a flash sale's price, window or caps. Units already sold are kept.
+// @Tags Admin - Flash Sales
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Flash sale ID"
+// @Param sale body FlashSaleInput true "Flash sale data"
+// @Success 200 {object} map[string]interface{} "Flash sale updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Flash sale not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/flash-sales/{id} [put]
+func UpdateFlashSale(c *gin.Context) {
+	saleID := c.Param("id")
+
+	var sale models.FlashSale
+	if err := config.GetDB().Where("id = ?", saleID).First(&sale).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flash sale not found"})
+		return
+	}
+
+	var input FlashSaleInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.applyTo(config.GetDB(), &sale); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.GetDB().Omit("Product").Save(&sale).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update flash sale"})
+		return
+	}
+
+	config.GetDB().Preload("Product").First(&sale, "id = ?", sale.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Flash sale updated successfully",
+		"flash_sale": sale,
+	})
+}
+
+// DeleteFlashSale godoc
+// @Summary Delete a flash sale (Admin only)
+// @Description Cancel a flash sale. Orders keep the price they were charged.
+// @Tags Admin - Flash Sales
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Flash sale ID"
+// @Success 200 {object} map[string]interface{} "Flash sale deleted successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Flash sale not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/flash-sales/{id} [delete]
+func DeleteFlashSale(c *gin.Context) {
+	saleID := c.Param("id")
+
+	result := config.GetDB().Where("id = ?", saleID).Delete(&models.FlashSale{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete flash sale"})
+		return
+	}
+
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flash sale not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Flash sale deleted successfully"})
+}
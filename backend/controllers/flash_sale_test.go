@@ -0,0 +1,85 @@
+//go:build cgo
+
+package controllers
+
+import (
+	"net/http"
+	"pet-food-ecommerce/models"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// priceVariant prices a cart of one unit of the variant and returns its line
+func priceVariant(t *testing.T, db *gorm.DB, user models.User, product models.Product, variantID uuid.UUID) cartLine {
+	t.Helper()
+	db.Where("user_id = ?", user.ID).Delete(&models.Cart{})
+	db.Create(&models.Cart{UserID: user.ID, ProductID: product.ID, VariantID: &variantID, Quantity: 1})
+	totals, err := priceCart(db, user.ID.String(), testCart(t, db, user.ID), pricingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return totals.Lines[0]
+}
+
+func TestFlashSaleOnMultiVariantProductKeepsOtherVariantPrices(t *testing.T) {
+	db := newTestDB(t)
+	admin := models.User{Role: "admin"}
+	customer := createTestUser(t, db)
+	product := createTestProduct(t, db, 30000, 300000) // 1kg and 15kg bags
+	small, large := product.Variants[0].ID, product.Variants[1].ID
+	sale := map[string]interface{}{
+		"product_id": product.ID,
+		"sale_price": 250,
+		"starts_at":  time.Now().Add(-time.Hour),
+		"ends_at":    time.Now().Add(time.Hour),
+	}
+
+	code, out := serveTest(t, CreateFlashSale, http.MethodPost, "/flash-sales", "/flash-sales", admin, sale)
+	if code != http.StatusBadRequest {
+		t.Fatalf("product-wide sale on two variants: status %d %v, want 400", code, out)
+	}
+
+	sale["variant_id"] = small
+	code, out = serveTest(t, CreateFlashSale, http.MethodPost, "/flash-sales", "/flash-sales", admin, sale)
+	if code != http.StatusCreated {
+		t.Fatalf("sale on the 1kg bag: status %d %v, want 201", code, out)
+	}
+
+	if line := priceVariant(t, db, customer, product, small); line.SaleQuantity != 1 || line.Total != 25000 {
+		t.Errorf("1kg bag: sale quantity %d, total %s; want 1 and 250.00", line.SaleQuantity, line.Total)
+	}
+	if line := priceVariant(t, db, customer, product, large); line.SaleQuantity != 0 || line.Total != 300000 {
+		t.Errorf("15kg bag: sale quantity %d, total %s; want 0 and 3000.00", line.SaleQuantity, line.Total)
+	}
+}
+
+func TestAddingVariantPinsProductWideFlashSale(t *testing.T) {
+	db := newTestDB(t)
+	admin := models.User{Role: "admin"}
+	customer := createTestUser(t, db)
+	product := createTestProduct(t, db, 30000)
+	db.Create(&models.FlashSale{
+		ProductID: product.ID,
+		SalePrice: 25000,
+		StartsAt:  time.Now().Add(-time.Hour),
+		EndsAt:    time.Now().Add(time.Hour),
+	})
+
+	code, out := serveTest(t, CreateProductVariant, http.MethodPost, "/products/:id/variants", "/products/"+product.ID.String()+"/variants", admin,
+		map[string]interface{}{"name": "15kg", "price": 3000, "stock": 10})
+	if code != http.StatusCreated {
+		t.Fatalf("add variant: status %d %v, want 201", code, out)
+	}
+	var large models.ProductVariant
+	db.Where("product_id = ? AND name = ?", product.ID, "15kg").First(&large)
+
+	if line := priceVariant(t, db, customer, product, product.Variants[0].ID); line.Total != 25000 {
+		t.Errorf("1kg bag total %s, want the 250.00 sale price", line.Total)
+	}
+	if line := priceVariant(t, db, customer, product, large.ID); line.SaleQuantity != 0 || line.Total != 300000 {
+		t.Errorf("15kg bag: sale quantity %d, total %s; want 0 and 3000.00", line.SaleQuantity, line.Total)
+	}
+}
@@ -0,0 +1,328 @@
+package controllers
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// IssueGiftCardRequest represents the request body for issuing a gift card
+type IssueGiftCardRequest struct {
+	Amount         models.Money `json:"amount" binding:"required" swaggertype:"number" example:"500.00"`
+	Code           string       `json:"code" example:"PETLOVER-2026"` // generated when empty
+	ExpiresAt      *time.Time   `json:"expires_at" example:"2027-12-31T23:59:59+07:00"`
+	RecipientEmail string       `json:"recipient_email" binding:"omitempty,email" example:"friend@example.com"`
+	Note           string       `json:"note" example:"Sold at Pet Expo booth"`
+}
+
+// AdjustGiftCardRequest represents the request body for adjusting a gift card balance
+type AdjustGiftCardRequest struct {
+	Amount models.Money `json:"amount" binding:"required" swaggertype:"number" example:"-100.00"` // signed change
+	Reason string       `json:"reason" binding:"required" example:"Goodwill top-up"`
+}
+
+// UpdateGiftCardRequest represents the request body for changing a gift card's status
+type UpdateGiftCardRequest struct {
+	Active    *bool      `json:"active" example:"false"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2027-12-31T23:59:59+07:00"`
+}
+
+// generateGiftCardCode returns a random code like "A1B2-C3D4-E5F6-A7B8"
+func generateGiftCardCode() (string, error) {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	code := strings.ToUpper(hex.EncodeToString(bytes))
+	return code[0:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:16], nil
+}
+
+// CheckGiftCard godoc
+// @Summary Check a gift card balance
+// @Description Look up the balance and expiry of a gift card before checkout
+// @Tags Gift Cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param code path string true "Gift card code"
+// @Success 200 {object} map[string]interface{} "Gift card balance"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Gift card not found"
+// @Router /gift-cards/{code} [get]
+func CheckGiftCard(c *gin.Context) {
+	var card models.GiftCard
+	if err := config.GetDB().Where("code = ?", normalizeGiftCardCode(c.Param("code"))).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Gift card not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"gift_card": gin.H{
+			"code":       card.Code,
+			"balance":    card.Balance,
+			"currency":   card.Currency,
+			"expires_at": card.ExpiresAt,
+			"usable":     card.IsUsable(time.Now()),
+		},
+	})
+}
+
+// GetGiftCards godoc
+// @Summary Get all gift cards (Admin only)
+// @Description Get all issued gift cards with their balances
+// @Tags Admin - Gift Cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of gift cards"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/gift-cards [get]
+func GetGiftCards(c *gin.Context) {
+	var cards []models.GiftCard
+	if err := config.GetDB().Order("created_at DESC").Find(&cards).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch gift cards"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"gift_cards": cards})
+}
+
+// GetGiftCard godoc
+// @Summary Get a gift card (Admin only)
+// @Description Get a gift card with its full transaction history
+// @Tags Admin - Gift Cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Gift card ID"
+// @Success 200 {object} map[string]interface{} "Gift card and transactions"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Gift card not found"
+// @Router /admin/gift-cards/{id} [get]
+func GetGiftCard(c *gin.Context) {
+	var card models.GiftCard
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Gift card not found"})
+		return
+	}
+
+	var transactions []models.GiftCardTransaction
+	config.GetDB().Where("gift_card_id = ?", card.ID).Order("created_at DESC").Find(&transactions)
+
+	c.JSON(http.StatusOK, gin.H{
+		"gift_card":    card,
+		"transactions": transactions,
+	})
+}
+
+// IssueGiftCard godoc
+// @Summary Issue a gift card (Admin only)
+// @Description Issue a new gift card with an opening balance
+// @Tags Admin - Gift Cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body IssueGiftCardRequest true "Gift card data"
+// @Success 201 {object} map[string]interface{} "Gift card issued"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 409 {object} map[string]interface{} "Gift card code already exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/gift-cards [post]
+func IssueGiftCard(c *gin.Context) {
+	var req IssueGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.Amount <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "amount must be greater than 0"})
+		return
+	}
+
+	code := normalizeGiftCardCode(req.Code)
+	if code == "" {
+		generated, err := generateGiftCardCode()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate gift card code"})
+			return
+		}
+		code = generated
+	}
+
+	var existing models.GiftCard
+	if err := config.GetDB().Where("code = ?", code).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Gift card code already exists"})
+		return
+	}
+
+	adminID, _ := uuid.Parse(c.GetString("user_id"))
+	card := models.GiftCard{
+		Code:           code,
+		InitialBalance: req.Amount,
+		Balance:        req.Amount,
+		ExpiresAt:      req.ExpiresAt,
+		Active:         true,
+		RecipientEmail: req.RecipientEmail,
+		Note:           req.Note,
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&card).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.GiftCardTransaction{
+			GiftCardID: card.ID,
+			Type:       models.GiftCardTxIssue,
+			Amount:     card.Balance,
+			Balance:    card.Balance,
+			ActorID:    &adminID,
+			Reason:     req.Note,
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to issue gift card"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Gift card issued successfully",
+		"gift_card": card,
+	})
+}
+
+// AdjustGiftCard godoc
+// @Summary Adjust a gift card balance (Admin only)
+// @Description Add to or deduct from a gift card balance. Every adjustment is recorded with the admin who made it.
+// @Tags Admin - Gift Cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Gift card ID"
+// @Param request body AdjustGiftCardRequest true "Adjustment"
+// @Success 200 {object} map[string]interface{} "Gift card adjusted"
+// @Failure 400 {object} map[string]interface{} "Bad request or insufficient balance"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Gift card not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/gift-cards/{id}/adjustments [post]
+func AdjustGiftCard(c *gin.Context) {
+	var card models.GiftCard
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Gift card not found"})
+		return
+	}
+
+	var req AdjustGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	adminID, _ := uuid.Parse(c.GetString("user_id"))
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		return changeGiftCardBalance(tx, &card, req.Amount, models.GiftCardTxAdjust, nil, &adminID, req.Reason)
+	})
+	if errors.Is(err, errGiftCardUnusable) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Insufficient gift card balance"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust gift card"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Gift card adjusted successfully",
+		"gift_card": card,
+	})
+}
+
+// UpdateGiftCard godoc
+// @Summary Activate, deactivate or re-date a gift card (Admin only)
+// @Description Change a gift card's active flag or expiry date
+// @Tags Admin - Gift Cards
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Gift card ID"
+// @Param request body UpdateGiftCardRequest true "Status changes"
+// @Success 200 {object} map[string]interface{} "Gift card updated"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Gift card not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/gift-cards/{id} [put]
+func UpdateGiftCard(c *gin.Context) {
+	var card models.GiftCard
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&card).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Gift card not found"})
+		return
+	}
+
+	var req UpdateGiftCardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var changes []string
+	if req.Active != nil && *req.Active != card.Active {
+		card.Active = *req.Active
+		if card.Active {
+			changes = append(changes, "activated")
+		} else {
+			changes = append(changes, "deactivated")
+		}
+	}
+	if req.ExpiresAt != nil {
+		card.ExpiresAt = req.ExpiresAt
+		changes = append(changes, "expiry set to "+req.ExpiresAt.Format(time.RFC3339))
+	}
+
+	adminID, _ := uuid.Parse(c.GetString("user_id"))
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if len(changes) == 0 {
+			return nil
+		}
+		if err := tx.Model(&card).Updates(map[string]interface{}{
+			"active":     card.Active,
+			"expires_at": card.ExpiresAt,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.GiftCardTransaction{
+			GiftCardID: card.ID,
+			Type:       models.GiftCardTxStatus,
+			Balance:    card.Balance,
+			ActorID:    &adminID,
+			Reason:     strings.Join(changes, ", "),
+		}).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update gift card"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Gift card updated successfully",
+		"gift_card": card,
+	})
+}
@@ -0,0 +1,133 @@
+//go:build cgo
+
+package controllers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// newTestDB opens an empty in-memory SQLite database with every table
+// migrated, and makes it the database the handlers use
+func newTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.ReplaceAll(t.Name(), "/", "_"))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SQLite has no gen_random_uuid(); the models' BeforeCreate hooks set IDs
+	db.Callback().Raw().Before("gorm:raw").Register("test:uuid_default", func(tx *gorm.DB) {
+		if sql := tx.Statement.SQL.String(); strings.Contains(sql, " DEFAULT gen_random_uuid()") {
+			tx.Statement.SQL.Reset()
+			tx.Statement.SQL.WriteString(strings.ReplaceAll(sql, " DEFAULT gen_random_uuid()", ""))
+		}
+	})
+	if err := db.AutoMigrate(
+		&models.User{}, &models.Category{}, &models.Product{}, &models.ProductVariant{},
+		&models.Cart{}, &models.Order{}, &models.OrderItem{}, &models.OrderPayment{},
+		&models.Coupon{}, &models.CouponRedemption{}, &models.CartCoupon{},
+		&models.Promotion{}, &models.PromotionTier{}, &models.FlashSale{},
+		&models.WalletEntry{}, &models.GiftCard{}, &models.GiftCardTransaction{},
+		&models.LoyaltyEntry{}, &models.TierPrice{}, &models.Referral{},
+		&models.Pet{}, &models.Prescription{}, &models.Notification{}, &models.WishlistItem{}, &models.ProductAlert{},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := config.DB
+	config.DB = db
+	t.Cleanup(func() {
+		config.DB = previous
+		if sqlDB, err := db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	return db
+}
+
+// createTestProduct saves a published product with one variant per price,
+// in the order given
+func createTestProduct(t *testing.T, db *gorm.DB, prices ...models.Money) models.Product {
+	t.Helper()
+	category := models.Category{Name: "Dog Food " + uuid.NewString()}
+	if err := db.Create(&category).Error; err != nil {
+		t.Fatal(err)
+	}
+	product := models.Product{Name: "Kibble", CategoryID: category.ID, Price: prices[0], Status: models.ProductPublished}
+	for i, price := range prices {
+		product.Variants = append(product.Variants, models.ProductVariant{
+			Name:     fmt.Sprintf("Pack %d", i+1),
+			Price:    price,
+			Stock:    100,
+			Position: i,
+		})
+		product.Stock += 100
+	}
+	if err := db.Create(&product).Error; err != nil {
+		t.Fatal(err)
+	}
+	return product
+}
+
+// createTestUser saves a customer
+func createTestUser(t *testing.T, db *gorm.DB) models.User {
+	t.Helper()
+	user := models.User{Email: uuid.NewString() + "@example.com", Name: "Test Customer", PasswordHash: "x", Role: "customer"}
+	if err := db.Create(&user).Error; err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+// testCart loads the user's cart the way checkout prices it
+func testCart(t *testing.T, db *gorm.DB, userID uuid.UUID) []models.Cart {
+	t.Helper()
+	var items []models.Cart
+	if err := db.Preload("Product").Preload("Variant").Where("user_id = ?", userID).Find(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+	return items
+}
+
+// serveTest sends a JSON request to handler mounted at route, as the user with
+// the given ID and role, and decodes the JSON response
+func serveTest(t *testing.T, handler gin.HandlerFunc, method, route, path string, user models.User, body interface{}) (int, map[string]interface{}) {
+	t.Helper()
+	router := gin.New()
+	router.Handle(method, route, func(c *gin.Context) {
+		c.Set("user_id", user.ID.String())
+		c.Set("user_role", user.Role)
+	}, handler)
+
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var out map[string]interface{}
+	json.Unmarshal(rec.Body.Bytes(), &out)
+	return rec.Code, out
+}
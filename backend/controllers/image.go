@@ -0,0 +1,614 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"path/filepath"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"pet-food-ecommerce/storage"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ImageUpload represents the form fields sent with an image file
+type ImageUpload struct {
+	AltText  string `form:"alt_text"`
+	Position *int   `form:"position" binding:"omitempty,min=0"` // place in the gallery, last when empty
+}
+
+// UpdateImageRequest represents the request body for editing an image
+type UpdateImageRequest struct {
+	AltText  *string `json:"alt_text" example:"Royal Canin Medium Adult 4kg bag, front"`
+	Position *int    `json:"position" binding:"omitempty,min=0" example:"0"` // 0 makes it the cover
+}
+
+// ReorderImagesRequest represents the request body for reordering a gallery
+type ReorderImagesRequest struct {
+	ImageIDs []string `json:"image_ids" binding:"required,min=1" example:"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb,aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"`
+}
+
+var errImageOrder = errors.New("image_ids must list every image in the gallery once")
+
+// imageOwner is a kind of record with an image gallery
+type imageOwner struct {
+	Type     string             // models.ImageOwnerProduct or models.ImageOwnerCategory
+	model    func() interface{} // the owner's model, for finding it and setting its cover
+	NotFound string
+}
+
+var (
+	productImages  = imageOwner{models.ImageOwnerProduct, func() interface{} { return &models.Product{} }, "Product not found"}
+	categoryImages = imageOwner{models.ImageOwnerCategory, func() interface{} { return &models.Category{} }, "Category not found"}
+)
+
+// imagesInOrder sorts a gallery the way it is shown
+func imagesInOrder(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC, created_at ASC")
+}
+
+// find parses the owner's ID and checks it exists and is not archived
+func (o imageOwner) find(db *gorm.DB, id string) (uuid.UUID, error) {
+	ownerID, err := uuid.Parse(id)
+	if err != nil {
+		return uuid.Nil, gorm.ErrRecordNotFound
+	}
+	var count int64
+	if err := db.Model(o.model()).Where("id = ?", ownerID).Count(&count).Error; err != nil {
+		return uuid.Nil, err
+	}
+	if count == 0 {
+		return uuid.Nil, gorm.ErrRecordNotFound
+	}
+	return ownerID, nil
+}
+
+// gallery loads the owner's images in display order
+func (o imageOwner) gallery(db *gorm.DB, ownerID uuid.UUID) ([]models.Image, error) {
+	var images []models.Image
+	err := db.Scopes(imagesInOrder).Where("owner_type = ? AND owner_id = ?", o.Type, ownerID).Find(&images).Error
+	return images, err
+}
+
+// image loads one image of the owner's gallery
+func (o imageOwner) image(db *gorm.DB, ownerID uuid.UUID, id string) (models.Image, error) {
+	var img models.Image
+	err := db.Where("id = ? AND owner_type = ? AND owner_id = ?", id, o.Type, ownerID).First(&img).Error
+	return img, err
+}
+
+// arrange numbers the images in slice order and makes the first the owner's cover
+func (o imageOwner) arrange(tx *gorm.DB, ownerID uuid.UUID, images []models.Image) error {
+	for i := range images {
+		if images[i].Position == i {
+			continue
+		}
+		if err := tx.Model(&images[i]).Update("position", i).Error; err != nil {
+			return err
+		}
+	}
+	cover := ""
+	if len(images) > 0 {
+		cover = images[0].URL
+	}
+	return tx.Model(o.model()).Where("id = ?", ownerID).Update("image_url", cover).Error
+}
+
+// moveImage returns images with the one at from moved to index to, clamped to the end
+func moveImage(images []models.Image, from, to int) []models.Image {
+	img := images[from]
+	images = slices.Delete(images, from, from+1)
+	return slices.Insert(images, min(to, len(images)), img)
+}
+
+// storeImage saves the original upload and its thumbnails under the image's
+// folder, filling in their URLs. Files already written are removed on failure.
+func storeImage(ctx context.Context, img *models.Image, data []byte, decoded image.Image) (err error) {
+	folder := path.Join("images", img.OwnerType, img.OwnerID.String(), img.ID.String())
+	put := func(key string, data []byte, contentType string) error {
+		if err := storage.Put(ctx, key, bytes.NewReader(data), int64(len(data)), contentType); err != nil {
+			return err
+		}
+		img.Files = append(img.Files, key)
+		return nil
+	}
+	defer func() {
+		if err != nil {
+			deleteImageFiles(ctx, img)
+		}
+	}()
+
+	original := path.Join(folder, "original"+imageFileTypes[img.ContentType])
+	if err := put(original, data, img.ContentType); err != nil {
+		return err
+	}
+	img.URL = storage.URL(original)
+
+	img.Thumbnails = make([]models.Thumbnail, 0, len(thumbnailSizes))
+	for _, size := range thumbnailSizes {
+		resized := fitImage(decoded, size.Side)
+		thumbnail := models.Thumbnail{
+			Size:   size.Name,
+			Width:  resized.Bounds().Dx(),
+			Height: resized.Bounds().Dy(),
+		}
+		if webpThumbnails {
+			webpData, err := encodeWebP(resized)
+			if err != nil {
+				return err
+			}
+			webpKey := path.Join(folder, size.Name+".webp")
+			if err := put(webpKey, webpData, "image/webp"); err != nil {
+				return err
+			}
+			thumbnail.WebPURL = storage.URL(webpKey)
+		}
+		jpegData, err := encodeJPEG(resized)
+		if err != nil {
+			return err
+		}
+		jpegKey := path.Join(folder, size.Name+".jpg")
+		if err := put(jpegKey, jpegData, "image/jpeg"); err != nil {
+			return err
+		}
+		thumbnail.JPEGURL = storage.URL(jpegKey)
+		img.Thumbnails = append(img.Thumbnails, thumbnail)
+	}
+	return nil
+}
+
+// deleteImageFiles removes an image's files from storage. The image is already
+// gone by then, so a failure only leaves files behind and is logged.
+func deleteImageFiles(ctx context.Context, img *models.Image) {
+	if err := storage.Delete(ctx, img.Files...); err != nil {
+		log.Printf("Image %s: failed to delete files: %v", img.ID, err)
+	}
+}
+
+// readImageFile reads an uploaded image, checking its size and that it decodes
+func readImageFile(header *multipart.FileHeader) ([]byte, image.Image, string, error) {
+	if header.Size > maxImageFileSize {
+		return nil, nil, "", errImageTooLarge
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, nil, "", errors.New("Failed to read file")
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, maxImageFileSize+1))
+	if err != nil {
+		return nil, nil, "", errors.New("Failed to read file")
+	}
+	if len(data) > maxImageFileSize {
+		return nil, nil, "", errImageTooLarge
+	}
+	decoded, contentType, err := decodeImage(data)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return data, decoded, contentType, nil
+}
+
+// uploadImage adds an uploaded image to an owner's gallery
+func uploadImage(c *gin.Context, owner imageOwner) {
+	db := config.GetDB()
+
+	ownerID, err := owner.find(db, c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": owner.NotFound})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload image"})
+		return
+	}
+
+	var form ImageUpload
+	if err := c.ShouldBind(&form); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	data, decoded, contentType, err := readImageFile(header)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	img := models.Image{
+		ID:          uuid.New(),
+		OwnerID:     ownerID,
+		OwnerType:   owner.Type,
+		FileName:    filepath.Base(header.Filename),
+		ContentType: contentType,
+		FileSize:    int64(len(data)),
+		Width:       decoded.Bounds().Dx(),
+		Height:      decoded.Bounds().Dy(),
+		AltText:     strings.TrimSpace(form.AltText),
+	}
+	ctx := c.Request.Context()
+	if err := storeImage(ctx, &img, data, decoded); err != nil {
+		log.Printf("Image %s: failed to store: %v", img.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		images, err := owner.gallery(tx, ownerID)
+		if err != nil {
+			return err
+		}
+		img.Position = len(images)
+		if err := tx.Create(&img).Error; err != nil {
+			return err
+		}
+		images = append(images, img)
+		if form.Position != nil {
+			images = moveImage(images, len(images)-1, *form.Position)
+		}
+		return owner.arrange(tx, ownerID, images)
+	})
+	if err != nil {
+		deleteImageFiles(ctx, &img)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save image"})
+		return
+	}
+	db.First(&img, "id = ?", img.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Image uploaded",
+		"image":   img,
+	})
+}
+
+// updateImage changes an image's alt text or moves it within the gallery
+func updateImage(c *gin.Context, owner imageOwner) {
+	db := config.GetDB()
+
+	ownerID, err := owner.find(db, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": owner.NotFound})
+		return
+	}
+	img, err := owner.image(db, ownerID, c.Param("imageId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	var req UpdateImageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if req.AltText != nil {
+			if err := tx.Model(&img).Update("alt_text", strings.TrimSpace(*req.AltText)).Error; err != nil {
+				return err
+			}
+		}
+		if req.Position == nil {
+			return nil
+		}
+		images, err := owner.gallery(tx, ownerID)
+		if err != nil {
+			return err
+		}
+		from := slices.IndexFunc(images, func(other models.Image) bool { return other.ID == img.ID })
+		return owner.arrange(tx, ownerID, moveImage(images, from, *req.Position))
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update image"})
+		return
+	}
+	db.First(&img, "id = ?", img.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Image updated",
+		"image":   img,
+	})
+}
+
+// reorderImages puts a whole gallery in the order given
+func reorderImages(c *gin.Context, owner imageOwner) {
+	db := config.GetDB()
+
+	ownerID, err := owner.find(db, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": owner.NotFound})
+		return
+	}
+
+	var req ReorderImagesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var images []models.Image
+	err = db.Transaction(func(tx *gorm.DB) error {
+		current, err := owner.gallery(tx, ownerID)
+		if err != nil {
+			return err
+		}
+		if len(req.ImageIDs) != len(current) {
+			return errImageOrder
+		}
+		byID := make(map[string]models.Image, len(current))
+		for _, img := range current {
+			byID[img.ID.String()] = img
+		}
+		for _, id := range req.ImageIDs {
+			img, ok := byID[strings.ToLower(id)]
+			if !ok {
+				return errImageOrder
+			}
+			delete(byID, img.ID.String())
+			images = append(images, img)
+		}
+		return owner.arrange(tx, ownerID, images)
+	})
+	if errors.Is(err, errImageOrder) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder images"})
+		return
+	}
+	images, _ = owner.gallery(db, ownerID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Images reordered",
+		"images":  images,
+	})
+}
+
+// deleteImage removes an image from the gallery and its files from storage
+func deleteImage(c *gin.Context, owner imageOwner) {
+	db := config.GetDB()
+
+	ownerID, err := owner.find(db, c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": owner.NotFound})
+		return
+	}
+	img, err := owner.image(db, ownerID, c.Param("imageId"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Image not found"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&img).Error; err != nil {
+			return err
+		}
+		images, err := owner.gallery(tx, ownerID)
+		if err != nil {
+			return err
+		}
+		return owner.arrange(tx, ownerID, images)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete image"})
+		return
+	}
+	deleteImageFiles(c.Request.Context(), &img)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Image deleted"})
+}
+
+// UploadProductImage godoc
+// @Summary Upload a product image (Admin only)
+// @Description Add a JPEG, PNG or WebP image of up to 10 MB to the product's gallery. Small and medium thumbnails are made in JPEG, and in WebP when the server is built with cgo. The first image in the gallery becomes the product's image_url.
+// @Tags Admin - Products
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param file formData file true "Image file"
+// @Param alt_text formData string false "Text describing the image for screen readers and search engines"
+// @Param position formData int false "Place in the gallery, 0 for the cover; last when empty"
+// @Success 201 {object} map[string]interface{} "Image uploaded"
+// @Failure 400 {object} map[string]interface{} "Missing, unsupported or oversized file"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/{id}/images [post]
+func UploadProductImage(c *gin.Context) {
+	uploadImage(c, productImages)
+}
+
+// UpdateProductImage godoc
+// @Summary Update a product image (Admin only)
+// @Description Change an image's alt text or move it to another place in the product's gallery
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param imageId path string true "Image ID"
+// @Param request body UpdateImageRequest true "Alt text and position"
+// @Success 200 {object} map[string]interface{} "Image updated"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Product or image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/{id}/images/{imageId} [put]
+func UpdateProductImage(c *gin.Context) {
+	updateImage(c, productImages)
+}
+
+// ReorderProductImages godoc
+// @Summary Reorder a product's images (Admin only)
+// @Description Put the whole gallery in the given order; the first image becomes the product's image_url
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param request body ReorderImagesRequest true "Every image ID in the new order"
+// @Success 200 {object} map[string]interface{} "Images reordered"
+// @Failure 400 {object} map[string]interface{} "image_ids does not match the gallery"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/{id}/images/order [put]
+func ReorderProductImages(c *gin.Context) {
+	reorderImages(c, productImages)
+}
+
+// DeleteProductImage godoc
+// @Summary Delete a product image (Admin only)
+// @Description Remove an image from the product's gallery together with its thumbnails
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param imageId path string true "Image ID"
+// @Success 200 {object} map[string]interface{} "Image deleted"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Product or image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/{id}/images/{imageId} [delete]
+func DeleteProductImage(c *gin.Context) {
+	deleteImage(c, productImages)
+}
+
+// UploadCategoryImage godoc
+// @Summary Upload a category image (Admin only)
+// @Description Add a JPEG, PNG or WebP image of up to 10 MB to the category's gallery. Small and medium thumbnails are made in JPEG, and in WebP when the server is built with cgo. The first image in the gallery becomes the category's image_url.
+// @Tags Admin - Categories
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param file formData file true "Image file"
+// @Param alt_text formData string false "Text describing the image for screen readers and search engines"
+// @Param position formData int false "Place in the gallery, 0 for the cover; last when empty"
+// @Success 201 {object} map[string]interface{} "Image uploaded"
+// @Failure 400 {object} map[string]interface{} "Missing, unsupported or oversized file"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/categories/{id}/images [post]
+func UploadCategoryImage(c *gin.Context) {
+	uploadImage(c, categoryImages)
+}
+
+// UpdateCategoryImage godoc
+// @Summary Update a category image (Admin only)
+// @Description Change an image's alt text or move it to another place in the category's gallery
+// @Tags Admin - Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param imageId path string true "Image ID"
+// @Param request body UpdateImageRequest true "Alt text and position"
+// @Success 200 {object} map[string]interface{} "Image updated"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Category or image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/categories/{id}/images/{imageId} [put]
+func UpdateCategoryImage(c *gin.Context) {
+	updateImage(c, categoryImages)
+}
+
+// ReorderCategoryImages godoc
+// @Summary Reorder a category's images (Admin only)
+// @Description Put the whole gallery in the given order; the first image becomes the category's image_url
+// @Tags Admin - Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param request body ReorderImagesRequest true "Every image ID in the new order"
+// @Success 200 {object} map[string]interface{} "Images reordered"
+// @Failure 400 {object} map[string]interface{} "image_ids does not match the gallery"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Category not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/categories/{id}/images/order [put]
+func ReorderCategoryImages(c *gin.Context) {
+	reorderImages(c, categoryImages)
+}
+
+// DeleteCategoryImage godoc
+// @Summary Delete a category image (Admin only)
+// @Description Remove an image from the category's gallery together with its thumbnails
+// @Tags Admin - Categories
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Category ID"
+// @Param imageId path string true "Image ID"
+// @Success 200 {object} map[string]interface{} "Image deleted"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Category or image not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/categories/{id}/images/{imageId} [delete]
+func DeleteCategoryImage(c *gin.Context) {
+	deleteImage(c, categoryImages)
+}
+
+// ServeUpload godoc
+// @Summary Get an uploaded image
+// @Description Serve a product or category image or thumbnail from file storage. Files never change once stored, so they are cached for a year.
+// @Tags Images
+// @Produce image/jpeg,image/png,image/webp
+// @Param filepath path string true "Storage key, e.g. images/products/{id}/{imageId}/small.webp"
+// @Success 200 {file} file "Image"
+// @Success 304 "Not modified"
+// @Failure 404 {object} map[string]interface{} "File not found"
+// @Router /uploads/{filepath} [get]
+func ServeUpload(c *gin.Context) {
+	key, ok := storage.CleanKey(strings.TrimPrefix(c.Param("filepath"), "/"))
+	if !ok || !strings.HasPrefix(key, "images/") {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	file, info, err := storage.Open(c.Request.Context(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	c.Header("Cache-Control", storage.CacheControl)
+	c.Header("ETag", `"`+key+`"`)
+	c.Header("Content-Type", info.ContentType)
+	c.Header("X-Content-Type-Options", "nosniff")
+	http.ServeContent(c.Writer, c.Request, path.Base(key), info.ModTime, file)
+}
@@ -0,0 +1,608 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errInsufficientPoints = errors.New("Insufficient loyalty points")
+	errInvalidTier        = errors.New("tier must be silver or gold")
+)
+
+// loyaltySettings holds the points program rules. Each value can be overridden
+// through the environment; money values are in baht, e.g. LOYALTY_POINT_VALUE=0.25.
+type loyaltySettings struct {
+	SpendPerPoint models.Money // LOYALTY_SPEND_PER_POINT: spend that earns one point
+	PointValue    models.Money // LOYALTY_POINT_VALUE: checkout discount per point
+	ExpiryMonths  int          // LOYALTY_POINTS_EXPIRY_MONTHS: 0 = points never expire
+	SilverSpend   models.Money // LOYALTY_SILVER_SPEND: 12-month spend for silver
+	GoldSpend     models.Money // LOYALTY_GOLD_SPEND: 12-month spend for gold
+}
+
+func loadLoyaltySettings() loyaltySettings {
+	return loyaltySettings{
+		SpendPerPoint: envMoney("LOYALTY_SPEND_PER_POINT", 2500),
+		PointValue:    envMoney("LOYALTY_POINT_VALUE", 25),
+		ExpiryMonths:  envInt("LOYALTY_POINTS_EXPIRY_MONTHS", 12),
+		SilverSpend:   envMoney("LOYALTY_SILVER_SPEND", 500000),
+		GoldSpend:     envMoney("LOYALTY_GOLD_SPEND", 2000000),
+	}
+}
+
+func envMoney(key string, fallback models.Money) models.Money {
+	if value, err := models.ParseMoney(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return fallback
+}
+
+func envInt(key string, fallback int) int {
+	if value, err := strconv.Atoi(os.Getenv(key)); err == nil && value >= 0 {
+		return value
+	}
+	return fallback
+}
+
+// LoyaltyAdjustmentRequest represents the request body for adjusting a user's points
+type LoyaltyAdjustmentRequest struct {
+	Points    int        `json:"points" binding:"required" example:"500"` // positive to award, negative to deduct
+	Reason    string     `json:"reason" binding:"required" example:"Goodwill for damaged parcel"`
+	ExpiresAt *time.Time `json:"expires_at" example:"2027-06-30T00:00:00+07:00"` // awards only, defaults to the program expiry
+}
+
+// TierPriceInput is one member-only price of a product
+type TierPriceInput struct {
+	VariantID string       `json:"variant_id" example:"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"` // required when the product has several variants
+	Tier      string       `json:"tier" binding:"required" example:"gold" enums:"silver,gold"`
+	Price     models.Money `json:"price" binding:"required" swaggertype:"number" example:"1399.00"`
+}
+
+// SetTierPricesRequest represents the request body for replacing a product's tier prices
+type SetTierPricesRequest struct {
+	TierPrices []TierPriceInput `json:"tier_prices"`
+}
+
+// memberTier derives a user's tier from delivered spend over the last 12 months
+func memberTier(db *gorm.DB, userID uuid.UUID, now time.Time) (string, models.Money, error) {
+	var spend models.Money
+	if err := db.Model(&models.Order{}).
+		Where("user_id = ? AND status = ? AND delivered_at > ?", userID, "delivered", now.AddDate(-1, 0, 0)).
+		Select("COALESCE(SUM(total_amount - shipping_fee), 0)").Scan(&spend).Error; err != nil {
+		return "", 0, err
+	}
+
+	settings := loadLoyaltySettings()
+	switch {
+	case spend >= settings.GoldSpend:
+		return models.TierGold, spend, nil
+	case spend >= settings.SilverSpend:
+		return models.TierSilver, spend, nil
+	}
+	return models.TierMember, spend, nil
+}
+
+// tierPricesFor returns the member prices per product open to the given tier;
+// gold members also get silver prices
+func tierPricesFor(db *gorm.DB, productIDs []uuid.UUID, tier string) (map[uuid.UUID][]models.TierPrice, error) {
+	byProduct := make(map[uuid.UUID][]models.TierPrice)
+	if len(productIDs) == 0 || models.TierRank(tier) == 0 {
+		return byProduct, nil
+	}
+
+	var prices []models.TierPrice
+	if err := db.Where("product_id IN ?", productIDs).Find(&prices).Error; err != nil {
+		return nil, err
+	}
+
+	for _, price := range prices {
+		if models.TierRank(price.Tier) > models.TierRank(tier) {
+			continue
+		}
+		byProduct[price.ProductID] = append(byProduct[price.ProductID], price)
+	}
+	return byProduct, nil
+}
+
+// lowestTierPrice picks the lowest member price covering a variant
+func lowestTierPrice(prices []models.TierPrice, variantID *uuid.UUID) (models.Money, bool) {
+	var best models.Money
+	found := false
+	for _, price := range prices {
+		if !price.CoversVariant(variantID) {
+			continue
+		}
+		if !found || price.Price < best {
+			best = price.Price
+			found = true
+		}
+	}
+	return best, found
+}
+
+// loyaltyBalance sums the unspent, unexpired points of a user
+func loyaltyBalance(db *gorm.DB, userID uuid.UUID, now time.Time) (int, error) {
+	var balance int
+	err := db.Model(&models.LoyaltyEntry{}).
+		Where("user_id = ? AND remaining > 0", userID).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Select("COALESCE(SUM(remaining), 0)").Scan(&balance).Error
+	return balance, err
+}
+
+// pointsDiscount works out how many of the requested points to burn against an
+// amount, never burning more points than the amount needs
+func pointsDiscount(db *gorm.DB, userID uuid.UUID, requested int, amount models.Money) (int, models.Money, error) {
+	balance, err := loyaltyBalance(db, userID, time.Now())
+	if err != nil {
+		return 0, 0, err
+	}
+	if requested > balance {
+		return 0, 0, errInsufficientPoints
+	}
+
+	value := loadLoyaltySettings().PointValue
+	if value <= 0 || amount <= 0 {
+		return 0, 0, nil
+	}
+
+	points := requested
+	if needed := int((amount + value - 1) / value); needed < points {
+		points = needed
+	}
+	return points, value.Mul(points).Min(amount), nil
+}
+
+// creditPoints adds points to a user's ledger, applying the program expiry when
+// the entry does not carry its own
+func creditPoints(tx *gorm.DB, entry *models.LoyaltyEntry) error {
+	if entry.ExpiresAt == nil {
+		if months := loadLoyaltySettings().ExpiryMonths; months > 0 {
+			expiresAt := time.Now().AddDate(0, months, 0)
+			entry.ExpiresAt = &expiresAt
+		}
+	}
+	entry.Remaining = entry.Points
+	return tx.Create(entry).Error
+}
+
+// takePoints consumes points from a user's ledger, soonest-expiring first, and
+// returns how many were taken. Without partial it fails unless all are available.
+func takePoints(tx *gorm.DB, userID uuid.UUID, points int, partial bool) (int, error) {
+	var credits []models.LoyaltyEntry
+	if err := tx.Where("user_id = ? AND remaining > 0", userID).
+		Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("CASE WHEN expires_at IS NULL THEN 1 ELSE 0 END, expires_at ASC, created_at ASC").
+		Find(&credits).Error; err != nil {
+		return 0, err
+	}
+
+	left := points
+	for _, credit := range credits {
+		if left == 0 {
+			break
+		}
+		take := credit.Remaining
+		if take > left {
+			take = left
+		}
+
+		// Guard against a concurrent checkout spending the same points
+		result := tx.Model(&models.LoyaltyEntry{}).
+			Where("id = ? AND remaining >= ?", credit.ID, take).
+			Update("remaining", gorm.Expr("remaining - ?", take))
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, errInsufficientPoints
+		}
+		left -= take
+	}
+	if left > 0 && !partial {
+		return 0, errInsufficientPoints
+	}
+
+	return points - left, nil
+}
+
+// spendPoints takes points from a user and records the entry with the negative change
+func spendPoints(tx *gorm.DB, entry *models.LoyaltyEntry, points int) error {
+	if _, err := takePoints(tx, entry.UserID, points, false); err != nil {
+		return err
+	}
+	entry.Points = -points
+	return tx.Create(entry).Error
+}
+
+// expireLoyaltyPoints writes expiry entries for points that lapsed unspent
+func expireLoyaltyPoints(tx *gorm.DB, userID uuid.UUID, now time.Time) error {
+	var expired []models.LoyaltyEntry
+	if err := tx.Where("user_id = ? AND remaining > 0 AND expires_at <= ?", userID, now).
+		Find(&expired).Error; err != nil {
+		return err
+	}
+
+	for _, credit := range expired {
+		if err := tx.Model(&models.LoyaltyEntry{}).Where("id = ?", credit.ID).Update("remaining", 0).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.LoyaltyEntry{
+			UserID: userID,
+			Type:   models.LoyaltyEntryExpire,
+			Points: -credit.Remaining,
+			Reason: "Points expired",
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasOrderLoyaltyEntry reports whether an order already has a ledger entry of a type
+func hasOrderLoyaltyEntry(tx *gorm.DB, orderID uuid.UUID, entryType string) (bool, error) {
+	var count int64
+	err := tx.Model(&models.LoyaltyEntry{}).Where("order_id = ? AND type = ?", orderID, entryType).Count(&count).Error
+	return count > 0, err
+}
+
+// earnOrderPoints awards points for the goods paid on a delivered order, once
+func earnOrderPoints(tx *gorm.DB, order *models.Order) error {
+	earned, err := hasOrderLoyaltyEntry(tx, order.ID, models.LoyaltyEntryEarn)
+	if err != nil || earned {
+		return err
+	}
+
+	settings := loadLoyaltySettings()
+	if settings.SpendPerPoint <= 0 {
+		return nil
+	}
+	points := int((order.TotalAmount - order.ShippingFee) / settings.SpendPerPoint)
+	if points <= 0 {
+		return nil
+	}
+
+	if err := creditPoints(tx, &models.LoyaltyEntry{
+		UserID:  order.UserID,
+		Type:    models.LoyaltyEntryEarn,
+		Points:  points,
+		OrderID: &order.ID,
+		Reason:  "Points earned on delivered order",
+	}); err != nil {
+		return err
+	}
+
+	order.PointsEarned = points
+	return tx.Model(order).Update("points_earned", points).Error
+}
+
+// reverseOrderPoints undoes the loyalty side of a cancelled or refunded order: points
+// it earned are taken back (as far as the balance allows) and points burned on it
+// are restored
+func reverseOrderPoints(tx *gorm.DB, order *models.Order, actorID *uuid.UUID) error {
+	reversed, err := hasOrderLoyaltyEntry(tx, order.ID, models.LoyaltyEntryReverse)
+	if err != nil {
+		return err
+	}
+
+	var earn models.LoyaltyEntry
+	err = tx.Where("order_id = ? AND type = ?", order.ID, models.LoyaltyEntryEarn).First(&earn).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil && !reversed {
+		// The earned entry itself goes first, then any other points the user holds
+		taken := earn.Remaining
+		if taken > 0 {
+			if err := tx.Model(&models.LoyaltyEntry{}).Where("id = ?", earn.ID).Update("remaining", 0).Error; err != nil {
+				return err
+			}
+		}
+		if rest := earn.Points - taken; rest > 0 {
+			more, err := takePoints(tx, order.UserID, rest, true)
+			if err != nil {
+				return err
+			}
+			taken += more
+		}
+		if err := tx.Create(&models.LoyaltyEntry{
+			UserID:  order.UserID,
+			Type:    models.LoyaltyEntryReverse,
+			Points:  -taken,
+			OrderID: &order.ID,
+			ActorID: actorID,
+			Reason:  "Points reversed for " + order.Status + " order",
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	if order.PointsRedeemed > 0 {
+		restored, err := hasOrderLoyaltyEntry(tx, order.ID, models.LoyaltyEntryRestore)
+		if err != nil || restored {
+			return err
+		}
+		return creditPoints(tx, &models.LoyaltyEntry{
+			UserID:  order.UserID,
+			Type:    models.LoyaltyEntryRestore,
+			Points:  order.PointsRedeemed,
+			OrderID: &order.ID,
+			ActorID: actorID,
+			Reason:  "Points returned for " + order.Status + " order",
+		})
+	}
+
+	return nil
+}
+
+// loyaltyResponse expires lapsed points and renders balance, tier and ledger
+func loyaltyResponse(db *gorm.DB, userID uuid.UUID) (gin.H, error) {
+	now := time.Now()
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return expireLoyaltyPoints(tx, userID, now)
+	}); err != nil {
+		return nil, err
+	}
+
+	balance, err := loyaltyBalance(db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	tier, spend, err := memberTier(db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []models.LoyaltyEntry
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	settings := loadLoyaltySettings()
+	response := gin.H{
+		"points":          balance,
+		"points_value":    settings.PointValue.Mul(balance),
+		"point_value":     settings.PointValue,
+		"spend_per_point": settings.SpendPerPoint,
+		"tier":            tier,
+		"spend_12_months": spend,
+		"entries":         entries,
+	}
+	switch tier {
+	case models.TierMember:
+		response["next_tier"] = models.TierSilver
+		response["next_tier_spend"] = settings.SilverSpend - spend
+	case models.TierSilver:
+		response["next_tier"] = models.TierGold
+		response["next_tier_spend"] = settings.GoldSpend - spend
+	}
+
+	return response, nil
+}
+
+// GetLoyalty godoc
+// @Summary Get my loyalty points
+// @Description Get the authenticated user's points balance, member tier and points ledger
+// @Tags Loyalty
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Balance, tier and ledger entries"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /loyalty [get]
+func GetLoyalty(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	response, err := loyaltyResponse(config.GetDB(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loyalty points"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetUserLoyalty godoc
+// @Summary Get a user's loyalty points (Admin only)
+// @Description Get a customer's points balance, member tier and full points ledger
+// @Tags Admin - Loyalty
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "Balance, tier and ledger entries"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/users/{id}/loyalty [get]
+func GetUserLoyalty(c *gin.Context) {
+	var user models.User
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	response, err := loyaltyResponse(config.GetDB(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loyalty points"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AdjustUserLoyalty godoc
+// @Summary Adjust a user's loyalty points (Admin only)
+// @Description Award (positive) or deduct (negative) points. Every adjustment is kept in the ledger with the admin who made it.
+// @Tags Admin - Loyalty
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body LoyaltyAdjustmentRequest true "Adjustment"
+// @Success 201 {object} map[string]interface{} "Points adjusted"
+// @Failure 400 {object} map[string]interface{} "Bad request or insufficient points"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/users/{id}/loyalty/adjustments [post]
+func AdjustUserLoyalty(c *gin.Context) {
+	var user models.User
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req LoyaltyAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	adminID, _ := uuid.Parse(c.GetString("user_id"))
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if req.Points > 0 {
+			return creditPoints(tx, &models.LoyaltyEntry{
+				UserID:    user.ID,
+				Type:      models.LoyaltyEntryAdjust,
+				Points:    req.Points,
+				ExpiresAt: req.ExpiresAt,
+				Reason:    req.Reason,
+				ActorID:   &adminID,
+			})
+		}
+		return spendPoints(tx, &models.LoyaltyEntry{
+			UserID:  user.ID,
+			Type:    models.LoyaltyEntryAdjust,
+			Reason:  req.Reason,
+			ActorID: &adminID,
+		}, -req.Points)
+	})
+	if errors.Is(err, errInsufficientPoints) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust loyalty points"})
+		return
+	}
+
+	response, err := loyaltyResponse(config.GetDB(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch loyalty points"})
+		return
+	}
+	response["message"] = "Loyalty points adjusted successfully"
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// SetTierPrices godoc
+// @Summary Set member tier prices for a product (Admin only)
+// @Description Replace the silver/gold member-only prices of a product. Each price is for one variant; products with a single variant may leave variant_id out. Send an empty list to remove them.
+// @Tags Admin - Loyalty
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param request body SetTierPricesRequest true "Tier prices"
+// @Success 200 {object} map[string]interface{} "Tier prices updated"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/{id}/tier-prices [put]
+func SetTierPrices(c *gin.Context) {
+	var product models.Product
+	if err := config.GetDB().Preload("Variants").Where("id = ?", c.Param("id")).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	variantPrices := make(map[string]models.Money, len(product.Variants))
+	for _, variant := range product.Variants {
+		variantPrices[variant.ID.String()] = variant.Price
+	}
+
+	var req SetTierPricesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prices := make([]models.TierPrice, 0, len(req.TierPrices))
+	seen := make(map[string]bool)
+	for _, input := range req.TierPrices {
+		if models.TierRank(input.Tier) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidTier.Error()})
+			return
+		}
+		key := input.VariantID + "/" + input.Tier
+		if seen[key] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "each tier can only have one price per variant"})
+			return
+		}
+
+		price := models.TierPrice{ProductID: product.ID, Tier: input.Tier, Price: input.Price}
+		regularPrice := product.Price
+		if input.VariantID == "" && len(product.Variants) > 1 {
+			// One fixed price for every pack size could sell a large pack below a small one
+			c.JSON(http.StatusBadRequest, gin.H{"error": errVariantRequired.Error()})
+			return
+		}
+		if input.VariantID != "" {
+			variantPrice, ok := variantPrices[input.VariantID]
+			if !ok {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "variant_id does not belong to the product"})
+				return
+			}
+			variantID, _ := uuid.Parse(input.VariantID)
+			price.VariantID = &variantID
+			regularPrice = variantPrice
+		}
+		if input.Price <= 0 || input.Price >= regularPrice {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "tier price must be greater than 0 and lower than the regular price"})
+			return
+		}
+		seen[key] = true
+		prices = append(prices, price)
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", product.ID).Delete(&models.TierPrice{}).Error; err != nil {
+			return err
+		}
+		if len(prices) == 0 {
+			return nil
+		}
+		return tx.Create(&prices).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tier prices"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Tier prices updated successfully",
+		"tier_prices": prices,
+	})
+}
@@ -0,0 +1,59 @@
+//go:build cgo
+
+package controllers
+
+import (
+	"net/http"
+	"pet-food-ecommerce/models"
+	"testing"
+)
+
+func TestSetTierPricesRequiresVariantOnMultiVariantProducts(t *testing.T) {
+	db := newTestDB(t)
+	admin := models.User{Role: "admin"}
+	product := createTestProduct(t, db, 30000, 300000) // 1kg and 15kg bags
+	path := "/products/" + product.ID.String() + "/tier-prices"
+
+	code, out := serveTest(t, SetTierPrices, http.MethodPut, "/products/:id/tier-prices", path, admin, map[string]interface{}{
+		"tier_prices": []map[string]interface{}{{"tier": "gold", "price": 250}},
+	})
+	if code != http.StatusBadRequest {
+		t.Fatalf("product-wide tier price on two variants: status %d %v, want 400", code, out)
+	}
+
+	code, out = serveTest(t, SetTierPrices, http.MethodPut, "/products/:id/tier-prices", path, admin, map[string]interface{}{
+		"tier_prices": []map[string]interface{}{{"tier": "gold", "price": 250, "variant_id": product.Variants[0].ID}},
+	})
+	if code != http.StatusOK {
+		t.Fatalf("tier price on the 1kg bag: status %d %v, want 200", code, out)
+	}
+	var prices []models.TierPrice
+	db.Where("product_id = ?", product.ID).Find(&prices)
+	if price, ok := lowestTierPrice(prices, &product.Variants[1].ID); ok {
+		t.Errorf("15kg bag got tier price %s, want its regular price", price)
+	}
+}
+
+func TestAddingVariantPinsProductWideTierPrices(t *testing.T) {
+	db := newTestDB(t)
+	admin := models.User{Role: "admin"}
+	product := createTestProduct(t, db, 30000)
+	db.Create(&models.TierPrice{ProductID: product.ID, Tier: models.TierGold, Price: 25000})
+
+	code, out := serveTest(t, CreateProductVariant, http.MethodPost, "/products/:id/variants", "/products/"+product.ID.String()+"/variants", admin,
+		map[string]interface{}{"name": "15kg", "price": 3000, "stock": 10})
+	if code != http.StatusCreated {
+		t.Fatalf("add variant: status %d %v, want 201", code, out)
+	}
+
+	var prices []models.TierPrice
+	db.Where("product_id = ?", product.ID).Find(&prices)
+	if price, ok := lowestTierPrice(prices, &product.Variants[0].ID); !ok || price != 25000 {
+		t.Errorf("1kg bag tier price %s (found %v), want 250.00", price, ok)
+	}
+	var added models.ProductVariant
+	db.Where("product_id = ? AND name = ?", product.ID, "15kg").First(&added)
+	if price, ok := lowestTierPrice(prices, &added.ID); ok {
+		t.Errorf("new 15kg bag got tier price %s, want its regular price", price)
+	}
+}
@@ -0,0 +1,116 @@
+package controllers
+
+import (
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const maxNotifications = 50
+
+// notifyUser puts a notification in the user's inbox. Pass the transaction
+// that made the change it is about, so it is only sent if that commits.
+func notifyUser(tx *gorm.DB, notification *models.Notification) error {
+	return tx.Create(notification).Error
+}
+
+// GetNotifications godoc
+// @Summary Get my notifications
+// @Description List the current user's latest 50 notifications, newest first, with the number still unread
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param unread query bool false "Only unread notifications"
+// @Success 200 {object} map[string]interface{} "Notifications and unread count"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /notifications [get]
+func GetNotifications(c *gin.Context) {
+	db := config.GetDB()
+	userID := c.GetString("user_id")
+
+	query := db.Where("user_id = ?", userID)
+	if c.Query("unread") == "true" {
+		query = query.Where("read_at IS NULL")
+	}
+	var notifications []models.Notification
+	if err := query.Order("created_at DESC").Limit(maxNotifications).Find(&notifications).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+	var unread int64
+	if err := db.Model(&models.Notification{}).Where("user_id = ? AND read_at IS NULL", userID).
+		Count(&unread).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": notifications,
+		"unread":        unread,
+	})
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark a notification as read
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Notification ID"
+// @Success 200 {object} map[string]interface{} "Notification marked as read"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Notification not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /notifications/{id}/read [put]
+func MarkNotificationRead(c *gin.Context) {
+	db := config.GetDB()
+
+	var notification models.Notification
+	if err := db.Where("id = ? AND user_id = ?", c.Param("id"), c.GetString("user_id")).
+		First(&notification).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+	if notification.ReadAt == nil {
+		now := time.Now()
+		if err := db.Model(&notification).Update("read_at", now).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notification"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Notification marked as read",
+		"notification": notification,
+	})
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary Mark all notifications as read
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Notifications marked as read"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /notifications/read [put]
+func MarkAllNotificationsRead(c *gin.Context) {
+	result := config.GetDB().Model(&models.Notification{}).
+		Where("user_id = ? AND read_at IS NULL", c.GetString("user_id")).Update("read_at", time.Now())
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Notifications marked as read",
+		"updated": result.RowsAffected,
+	})
+}
@@ -1,9 +1,11 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"pet-food-ecommerce/config"
 	"pet-food-ecommerce/models"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -12,25 +14,32 @@ import (
 
 // CreateOrderRequest represents the request body for creating an order
 type CreateOrderRequest struct {
-	ShippingAddress string `json:"shipping_address" binding:"required" example:"123 Main St, Bangkok 10110"`
+	ShippingAddress   string       `json:"shipping_address" binding:"required" example:"123 Main St, Bangkok 10110"`
+	PaymentMethod     string       `json:"payment_method" example:"cod" enums:"cod,bank_transfer,promptpay,card"`
+	UseStoreCredit    bool         `json:"use_store_credit" example:"false"`
+	StoreCreditAmount models.Money `json:"store_credit_amount" swaggertype:"number" example:"0"` // optional cap, 0 = as much as needed
+	GiftCardCodes     []string     `json:"gift_card_codes"`
+	RedeemPoints      int          `json:"redeem_points" binding:"min=0" example:"0"` // loyalty points to spend as a discount
 }
 
 // UpdateOrderStatusRequest represents the request body for updating order status
 type UpdateOrderStatusRequest struct {
-	Status string `json:"status" binding:"required" example:"processing" enums:"pending,processing,shipped,delivered,cancelled"`
+	Status string `json:"status" binding:"required" example:"processing" enums:"pending,processing,shipped,delivered,cancelled,refunded"`
 }
 
 // CreateOrder godoc
 // @Summary Create a new order
-// @Description Create an order from the items in the user's cart
+// @Description Create an order from the items in the user's cart. Loyalty points are taken off the price; gift cards and store credit are applied first and the payment method covers the rest.
 // @Tags Orders
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param request body CreateOrderRequest true "Shipping address"
+// @Param request body CreateOrderRequest true "Shipping address and payment"
 // @Success 201 {object} map[string]interface{} "Order created successfully"
 // @Failure 400 {object} map[string]interface{} "Bad request - empty cart or insufficient stock"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Prescription diet without a valid prescription"
+// @Failure 409 {object} map[string]interface{} "Flash sale sold out or referral discount already used during checkout"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /orders [post]
 func CreateOrder(c *gin.Context) {
@@ -43,9 +52,17 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
+	if req.PaymentMethod == "" {
+		req.PaymentMethod = models.PaymentMethodCOD
+	}
+	if !isExternalPaymentMethod(req.PaymentMethod) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errInvalidPaymentMethod.Error()})
+		return
+	}
+
 	// Get user's cart items
 	var cartItems []models.Cart
-	if err := config.GetDB().Preload("Product").Where("user_id = ?", userID).Find(&cartItems).Error; err != nil {
+	if err := config.GetDB().Preload("Product").Preload("Variant").Where("user_id = ?", userID).Find(&cartItems).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cart"})
 		return
 	}
@@ -55,27 +72,6 @@ func CreateOrder(c *gin.Context) {
 		return
 	}
 
-	// Calculate total and prepare order items
-	var totalAmount float64
-	var orderItems []models.OrderItem
-
-	for _, item := range cartItems {
-		// Check stock availability
-		if item.Product.Stock < item.Quantity {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "Insufficient stock for product: " + item.Product.Name,
-			})
-			return
-		}
-
-		totalAmount += item.Product.Price * float64(item.Quantity)
-		orderItems = append(orderItems, models.OrderItem{
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			Price:     item.Product.Price,
-		})
-	}
-
 	// Start transaction
 	tx := config.GetDB().Begin()
 	defer func() {
@@ -84,37 +80,17 @@ func CreateOrder(c *gin.Context) {
 		}
 	}()
 
-	// Create order
 	userUUID, _ := uuid.Parse(userID)
-	order := models.Order{
-		UserID:          userUUID,
-		TotalAmount:     totalAmount,
-		Status:          "pending",
-		ShippingAddress: req.ShippingAddress,
-	}
-
-	if err := tx.Create(&order).Error; err != nil {
+	order := models.Order{UserID: userUUID}
+	if err := placeOrder(tx, &order, cartItems, &req, pricingOptions{RedeemPoints: req.RedeemPoints}); err != nil {
 		tx.Rollback()
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
-		return
-	}
-
-	// Create order items and update stock
-	for i := range orderItems {
-		orderItems[i].OrderID = order.ID
-		if err := tx.Create(&orderItems[i]).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order items"})
-			return
-		}
-
-		// Update product stock
-		if err := tx.Model(&models.Product{}).Where("id = ?", orderItems[i].ProductID).
-			Update("stock", gorm.Expr("stock - ?", orderItems[i].Quantity)).Error; err != nil {
-			tx.Rollback()
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update stock"})
+		var checkoutErr *checkoutError
+		if errors.As(err, &checkoutErr) {
+			c.JSON(checkoutErr.Status, gin.H{"error": checkoutErr.Error()})
 			return
 		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create order"})
+		return
 	}
 
 	// Clear cart
@@ -123,6 +99,11 @@ func CreateOrder(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
 		return
 	}
+	if err := tx.Where("user_id = ?", userID).Delete(&models.CartCoupon{}).Error; err != nil {
+		tx.Rollback()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to clear cart"})
+		return
+	}
 
 	// Commit transaction
 	if err := tx.Commit().Error; err != nil {
@@ -131,7 +112,7 @@ func CreateOrder(c *gin.Context) {
 	}
 
 	// Reload order with items
-	config.GetDB().Preload("OrderItems.Product").First(&order, order.ID)
+	config.GetDB().Preload("OrderItems.Product", withArchived).Preload("Payments").First(&order, order.ID)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Order created successfully",
@@ -154,7 +135,7 @@ func GetOrders(c *gin.Context) {
 	userID := c.GetString("user_id")
 
 	var orders []models.Order
-	if err := config.GetDB().Preload("OrderItems.Product").Where("user_id = ?", userID).
+	if err := config.GetDB().Preload("OrderItems.Product", withArchived).Where("user_id = ?", userID).
 		Order("created_at DESC").Find(&orders).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
 		return
@@ -180,7 +161,7 @@ func GetOrder(c *gin.Context) {
 	orderID := c.Param("id")
 
 	var order models.Order
-	if err := config.GetDB().Preload("OrderItems.Product").Preload("OrderItems.Product.Category").
+	if err := config.GetDB().Preload("OrderItems.Product", withArchived).Preload("OrderItems.Product.Category", withArchived).
 		Where("id = ? AND user_id = ?", orderID, userID).First(&order).Error; err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Order not found"})
 		return
@@ -191,7 +172,7 @@ func GetOrder(c *gin.Context) {
 
 // UpdateOrderStatus godoc
 // @Summary Update order status (Admin only)
-// @Description Update the status of an order
+// @Description Update the status of an order. Cancelled and refunded orders keep their status.
 // @Tags Admin - Orders
 // @Accept json
 // @Produce json
@@ -203,6 +184,7 @@ func GetOrder(c *gin.Context) {
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
 // @Failure 404 {object} map[string]interface{} "Order not found"
+// @Failure 409 {object} map[string]interface{} "Order is already cancelled or refunded, or changed meanwhile"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /admin/orders/{id}/status [put]
 func UpdateOrderStatus(c *gin.Context) {
@@ -216,7 +198,7 @@ func UpdateOrderStatus(c *gin.Context) {
 	}
 
 	// Validate status
-	validStatuses := []string{"pending", "processing", "shipped", "delivered", "cancelled"}
+	validStatuses := []string{"pending", "processing", "shipped", "delivered", "cancelled", "refunded"}
 	isValid := false
 	for _, status := range validStatuses {
 		if req.Status == status {
@@ -236,8 +218,38 @@ func UpdateOrderStatus(c *gin.Context) {
 		return
 	}
 
+	// Cancelling or refunding has given back the payments, points and referral,
+	// so the order cannot be reopened
+	if (order.Status == "cancelled" || order.Status == "refunded") && req.Status != order.Status {
+		c.JSON(http.StatusConflict, gin.H{"error": "Order is already " + order.Status + " and its status can no longer change"})
+		return
+	}
+
+	previousStatus := order.Status
 	order.Status = req.Status
-	if err := config.GetDB().Save(&order).Error; err != nil {
+
+	var actorID *uuid.UUID
+	if adminID, err := uuid.Parse(c.GetString("user_id")); err == nil {
+		actorID = &adminID
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		// Move only from the status read above: of two changes at once, the
+		// second finds the row changed and runs none of the side effects
+		result := tx.Model(&order).Where("status = ?", previousStatus).Update("status", order.Status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errOrderStatusChanged
+		}
+		return onOrderStatusChange(tx, &order, previousStatus, actorID)
+	})
+	if errors.Is(err, errOrderStatusChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update order status"})
 		return
 	}
@@ -248,6 +260,48 @@ func UpdateOrderStatus(c *gin.Context) {
 	})
 }
 
+var errOrderStatusChanged = errors.New("Order status was changed by another request, please reload the order")
+
+// onOrderStatusChange runs the side effects of an order moving between statuses
+func onOrderStatusChange(tx *gorm.DB, order *models.Order, previousStatus string, actorID *uuid.UUID) error {
+	if order.Status == previousStatus {
+		return nil
+	}
+
+	switch order.Status {
+	case "delivered":
+		now := time.Now()
+		order.DeliveredAt = &now
+		if err := tx.Model(order).Update("delivered_at", now).Error; err != nil {
+			return err
+		}
+		if err := earnOrderPoints(tx, order); err != nil {
+			return err
+		}
+		if err := convertReferral(tx, order, actorID); err != nil {
+			return err
+		}
+	case "cancelled", "refunded":
+		if err := refundOrderPayments(tx, order, actorID); err != nil {
+			return err
+		}
+		if err := reverseOrderPoints(tx, order, actorID); err != nil {
+			return err
+		}
+		if err := releaseReferral(tx, order); err != nil {
+			return err
+		}
+		if err := releaseCoupon(tx, order); err != nil {
+			return err
+		}
+		if err := releaseFlashSaleStock(tx, order); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // GetAllOrders godoc
 // @Summary Get all orders (Admin only)
 // @Description Get all orders in the system with user details
@@ -262,7 +316,7 @@ func UpdateOrderStatus(c *gin.Context) {
 // @Router /admin/orders [get]
 func GetAllOrders(c *gin.Context) {
 	var orders []models.Order
-	if err := config.GetDB().Preload("User").Preload("OrderItems.Product").
+	if err := config.GetDB().Preload("User").Preload("OrderItems.Product", withArchived).
 		Order("created_at DESC").Find(&orders).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch orders"})
 		return
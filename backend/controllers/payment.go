@@ -0,0 +1,284 @@
+package controllers
+
+import (
+	"errors"
+	"pet-food-ecommerce/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errInvalidPaymentMethod    = errors.New("Invalid payment method")
+	errGiftCardNotFound        = errors.New("Gift card not found")
+	errGiftCardUnusable        = errors.New("Gift card is inactive, expired or has no balance")
+	errInsufficientStoreCredit = errors.New("Insufficient store credit")
+)
+
+// externalPaymentMethods can settle whatever store credit and gift cards do not cover
+var externalPaymentMethods = []string{
+	models.PaymentMethodCOD,
+	models.PaymentMethodBankTransfer,
+	models.PaymentMethodPromptPay,
+	models.PaymentMethodCard,
+}
+
+func isExternalPaymentMethod(method string) bool {
+	for _, m := range externalPaymentMethods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// payOrder splits the order total across gift cards, then store credit, then the
+// external payment method, and records one OrderPayment per tender
+func payOrder(tx *gorm.DB, order *models.Order, req *CreateOrderRequest) error {
+	remaining := order.TotalAmount
+	method := req.PaymentMethod
+	now := time.Now()
+
+	for _, code := range req.GiftCardCodes {
+		if remaining == 0 {
+			break
+		}
+
+		var card models.GiftCard
+		if err := tx.Where("code = ?", normalizeGiftCardCode(code)).First(&card).Error; err != nil {
+			return errGiftCardNotFound
+		}
+		if !card.IsUsable(now) || card.Currency != order.Currency {
+			return errGiftCardUnusable
+		}
+
+		amount := card.Balance.Min(remaining)
+		if err := changeGiftCardBalance(tx, &card, -amount, models.GiftCardTxRedeem, &order.ID, &order.UserID, "Order payment"); err != nil {
+			return err
+		}
+		if err := tx.Create(&models.OrderPayment{
+			OrderID:    order.ID,
+			Method:     models.PaymentMethodGiftCard,
+			Amount:     amount,
+			GiftCardID: &card.ID,
+		}).Error; err != nil {
+			return err
+		}
+
+		remaining -= amount
+		method = models.PaymentMethodGiftCard
+	}
+
+	if req.UseStoreCredit && remaining > 0 {
+		balance, err := walletBalance(tx, order.UserID, now)
+		if err != nil {
+			return err
+		}
+
+		amount := balance.Min(remaining)
+		if req.StoreCreditAmount > 0 {
+			amount = amount.Min(req.StoreCreditAmount)
+		}
+		if amount > 0 {
+			if err := debitWallet(tx, order.UserID, amount, "Order payment", &order.ID, nil); err != nil {
+				return err
+			}
+			if err := tx.Create(&models.OrderPayment{
+				OrderID: order.ID,
+				Method:  models.PaymentMethodStoreCredit,
+				Amount:  amount,
+			}).Error; err != nil {
+				return err
+			}
+
+			remaining -= amount
+			method = models.PaymentMethodStoreCredit
+		}
+	}
+
+	if remaining > 0 {
+		method = req.PaymentMethod
+		if err := tx.Create(&models.OrderPayment{
+			OrderID: order.ID,
+			Method:  method,
+			Amount:  remaining,
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	order.AmountDue = remaining
+	order.PaymentMethod = method
+	return tx.Model(order).Updates(map[string]interface{}{
+		"amount_due":     order.AmountDue,
+		"payment_method": order.PaymentMethod,
+	}).Error
+}
+
+// refundOrderPayments returns store credit and gift card tenders of a cancelled order
+func refundOrderPayments(tx *gorm.DB, order *models.Order, actorID *uuid.UUID) error {
+	var payments []models.OrderPayment
+	if err := tx.Where("order_id = ? AND refunded = ?", order.ID, false).Find(&payments).Error; err != nil {
+		return err
+	}
+
+	for _, payment := range payments {
+		switch payment.Method {
+		case models.PaymentMethodStoreCredit:
+			if err := creditWallet(tx, &models.WalletEntry{
+				UserID:  order.UserID,
+				Amount:  payment.Amount,
+				Reason:  "Refund for cancelled order",
+				OrderID: &order.ID,
+				ActorID: actorID,
+			}); err != nil {
+				return err
+			}
+		case models.PaymentMethodGiftCard:
+			var card models.GiftCard
+			if err := tx.Where("id = ?", payment.GiftCardID).First(&card).Error; err != nil {
+				return err
+			}
+			if err := changeGiftCardBalance(tx, &card, payment.Amount, models.GiftCardTxRefund, &order.ID, actorID, "Refund for cancelled order"); err != nil {
+				return err
+			}
+		default:
+			continue
+		}
+
+		if err := tx.Model(&payment).Update("refunded", true).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// walletBalance sums the unspent, unexpired store credit of a user
+func walletBalance(db *gorm.DB, userID uuid.UUID, now time.Time) (models.Money, error) {
+	var credits []models.WalletEntry
+	if err := db.Where("user_id = ? AND type = ? AND remaining > 0", userID, models.WalletEntryCredit).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Find(&credits).Error; err != nil {
+		return 0, err
+	}
+
+	var balance models.Money
+	for _, credit := range credits {
+		balance += credit.Remaining
+	}
+	return balance, nil
+}
+
+// creditWallet adds store credit to a user's ledger
+func creditWallet(tx *gorm.DB, entry *models.WalletEntry) error {
+	entry.Type = models.WalletEntryCredit
+	entry.Remaining = entry.Amount
+	return tx.Create(entry).Error
+}
+
+// debitWallet spends store credit, consuming the soonest-expiring credits first
+func debitWallet(tx *gorm.DB, userID uuid.UUID, amount models.Money, reason string, orderID, actorID *uuid.UUID) error {
+	now := time.Now()
+
+	var credits []models.WalletEntry
+	if err := tx.Where("user_id = ? AND type = ? AND remaining > 0", userID, models.WalletEntryCredit).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Order("CASE WHEN expires_at IS NULL THEN 1 ELSE 0 END, expires_at ASC, created_at ASC").
+		Find(&credits).Error; err != nil {
+		return err
+	}
+
+	left := amount
+	for _, credit := range credits {
+		if left == 0 {
+			break
+		}
+		take := credit.Remaining.Min(left)
+
+		// Guard against a concurrent checkout spending the same credit
+		result := tx.Model(&models.WalletEntry{}).
+			Where("id = ? AND remaining >= ?", credit.ID, take).
+			Update("remaining", gorm.Expr("remaining - ?", take))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errInsufficientStoreCredit
+		}
+		left -= take
+	}
+	if left > 0 {
+		return errInsufficientStoreCredit
+	}
+
+	return tx.Create(&models.WalletEntry{
+		UserID:  userID,
+		Type:    models.WalletEntryDebit,
+		Amount:  amount,
+		Reason:  reason,
+		OrderID: orderID,
+		ActorID: actorID,
+	}).Error
+}
+
+// expireWalletCredits writes expiry entries for credits that lapsed with money left on them
+func expireWalletCredits(tx *gorm.DB, userID uuid.UUID, now time.Time) error {
+	var expired []models.WalletEntry
+	if err := tx.Where("user_id = ? AND type = ? AND remaining > 0 AND expires_at <= ?",
+		userID, models.WalletEntryCredit, now).Find(&expired).Error; err != nil {
+		return err
+	}
+
+	for _, credit := range expired {
+		// Update through a fresh model so credit.Remaining still holds the expired amount
+		if err := tx.Model(&models.WalletEntry{}).Where("id = ?", credit.ID).Update("remaining", models.Money(0)).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.WalletEntry{
+			UserID: userID,
+			Type:   models.WalletEntryExpiry,
+			Amount: credit.Remaining,
+			Reason: "Store credit expired",
+		}).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// changeGiftCardBalance applies a signed balance change and writes the audit transaction
+func changeGiftCardBalance(tx *gorm.DB, card *models.GiftCard, amount models.Money, txType string, orderID, actorID *uuid.UUID, reason string) error {
+	query := tx.Model(&models.GiftCard{}).Where("id = ?", card.ID)
+	if amount < 0 {
+		// Guard against a concurrent checkout spending the same balance
+		query = query.Where("balance >= ?", -amount)
+	}
+
+	result := query.Update("balance", gorm.Expr("balance + ?", amount))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errGiftCardUnusable
+	}
+	card.Balance += amount
+
+	return tx.Create(&models.GiftCardTransaction{
+		GiftCardID: card.ID,
+		Type:       txType,
+		Amount:     amount,
+		Balance:    card.Balance,
+		OrderID:    orderID,
+		ActorID:    actorID,
+		Reason:     reason,
+	}).Error
+}
+
+// normalizeGiftCardCode makes codes case- and whitespace-insensitive
+func normalizeGiftCardCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
@@ -0,0 +1,396 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	defaultRecommendations = 10
+	maxRecommendations     = 50
+)
+
+// PetInput represents the request body for creating/updating a pet profile
+type PetInput struct {
+	Name      string   `json:"name" binding:"required" example:"Mochi"`
+	Species   string   `json:"species" binding:"required" example:"dog"` // dog, cat, bird, fish, small_pet, reptile
+	Breed     string   `json:"breed" example:"Shiba Inu"`
+	BreedSize string   `json:"breed_size" example:"medium"`                                            // toy, small, medium, large, giant
+	Birthdate string   `json:"birthdate" binding:"omitempty,datetime=2006-01-02" example:"2022-04-15"` // YYYY-MM-DD
+	WeightKg  float64  `json:"weight_kg" binding:"min=0" example:"9.5"`
+	Activity  string   `json:"activity_level" example:"normal"` // low, normal (default), high
+	Allergies []string `json:"allergies" example:"chicken,wheat"`
+}
+
+// applyTo validates the input and copies it onto a pet
+func (input *PetInput) applyTo(pet *models.Pet) error {
+	var err error
+	if pet.Species, err = oneOf("species", input.Species, models.SpeciesValues); err != nil {
+		return err
+	}
+	if pet.BreedSize, err = oneOf("breed_size", input.BreedSize, models.BreedSizeValues); err != nil {
+		return err
+	}
+
+	if pet.Activity, err = oneOf("activity_level", input.Activity, models.ActivityLevelValues); err != nil {
+		return err
+	}
+	if pet.Activity == "" {
+		pet.Activity = models.ActivityNormal
+	}
+
+	pet.Birthdate = nil
+	if input.Birthdate != "" {
+		birthdate, err := time.Parse("2006-01-02", input.Birthdate)
+		if err != nil {
+			return errors.New("birthdate must be a date like 2022-04-15")
+		}
+		if birthdate.After(time.Now()) {
+			return errors.New("birthdate cannot be in the future")
+		}
+		pet.Birthdate = &birthdate
+	}
+
+	pet.Name = strings.TrimSpace(input.Name)
+	pet.Breed = strings.TrimSpace(input.Breed)
+	pet.WeightKg = input.WeightKg
+	pet.Allergies = cleanList(input.Allergies, true)
+	return nil
+}
+
+// petResponse adds the life stage and size worked out for the pet
+func petResponse(pet *models.Pet, now time.Time) gin.H {
+	return gin.H{
+		"pet":        pet,
+		"age_months": pet.AgeMonths(now),
+		"life_stage": pet.LifeStage(now),
+		"size":       pet.Size(now),
+	}
+}
+
+// GetPets godoc
+// @Summary Get my pets
+// @Description List the current user's pet profiles with their life stage and size
+// @Tags Pets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of pets"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /pets [get]
+func GetPets(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var pets []models.Pet
+	if err := config.GetDB().Where("user_id = ?", userID).Order("created_at ASC").Find(&pets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pets"})
+		return
+	}
+
+	now := time.Now()
+	response := make([]gin.H, len(pets))
+	for i := range pets {
+		response[i] = petResponse(&pets[i], now)
+	}
+	c.JSON(http.StatusOK, gin.H{"pets": response})
+}
+
+// GetPet godoc
+// @Summary Get a pet
+// @Description Get one of the current user's pet profiles
+// @Tags Pets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Pet ID"
+// @Success 200 {object} map[string]interface{} "Pet details"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Pet not found"
+// @Router /pets/{id} [get]
+func GetPet(c *gin.Context) {
+	var pet models.Pet
+	if err := config.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), c.GetString("user_id")).First(&pet).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, petResponse(&pet, time.Now()))
+}
+
+// CreatePet godoc
+// @Summary Add a pet
+// @Description Register a pet under the current user's profile
+// @Tags Pets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param pet body PetInput true "Pet data"
+// @Success 201 {object} map[string]interface{} "Pet created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /pets [post]
+func CreatePet(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var input PetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pet := models.Pet{UserID: userID}
+	if err := input.applyTo(&pet); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.GetDB().Create(&pet).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pet"})
+		return
+	}
+
+	response := petResponse(&pet, time.Now())
+	response["message"] = "Pet created successfully"
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdatePet godoc
+// @Summary Update a pet
+// @Description Update one of the current user's pet profiles
+// @Tags Pets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Pet ID"
+// @Param pet body PetInput true "Pet data"
+// @Success 200 {object} map[string]interface{} "Pet updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Pet not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /pets/{id} [put]
+func UpdatePet(c *gin.Context) {
+	var pet models.Pet
+	if err := config.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), c.GetString("user_id")).First(&pet).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+		return
+	}
+
+	var input PetInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.applyTo(&pet); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.GetDB().Save(&pet).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update pet"})
+		return
+	}
+
+	response := petResponse(&pet, time.Now())
+	response["message"] = "Pet updated successfully"
+	c.JSON(http.StatusOK, response)
+}
+
+// DeletePet godoc
+// @Summary Delete a pet
+// @Description Remove one of the current user's pet profiles
+// @Tags Pets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Pet ID"
+// @Success 200 {object} map[string]interface{} "Pet deleted successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Pet not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /pets/{id} [delete]
+func DeletePet(c *gin.Context) {
+	result := config.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), c.GetString("user_id")).Delete(&models.Pet{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete pet"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pet deleted successfully"})
+}
+
+// recommendation is a product suggested for a pet and why
+type recommendation struct {
+	Product models.Product `json:"product"`
+	Score   float64        `json:"score"`
+	Reasons []string       `json:"reasons"`
+}
+
+// allergenConflict returns the first of the pet's allergies a product lists as
+// an allergen or in its ingredients
+func allergenConflict(product *models.Product, allergies []string) string {
+	for _, allergy := range allergies {
+		for _, allergen := range product.Allergens {
+			if allergen == allergy {
+				return allergy
+			}
+		}
+		for _, ingredient := range product.Ingredients {
+			if strings.Contains(strings.ToLower(ingredient), allergy) {
+				return allergy
+			}
+		}
+	}
+	return ""
+}
+
+// recommendFor scores the catalog for one pet. Products for another species,
+// life stage or breed size, and products with an allergen the pet reacts to,
+// are left out; the rest rank by how closely they match, then by rating.
+func recommendFor(pet *models.Pet, products []models.Product, now time.Time, limit int) []recommendation {
+	stage := pet.LifeStage(now)
+	size := pet.Size(now)
+
+	recommendations := []recommendation{}
+	for _, product := range products {
+		if product.Species != pet.Species || allergenConflict(&product, pet.Allergies) != "" {
+			continue
+		}
+
+		var score float64
+		var reasons []string
+		switch {
+		case stage == "" || product.LifeStage == "":
+			score++
+		case product.LifeStage == stage:
+			score += 3
+			reasons = append(reasons, "Made for the "+stage+" life stage")
+		case product.LifeStage == models.LifeStageAllStages:
+			score += 2
+			reasons = append(reasons, "Suits all life stages")
+		default:
+			continue
+		}
+
+		switch {
+		case size == "":
+		case product.BreedSize == size:
+			score += 2
+			reasons = append(reasons, "Made for "+size+" breeds")
+		case product.BreedSize == "":
+			score++
+		default:
+			continue
+		}
+
+		if len(pet.Allergies) > 0 {
+			reasons = append(reasons, "Free of "+strings.Join(pet.Allergies, ", "))
+			if product.Hypoallergenic {
+				score++
+				reasons = append(reasons, "Hypoallergenic")
+			}
+		}
+		score += product.RatingAverage / 5
+
+		recommendations = append(recommendations, recommendation{Product: product, Score: score, Reasons: reasons})
+	}
+
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		a, b := recommendations[i], recommendations[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Product.RatingCount > b.Product.RatingCount
+	})
+	if len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations
+}
+
+// GetRecommendations godoc
+// @Summary Get product recommendations for my pets
+// @Description Rank in-stock catalog products for each of the current user's pets by species, life stage and breed size. Products containing any of a pet's allergies are never recommended, nor are prescription diets.
+// @Tags Pets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param pet_id query string false "Only recommend for this pet"
+// @Param limit query int false "Products per pet" default(10) minimum(1) maximum(50)
+// @Success 200 {object} map[string]interface{} "Recommendations per pet"
+// @Failure 400 {object} map[string]interface{} "Invalid limit"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Pet not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /recommendations [get]
+func GetRecommendations(c *gin.Context) {
+	db := config.GetDB()
+	userID := c.GetString("user_id")
+
+	limit := defaultRecommendations
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxRecommendations {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 50"})
+			return
+		}
+		limit = parsed
+	}
+
+	query := db.Where("user_id = ?", userID).Order("created_at ASC")
+	if petID := c.Query("pet_id"); petID != "" {
+		query = query.Where("id = ?", petID)
+	}
+	var pets []models.Pet
+	if err := query.Find(&pets).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pets"})
+		return
+	}
+	if len(pets) == 0 && c.Query("pet_id") != "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+		return
+	}
+
+	species := make([]string, 0, len(pets))
+	for _, pet := range pets {
+		species = append(species, pet.Species)
+	}
+	var products []models.Product
+	if len(species) > 0 {
+		if err := db.Scopes(listedProducts).Preload("Category").
+			Where("species IN ? AND stock > 0 AND prescription = ?", species, false).
+			Find(&products).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+			return
+		}
+	}
+	if err := applySalePricesToList(db, products); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	now := time.Now()
+	results := make([]gin.H, len(pets))
+	for i := range pets {
+		result := petResponse(&pets[i], now)
+		result["products"] = recommendFor(&pets[i], products, now, limit)
+		results[i] = result
+	}
+
+	c.JSON(http.StatusOK, gin.H{"recommendations": results})
+}
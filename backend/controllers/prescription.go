@@ -0,0 +1,456 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const maxPrescriptionFileSize = 10 << 20 // 10 MB
+
+// prescriptionFileTypes maps the accepted file types to the extension they are stored with
+var prescriptionFileTypes = map[string]string{
+	"application/pdf": ".pdf",
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+}
+
+var (
+	errPrescriptionRequired = errors.New("requires an approved prescription")
+	errPrescriptionReviewed = errors.New("Prescription has already been reviewed")
+)
+
+// PrescriptionUpload represents the form fields sent with a prescription file
+type PrescriptionUpload struct {
+	PetID     string `form:"pet_id" binding:"required"`
+	ProductID string `form:"product_id"` // the prescribed diet, optional
+	VetName   string `form:"vet_name" binding:"required"`
+	VetClinic string `form:"vet_clinic"`
+	Notes     string `form:"notes"`
+}
+
+// ApprovePrescriptionRequest represents the request body for approving a prescription
+type ApprovePrescriptionRequest struct {
+	ValidUntil string `json:"valid_until" binding:"required,datetime=2006-01-02" example:"2027-04-30"` // last day it can be used
+	ProductID  string `json:"product_id" example:"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"`               // optional, limits it to one diet
+	Note       string `json:"note" example:"Renal diet for 6 months"`
+}
+
+// RejectPrescriptionRequest represents the request body for rejecting a prescription
+type RejectPrescriptionRequest struct {
+	Reason string `json:"reason" binding:"required" example:"Prescription is not signed by the vet"`
+}
+
+// prescriptionDir is where uploaded prescriptions are kept, outside any public path
+func prescriptionDir() string {
+	if dir := os.Getenv("PRESCRIPTION_UPLOAD_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join("uploads", "prescriptions")
+}
+
+// validPrescription finds the user's approved prescription that covers a
+// prescription diet today, or returns errPrescriptionRequired
+func validPrescription(db *gorm.DB, userID, productID uuid.UUID, now time.Time) (*models.Prescription, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	var prescription models.Prescription
+	err := db.Where("user_id = ? AND status = ? AND valid_until >= ? AND (product_id IS NULL OR product_id = ?)",
+		userID, models.PrescriptionApproved, today, productID).
+		Order("valid_until DESC").First(&prescription).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errPrescriptionRequired
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &prescription, nil
+}
+
+// checkPrescription lets a product through when it is not a prescription diet
+// or the user holds a valid approval for it
+func checkPrescription(db *gorm.DB, userID uuid.UUID, product *models.Product) error {
+	if !product.Prescription {
+		return nil
+	}
+	if _, err := validPrescription(db, userID, product.ID, time.Now()); err != nil {
+		if errors.Is(err, errPrescriptionRequired) {
+			return fmt.Errorf("%s %w", product.Name, errPrescriptionRequired)
+		}
+		return err
+	}
+	return nil
+}
+
+// GetPrescriptions godoc
+// @Summary Get my prescriptions
+// @Description List the prescriptions the current user uploaded with their review status
+// @Tags Prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of prescriptions"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /prescriptions [get]
+func GetPrescriptions(c *gin.Context) {
+	var prescriptions []models.Prescription
+	if err := config.GetDB().Preload("Pet").Preload("Product", withArchived).Where("user_id = ?", c.GetString("user_id")).
+		Order("created_at DESC").Find(&prescriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prescriptions": prescriptions})
+}
+
+// UploadPrescription godoc
+// @Summary Upload a prescription
+// @Description Upload a vet prescription (PDF, JPEG, PNG or WebP, up to 10 MB) for one of your pets. It is reviewed before prescription diets can be bought.
+// @Tags Prescriptions
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "Prescription document"
+// @Param pet_id formData string true "Pet the prescription is for"
+// @Param product_id formData string false "Prescribed product"
+// @Param vet_name formData string true "Prescribing vet"
+// @Param vet_clinic formData string false "Clinic"
+// @Param notes formData string false "Notes for the reviewer"
+// @Success 201 {object} map[string]interface{} "Prescription uploaded"
+// @Failure 400 {object} map[string]interface{} "Bad request - missing or unsupported file"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Pet or product not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /prescriptions [post]
+func UploadPrescription(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var form PrescriptionUpload
+	if err := c.ShouldBind(&form); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var pet models.Pet
+	if err := db.Where("id = ? AND user_id = ?", form.PetID, userID).First(&pet).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pet not found"})
+		return
+	}
+
+	prescription := models.Prescription{
+		UserID:    userID,
+		PetID:     pet.ID,
+		VetName:   strings.TrimSpace(form.VetName),
+		VetClinic: strings.TrimSpace(form.VetClinic),
+		Notes:     strings.TrimSpace(form.Notes),
+		Status:    models.PrescriptionPending,
+	}
+	if form.ProductID != "" {
+		var product models.Product
+		if err := db.Where("id = ?", form.ProductID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		prescription.ProductID = &product.ID
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if header.Size > maxPrescriptionFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File must be 10 MB or smaller"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	// Trust the file's content, not the name or header the client sent
+	sniff := make([]byte, 512)
+	n, _ := io.ReadFull(file, sniff)
+	contentType := http.DetectContentType(sniff[:n])
+	ext, ok := prescriptionFileTypes[contentType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File must be a PDF, JPEG, PNG or WebP"})
+		return
+	}
+
+	prescription.ID = uuid.New()
+	prescription.FileName = filepath.Base(header.Filename)
+	prescription.FilePath = filepath.Join(prescriptionDir(), prescription.ID.String()+ext)
+	prescription.ContentType = contentType
+	prescription.FileSize = header.Size
+
+	if err := os.MkdirAll(prescriptionDir(), 0o750); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store prescription"})
+		return
+	}
+	if err := c.SaveUploadedFile(header, prescription.FilePath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store prescription"})
+		return
+	}
+
+	if err := db.Create(&prescription).Error; err != nil {
+		os.Remove(prescription.FilePath)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save prescription"})
+		return
+	}
+	prescription.Pet = pet
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Prescription uploaded and waiting for review",
+		"prescription": prescription,
+	})
+}
+
+// GetPrescriptionFile godoc
+// @Summary Download a prescription file
+// @Description Download the uploaded document. Customers can fetch their own; vets and admins any.
+// @Tags Prescriptions
+// @Produce application/octet-stream
+// @Security BearerAuth
+// @Param id path string true "Prescription ID"
+// @Success 200 {file} file "Prescription document"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Prescription not found"
+// @Router /prescriptions/{id}/file [get]
+func GetPrescriptionFile(c *gin.Context) {
+	query := config.GetDB().Where("id = ?", c.Param("id"))
+	if role := c.GetString("user_role"); role != "admin" && role != "vet" {
+		query = query.Where("user_id = ?", c.GetString("user_id"))
+	}
+
+	var prescription models.Prescription
+	if err := query.First(&prescription).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prescription not found"})
+		return
+	}
+	if _, err := os.Stat(prescription.FilePath); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prescription file not found"})
+		return
+	}
+
+	c.Header("Content-Type", prescription.ContentType)
+	c.FileAttachment(prescription.FilePath, prescription.FileName)
+}
+
+// GetPrescriptionQueue godoc
+// @Summary Prescription review queue (Vet or admin)
+// @Description List prescriptions oldest first, pending ones by default
+// @Tags Vet - Prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Review status, default pending" Enums(pending, approved, rejected)
+// @Success 200 {object} map[string]interface{} "Prescriptions"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Vet or admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /vet/prescriptions [get]
+func GetPrescriptionQueue(c *gin.Context) {
+	status := c.DefaultQuery("status", models.PrescriptionPending)
+
+	var prescriptions []models.Prescription
+	if err := config.GetDB().Preload("User").Preload("Pet").Preload("Product", withArchived).
+		Where("status = ?", status).Order("created_at ASC").Find(&prescriptions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch prescriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"prescriptions": prescriptions})
+}
+
+// reviewPrescription loads a pending prescription for review, answering the
+// request itself when it cannot be reviewed
+func reviewPrescription(c *gin.Context) (*models.Prescription, bool) {
+	var prescription models.Prescription
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&prescription).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Prescription not found"})
+		return nil, false
+	}
+	if prescription.Status != models.PrescriptionPending {
+		c.JSON(http.StatusConflict, gin.H{"error": errPrescriptionReviewed.Error()})
+		return nil, false
+	}
+	return &prescription, true
+}
+
+// ApprovePrescription godoc
+// @Summary Approve a prescription (Vet or admin)
+// @Description Approve a pending prescription until valid_until. With product_id it covers that diet only; without, any prescription diet.
+// @Tags Vet - Prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Prescription ID"
+// @Param request body ApprovePrescriptionRequest true "Approval"
+// @Success 200 {object} map[string]interface{} "Prescription approved"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Vet or admin access required"
+// @Failure 404 {object} map[string]interface{} "Prescription or product not found"
+// @Failure 409 {object} map[string]interface{} "Prescription has already been reviewed"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /vet/prescriptions/{id}/approve [post]
+func ApprovePrescription(c *gin.Context) {
+	var req ApprovePrescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prescription, ok := reviewPrescription(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	validUntil, _ := time.Parse("2006-01-02", req.ValidUntil)
+	if validUntil.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "valid_until cannot be in the past"})
+		return
+	}
+
+	if req.ProductID != "" {
+		var product models.Product
+		if err := config.GetDB().Where("id = ?", req.ProductID).First(&product).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+			return
+		}
+		prescription.ProductID = &product.ID
+	}
+
+	reviewerID, _ := uuid.Parse(c.GetString("user_id"))
+	prescription.Status = models.PrescriptionApproved
+	prescription.ValidUntil = &validUntil
+	prescription.ReviewedBy = &reviewerID
+	prescription.ReviewedAt = &now
+	prescription.ReviewNote = strings.TrimSpace(req.Note)
+	if err := config.GetDB().Save(prescription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve prescription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Prescription approved",
+		"prescription": prescription,
+	})
+}
+
+// RejectPrescription godoc
+// @Summary Reject a prescription (Vet or admin)
+// @Description Reject a pending prescription with a reason shown to the customer
+// @Tags Vet - Prescriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Prescription ID"
+// @Param request body RejectPrescriptionRequest true "Rejection reason"
+// @Success 200 {object} map[string]interface{} "Prescription rejected"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Vet or admin access required"
+// @Failure 404 {object} map[string]interface{} "Prescription not found"
+// @Failure 409 {object} map[string]interface{} "Prescription has already been reviewed"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /vet/prescriptions/{id}/reject [post]
+func RejectPrescription(c *gin.Context) {
+	var req RejectPrescriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	prescription, ok := reviewPrescription(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	reviewerID, _ := uuid.Parse(c.GetString("user_id"))
+	prescription.Status = models.PrescriptionRejected
+	prescription.ReviewedBy = &reviewerID
+	prescription.ReviewedAt = &now
+	prescription.ReviewNote = strings.TrimSpace(req.Reason)
+	if err := config.GetDB().Save(prescription).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject prescription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Prescription rejected",
+		"prescription": prescription,
+	})
+}
+
+// userRoles are the roles an admin can give an account
+var userRoles = []string{"customer", "vet", "admin"}
+
+// SetUserRoleRequest represents the request body for changing a user's role
+type SetUserRoleRequest struct {
+	Role string `json:"role" binding:"required" example:"vet" enums:"customer,vet,admin"`
+}
+
+// SetUserRole godoc
+// @Summary Change a user's role (Admin only)
+// @Description Make an account a customer, vet (reviews prescriptions) or admin
+// @Tags Admin - Users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body SetUserRoleRequest true "New role"
+// @Success 200 {object} map[string]interface{} "Role updated"
+// @Failure 400 {object} map[string]interface{} "Invalid role"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/users/{id}/role [put]
+func SetUserRole(c *gin.Context) {
+	var req SetUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !slices.Contains(userRoles, req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of " + strings.Join(userRoles, ", ")})
+		return
+	}
+
+	var user models.User
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := config.GetDB().Model(&user).Update("role", req.Role).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Role updated",
+		"user":    user,
+	})
+}
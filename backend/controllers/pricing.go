@@ -0,0 +1,611 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"pet-food-ecommerce/models"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// shippingSettings holds the delivery charge, overridable through the
+// environment; money values are in baht. No fee is charged unless one is set.
+type shippingSettings struct {
+	Fee      models.Money // SHIPPING_FEE: charged on orders below FreeOver
+	FreeOver models.Money // SHIPPING_FREE_OVER: subtotal that ships free, 0 = never free
+}
+
+func loadShippingSettings() shippingSettings {
+	return shippingSettings{
+		Fee:      envMoney("SHIPPING_FEE", 0),
+		FreeOver: envMoney("SHIPPING_FREE_OVER", 0),
+	}
+}
+
+var (
+	errMixedCurrency       = errors.New("cart contains products priced in different currencies")
+	errCouponNotFound      = errors.New("Coupon not found")
+	errCouponInactive      = errors.New("Coupon is not active")
+	errCouponNotStarted    = errors.New("Coupon is not valid yet")
+	errCouponExpired       = errors.New("Coupon has expired")
+	errCouponUsageLimit    = errors.New("Coupon usage limit reached")
+	errCouponUserLimit     = errors.New("You have already used this coupon the maximum number of times")
+	errCouponNotApplicable = errors.New("Coupon does not apply to any item in your cart")
+	errFlashSaleSoldOut    = errors.New("Flash sale stock has just sold out, please review your cart")
+)
+
+// appliedPromotion is one automatic promotion applied to a cart line
+type appliedPromotion struct {
+	PromotionID uuid.UUID    `json:"promotion_id"`
+	Name        string       `json:"name"`
+	Discount    models.Money `json:"discount"`
+}
+
+// cartLine is the priced view of one cart item. Units covered by a flash sale are
+// priced at SalePrice; any units beyond the sale's caps fall back to UnitPrice.
+type cartLine struct {
+	CartItemID   uuid.UUID          `json:"cart_item_id"`
+	ProductID    uuid.UUID          `json:"product_id"`
+	VariantID    *uuid.UUID         `json:"variant_id,omitempty"`
+	SKU          string             `json:"sku,omitempty"`
+	VariantName  string             `json:"variant_name,omitempty"`
+	Quantity     int                `json:"quantity"`
+	UnitPrice    models.Money       `json:"unit_price"`
+	MemberPrice  bool               `json:"member_price,omitempty"` // UnitPrice is a tier-only price
+	SaleID       *uuid.UUID         `json:"sale_id,omitempty"`
+	SalePrice    models.Money       `json:"sale_price,omitempty"`
+	SaleQuantity int                `json:"sale_quantity,omitempty"`
+	Subtotal     models.Money       `json:"subtotal"`
+	Discount     models.Money       `json:"discount"`
+	Total        models.Money       `json:"total"`
+	Promotions   []appliedPromotion `json:"promotions"`
+
+	product    *models.Product
+	categories []uuid.UUID // the product's category and those above it
+}
+
+// promotionNames lists the applied promotions for storing on the order item
+func (l *cartLine) promotionNames() string {
+	names := make([]string, 0, len(l.Promotions))
+	for _, p := range l.Promotions {
+		names = append(names, p.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// cartTotals holds the money breakdown shared by GetCart and CreateOrder
+type cartTotals struct {
+	Currency          string
+	Lines             []cartLine
+	Subtotal          models.Money
+	PromotionDiscount models.Money
+	Discount          models.Money // promotions + subscriber + coupon + referral + points
+	ShippingFee       models.Money
+	Tax               models.Money // VAT already included in Total
+	Total             models.Money
+
+	Coupon         *models.Coupon
+	CouponDiscount models.Money
+	CouponError    string // set when the applied coupon no longer validates
+
+	Referral         *models.Referral
+	ReferralDiscount models.Money
+
+	SubscriberDiscount models.Money
+
+	MemberTier     string
+	PointsRedeemed int
+	PointsDiscount models.Money
+}
+
+// pricingOptions carries checkout choices that change the cart price
+type pricingOptions struct {
+	RedeemPoints int  // loyalty points to burn as a discount
+	Autoship     bool // a subscription order: subscriber discount instead of the cart coupon
+}
+
+// priceCart prices cart items (with Product and Variant preloaded) using exact satang arithmetic.
+// GetCart and CreateOrder both go through here so the two totals always match;
+// CreateOrder passes its transaction so promotions and the coupon are re-evaluated
+// against the same rows it commits.
+func priceCart(db *gorm.DB, userID string, items []models.Cart, opts pricingOptions) (cartTotals, error) {
+	totals := cartTotals{Currency: models.DefaultCurrency}
+	userUUID, _ := uuid.Parse(userID)
+
+	sales, err := runningFlashSales(db, items)
+	if err != nil {
+		return cartTotals{}, err
+	}
+
+	tier, _, err := memberTier(db, userUUID, time.Now())
+	if err != nil {
+		return cartTotals{}, err
+	}
+	totals.MemberTier = tier
+	productIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	tierPrices, err := tierPricesFor(db, productIDs, tier)
+	if err != nil {
+		return cartTotals{}, err
+	}
+	categories, err := loadCategoryTree(db)
+	if err != nil {
+		return cartTotals{}, err
+	}
+
+	for i := range items {
+		item := &items[i]
+		if i == 0 && item.Product.Currency != "" {
+			totals.Currency = item.Product.Currency
+		}
+		if item.Product.Currency != "" && item.Product.Currency != totals.Currency {
+			return cartTotals{}, errMixedCurrency
+		}
+
+		line := cartLine{
+			CartItemID: item.ID,
+			ProductID:  item.ProductID,
+			Quantity:   item.Quantity,
+			UnitPrice:  item.Product.Price,
+			Promotions: []appliedPromotion{},
+			product:    &item.Product,
+			categories: categories.ancestors(item.Product.CategoryID),
+		}
+		if item.VariantID != nil && item.Variant.ID != uuid.Nil {
+			line.VariantID = item.VariantID
+			line.SKU = item.Variant.SKU
+			line.VariantName = item.Variant.Name
+			line.UnitPrice = item.Variant.Price
+		}
+		if price, ok := lowestTierPrice(tierPrices[item.ProductID], item.VariantID); ok && price < line.UnitPrice {
+			line.UnitPrice = price
+			line.MemberPrice = true
+		}
+
+		if sale, ok := bestSaleFor(sales[item.ProductID], item.VariantID); ok && sale.SalePrice < line.UnitPrice {
+			saleQuantity, err := flashSaleQuantity(db, &sale, userID, item.Quantity)
+			if err != nil {
+				return cartTotals{}, err
+			}
+			if saleQuantity > 0 {
+				line.SaleID = &sale.ID
+				line.SalePrice = sale.SalePrice
+				line.SaleQuantity = saleQuantity
+			}
+		}
+
+		line.Subtotal = line.SalePrice.Mul(line.SaleQuantity) + line.UnitPrice.Mul(line.Quantity-line.SaleQuantity)
+		line.Total = line.Subtotal
+		totals.Lines = append(totals.Lines, line)
+		totals.Subtotal += line.Subtotal
+	}
+
+	// Automatic promotions
+	if len(totals.Lines) > 0 {
+		var promotions []models.Promotion
+		if err := db.Preload("Tiers").Preload("Categories").Preload("Products").
+			Where("active = ?", true).Order("priority DESC, created_at ASC").
+			Find(&promotions).Error; err != nil {
+			return cartTotals{}, err
+		}
+		applyPromotions(promotions, totals.Lines, time.Now())
+		for _, line := range totals.Lines {
+			totals.PromotionDiscount += line.Discount
+		}
+		totals.Discount += totals.PromotionDiscount
+	}
+
+	// Subscriber discount on what is left after promotions
+	if opts.Autoship {
+		totals.SubscriberDiscount = (totals.Subtotal - totals.Discount).Percent(loadSubscriptionSettings().DiscountBps)
+		totals.Discount += totals.SubscriberDiscount
+	}
+
+	// Applied coupon; the coupon in the cart belongs to the customer's next manual checkout
+	freeShipping := false
+	var applied models.CartCoupon
+	err = gorm.ErrRecordNotFound
+	if !opts.Autoship {
+		err = db.Preload("Coupon.Categories").Preload("Coupon.Products").
+			Where("user_id = ?", userID).First(&applied).Error
+	}
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return cartTotals{}, err
+	}
+	if err == nil {
+		if applied.Coupon.ID == uuid.Nil {
+			totals.CouponError = errCouponNotFound.Error()
+		} else {
+			coupon := applied.Coupon
+			totals.Coupon = &coupon
+			discount, free, err := evaluateCoupon(db, &coupon, userID, totals.Lines)
+			if err != nil {
+				totals.CouponError = err.Error()
+			} else {
+				totals.CouponDiscount = discount
+				totals.Discount += discount
+				freeShipping = free
+			}
+		}
+	}
+
+	// Referee first-order discount
+	referral, discount, err := referralDiscount(db, userUUID)
+	if err != nil {
+		return cartTotals{}, err
+	}
+	if referral != nil && len(items) > 0 {
+		totals.Referral = referral
+		totals.ReferralDiscount = discount.Min(totals.Subtotal - totals.Discount)
+		totals.Discount += totals.ReferralDiscount
+	}
+
+	// Loyalty points cover goods only, never shipping
+	if opts.RedeemPoints > 0 {
+		points, discount, err := pointsDiscount(db, userUUID, opts.RedeemPoints, totals.Subtotal-totals.Discount)
+		if err != nil {
+			return cartTotals{}, err
+		}
+		totals.PointsRedeemed = points
+		totals.PointsDiscount = discount
+		totals.Discount += discount
+	}
+
+	// A free shipping coupon waives the configured fee
+	shipping := loadShippingSettings()
+	if len(items) > 0 && !freeShipping && (shipping.FreeOver == 0 || totals.Subtotal < shipping.FreeOver) {
+		totals.ShippingFee = shipping.Fee
+	}
+
+	totals.Total = totals.Subtotal - totals.Discount
+	if totals.Total < 0 {
+		totals.Total = 0
+	}
+	totals.Total += totals.ShippingFee
+	totals.Tax = totals.Total.IncludedTax(models.VATRateBasisPoints)
+
+	return totals, nil
+}
+
+// applyPromotions evaluates promotions in priority order against each line.
+// A non-stackable promotion only applies to an untouched line and blocks any
+// further promotions on it; stackable promotions combine with each other.
+// Lines bought at a flash-sale price are already discounted and are skipped.
+func applyPromotions(promotions []models.Promotion, lines []cartLine, now time.Time) {
+	for i := range lines {
+		line := &lines[i]
+		exclusive := line.SaleQuantity > 0
+
+		for p := range promotions {
+			promotion := &promotions[p]
+			if exclusive || !promotion.IsRunning(now) || !promotion.AppliesTo(line.product, line.categories) {
+				continue
+			}
+			if !promotion.Stackable && len(line.Promotions) > 0 {
+				continue
+			}
+
+			discount := promotionDiscount(promotion, line).Min(line.Total)
+			if discount <= 0 {
+				continue
+			}
+
+			line.Discount += discount
+			line.Total -= discount
+			line.Promotions = append(line.Promotions, appliedPromotion{
+				PromotionID: promotion.ID,
+				Name:        promotion.Name,
+				Discount:    discount,
+			})
+			if !promotion.Stackable {
+				exclusive = true
+			}
+		}
+	}
+}
+
+// promotionDiscount computes what a single promotion takes off a line
+func promotionDiscount(promotion *models.Promotion, line *cartLine) models.Money {
+	switch promotion.Type {
+	case models.PromotionTypeBuyXGetY:
+		groupSize := promotion.BuyQuantity + promotion.GetQuantity
+		if promotion.BuyQuantity <= 0 || promotion.GetQuantity <= 0 {
+			return 0
+		}
+		discountedUnits := (line.Quantity / groupSize) * promotion.GetQuantity
+		return line.UnitPrice.Mul(discountedUnits).Percent(int64(promotion.GetPercentOff) * 100)
+
+	case models.PromotionTypeBundle:
+		if promotion.BundleQuantity <= 0 {
+			return 0
+		}
+		saving := line.UnitPrice.Mul(promotion.BundleQuantity) - promotion.BundlePrice
+		if saving <= 0 {
+			return 0
+		}
+		return saving.Mul(line.Quantity / promotion.BundleQuantity)
+
+	case models.PromotionTypeTiered:
+		best := 0
+		for _, tier := range promotion.Tiers {
+			if line.Quantity >= tier.MinQuantity && tier.PercentOff > best {
+				best = tier.PercentOff
+			}
+		}
+		return line.Total.Percent(int64(best) * 100)
+	}
+
+	return 0
+}
+
+// evaluateCoupon checks a coupon against the promoted cart lines and returns its
+// discount and whether it waives the shipping fee
+func evaluateCoupon(db *gorm.DB, coupon *models.Coupon, userID string, lines []cartLine) (models.Money, bool, error) {
+	now := time.Now()
+	if !coupon.Active {
+		return 0, false, errCouponInactive
+	}
+	if coupon.StartsAt != nil && now.Before(*coupon.StartsAt) {
+		return 0, false, errCouponNotStarted
+	}
+	if coupon.EndsAt != nil && !now.Before(*coupon.EndsAt) {
+		return 0, false, errCouponExpired
+	}
+	if coupon.UsageLimit > 0 && coupon.UsedCount >= coupon.UsageLimit {
+		return 0, false, errCouponUsageLimit
+	}
+	if coupon.PerUserLimit > 0 {
+		var used int64
+		if err := db.Model(&models.CouponRedemption{}).
+			Where("coupon_id = ? AND user_id = ?", coupon.ID, userID).Count(&used).Error; err != nil {
+			return 0, false, err
+		}
+		if used >= int64(coupon.PerUserLimit) {
+			return 0, false, errCouponUserLimit
+		}
+	}
+
+	// Only restricted products/categories count towards the discount
+	var eligible models.Money
+	for _, line := range lines {
+		if coupon.AppliesTo(line.product, line.categories) {
+			eligible += line.Total
+		}
+	}
+	if eligible == 0 {
+		return 0, false, errCouponNotApplicable
+	}
+	if eligible < coupon.MinSpend {
+		return 0, false, fmt.Errorf("Coupon requires a minimum spend of %s", coupon.MinSpend)
+	}
+
+	switch coupon.Type {
+	case models.CouponTypePercentage:
+		discount := eligible.Percent(int64(coupon.PercentOff) * 100)
+		if coupon.MaxDiscount > 0 {
+			discount = discount.Min(coupon.MaxDiscount)
+		}
+		return discount, false, nil
+	case models.CouponTypeFixed:
+		return coupon.AmountOff.Min(eligible), false, nil
+	case models.CouponTypeFreeShipping:
+		return 0, true, nil
+	}
+
+	return 0, false, errCouponInactive
+}
+
+// redeemCoupon records a coupon use for an order. The used_count guard makes
+// concurrent checkouts unable to exceed the total usage limit.
+func redeemCoupon(tx *gorm.DB, coupon *models.Coupon, order *models.Order, discount models.Money) error {
+	result := tx.Model(&models.Coupon{}).
+		Where("id = ? AND (usage_limit = 0 OR used_count < usage_limit)", coupon.ID).
+		Update("used_count", gorm.Expr("used_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errCouponUsageLimit
+	}
+
+	return tx.Create(&models.CouponRedemption{
+		CouponID: coupon.ID,
+		UserID:   order.UserID,
+		OrderID:  order.ID,
+		Discount: discount,
+	}).Error
+}
+
+// releaseCoupon gives back the coupon use of a cancelled or refunded order, so
+// it no longer counts toward the coupon's usage limit or the customer's
+func releaseCoupon(tx *gorm.DB, order *models.Order) error {
+	var redemption models.CouponRedemption
+	err := tx.Where("order_id = ?", order.ID).First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&models.Coupon{}).
+		Where("id = ? AND used_count > 0", redemption.CouponID).
+		Update("used_count", gorm.Expr("used_count - 1")).Error; err != nil {
+		return err
+	}
+	return tx.Delete(&redemption).Error
+}
+
+// runningFlashSales returns the running flash sales with stock left for the products in the cart
+func runningFlashSales(db *gorm.DB, items []models.Cart) (map[uuid.UUID][]models.FlashSale, error) {
+	productIDs := make([]uuid.UUID, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	return flashSalesByProduct(db, productIDs, time.Now())
+}
+
+// flashSalesByProduct groups the running flash sales with stock left by product
+func flashSalesByProduct(db *gorm.DB, productIDs []uuid.UUID, now time.Time) (map[uuid.UUID][]models.FlashSale, error) {
+	byProduct := make(map[uuid.UUID][]models.FlashSale)
+	if len(productIDs) == 0 {
+		return byProduct, nil
+	}
+
+	var sales []models.FlashSale
+	if err := db.Where("product_id IN ? AND starts_at <= ? AND ends_at > ?", productIDs, now, now).
+		Find(&sales).Error; err != nil {
+		return nil, err
+	}
+
+	for _, sale := range sales {
+		if sale.Remaining() == 0 {
+			continue
+		}
+		byProduct[sale.ProductID] = append(byProduct[sale.ProductID], sale)
+	}
+	return byProduct, nil
+}
+
+// bestSaleFor picks the lowest sale price covering a variant
+func bestSaleFor(sales []models.FlashSale, variantID *uuid.UUID) (models.FlashSale, bool) {
+	return lowestSale(sales, func(sale *models.FlashSale) bool { return sale.CoversVariant(variantID) })
+}
+
+// lowestSale picks the lowest sale price among the sales that match
+func lowestSale(sales []models.FlashSale, match func(*models.FlashSale) bool) (models.FlashSale, bool) {
+	var best models.FlashSale
+	found := false
+	for _, sale := range sales {
+		if !match(&sale) {
+			continue
+		}
+		if !found || sale.SalePrice < best.SalePrice {
+			best = sale
+			found = true
+		}
+	}
+	return best, found
+}
+
+// flashSaleQuantity returns how many of the wanted units the user may buy at the sale price
+func flashSaleQuantity(db *gorm.DB, sale *models.FlashSale, userID string, wanted int) (int, error) {
+	quantity := wanted
+	if remaining := sale.Remaining(); remaining >= 0 && remaining < quantity {
+		quantity = remaining
+	}
+
+	if sale.PerCustomerLimit > 0 {
+		var bought int64
+		if err := db.Model(&models.OrderItem{}).
+			Joins("JOIN orders ON orders.id = order_items.order_id").
+			Where("order_items.sale_id = ? AND orders.user_id = ? AND orders.status NOT IN ?", sale.ID, userID, []string{"cancelled", "refunded"}).
+			Select("COALESCE(SUM(order_items.quantity), 0)").Scan(&bought).Error; err != nil {
+			return 0, err
+		}
+		allowed := sale.PerCustomerLimit - int(bought)
+		if allowed < quantity {
+			quantity = allowed
+		}
+	}
+
+	if quantity < 0 {
+		return 0, nil
+	}
+	return quantity, nil
+}
+
+// applySalePrices fills in the sale price of products (and their loaded variants)
+// that are on a running flash sale
+func applySalePrices(db *gorm.DB, products ...*models.Product) error {
+	productIDs := make([]uuid.UUID, 0, len(products))
+	for _, product := range products {
+		productIDs = append(productIDs, product.ID)
+	}
+
+	sales, err := flashSalesByProduct(db, productIDs, time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, product := range products {
+		// The product shows the lowest sale price of any of its variants when it
+		// undercuts the "from" price; variant-level sales show on the variants
+		if sale, ok := lowestSale(sales[product.ID], func(*models.FlashSale) bool { return true }); ok && sale.SalePrice < product.Price {
+			salePrice := sale.SalePrice
+			endsAt := sale.EndsAt
+			product.SalePrice = &salePrice
+			product.SaleEndsAt = &endsAt
+		}
+		for i := range product.Variants {
+			variant := &product.Variants[i]
+			if sale, ok := bestSaleFor(sales[product.ID], &variant.ID); ok && sale.SalePrice < variant.Price {
+				salePrice := sale.SalePrice
+				variant.SalePrice = &salePrice
+			}
+		}
+	}
+	return nil
+}
+
+// applySalePricesToList resolves sale prices for a product listing
+func applySalePricesToList(db *gorm.DB, products []models.Product) error {
+	pointers := make([]*models.Product, 0, len(products))
+	for i := range products {
+		pointers = append(pointers, &products[i])
+	}
+	return applySalePrices(db, pointers...)
+}
+
+// applySalePricesToCart resolves sale prices for the products in cart items
+func applySalePricesToCart(db *gorm.DB, items []models.Cart) error {
+	pointers := make([]*models.Product, 0, len(items))
+	for i := range items {
+		pointers = append(pointers, &items[i].Product)
+	}
+	return applySalePrices(db, pointers...)
+}
+
+// claimFlashSaleStock reserves sale units for an order. The sold_count guard makes
+// concurrent checkouts unable to oversell the sale stock.
+func claimFlashSaleStock(tx *gorm.DB, saleID uuid.UUID, quantity int) error {
+	result := tx.Model(&models.FlashSale{}).
+		Where("id = ? AND (stock_limit = 0 OR sold_count + ? <= stock_limit)", saleID, quantity).
+		Update("sold_count", gorm.Expr("sold_count + ?", quantity))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errFlashSaleSoldOut
+	}
+	return nil
+}
+
+// releaseFlashSaleStock puts the sale units of a cancelled or refunded order
+// back on sale
+func releaseFlashSaleStock(tx *gorm.DB, order *models.Order) error {
+	var claimed []struct {
+		SaleID   uuid.UUID
+		Quantity int
+	}
+	if err := tx.Model(&models.OrderItem{}).
+		Select("sale_id, SUM(quantity) AS quantity").
+		Where("order_id = ? AND sale_id IS NOT NULL", order.ID).
+		Group("sale_id").Scan(&claimed).Error; err != nil {
+		return err
+	}
+
+	for _, sale := range claimed {
+		if err := tx.Model(&models.FlashSale{}).
+			Where("id = ? AND sold_count >= ?", sale.SaleID, sale.Quantity).
+			Update("sold_count", gorm.Expr("sold_count - ?", sale.Quantity)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
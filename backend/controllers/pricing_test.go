@@ -0,0 +1,62 @@
+//go:build cgo
+
+package controllers
+
+import (
+	"pet-food-ecommerce/models"
+	"testing"
+)
+
+func TestPriceCartChargesNoShippingByDefault(t *testing.T) {
+	db := newTestDB(t)
+	user := createTestUser(t, db)
+	product := createTestProduct(t, db, 25000)
+	db.Create(&models.Cart{UserID: user.ID, ProductID: product.ID, VariantID: &product.Variants[0].ID, Quantity: 1})
+
+	totals, err := priceCart(db, user.ID.String(), testCart(t, db, user.ID), pricingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.ShippingFee != 0 || totals.Total != 25000 {
+		t.Errorf("shipping fee %s, total %s; want 0.00 and 250.00", totals.ShippingFee, totals.Total)
+	}
+}
+
+func TestPriceCartShippingFee(t *testing.T) {
+	t.Setenv("SHIPPING_FEE", "50")
+	t.Setenv("SHIPPING_FREE_OVER", "1000")
+	db := newTestDB(t)
+	user := createTestUser(t, db)
+	product := createTestProduct(t, db, 25000)
+	db.Create(&models.Cart{UserID: user.ID, ProductID: product.ID, VariantID: &product.Variants[0].ID, Quantity: 1})
+
+	totals, err := priceCart(db, user.ID.String(), testCart(t, db, user.ID), pricingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.ShippingFee != 5000 || totals.Total != 30000 {
+		t.Errorf("below free-over: shipping fee %s, total %s; want 50.00 and 300.00", totals.ShippingFee, totals.Total)
+	}
+
+	db.Model(&models.Cart{}).Where("user_id = ?", user.ID).Update("quantity", 4)
+	totals, err = priceCart(db, user.ID.String(), testCart(t, db, user.ID), pricingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.ShippingFee != 0 || totals.Total != 100000 {
+		t.Errorf("at free-over: shipping fee %s, total %s; want 0.00 and 1000.00", totals.ShippingFee, totals.Total)
+	}
+
+	db.Model(&models.Cart{}).Where("user_id = ?", user.ID).Update("quantity", 1)
+	coupon := models.Coupon{Code: "FREESHIP", Type: models.CouponTypeFreeShipping, Active: true}
+	db.Create(&coupon)
+	db.Create(&models.CartCoupon{UserID: user.ID, CouponID: coupon.ID})
+	totals, err = priceCart(db, user.ID.String(), testCart(t, db, user.ID), pricingOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if totals.CouponError != "" || totals.ShippingFee != 0 || totals.Total != 25000 {
+		t.Errorf("free shipping coupon: error %q, shipping fee %s, total %s; want no error, 0.00 and 250.00",
+			totals.CouponError, totals.ShippingFee, totals.Total)
+	}
+}
@@ -1,127 +1,560 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"pet-food-ecommerce/config"
 	"pet-food-ecommerce/models"
-	"strconv"
+	"pet-food-ecommerce/search"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
-// ProductInput represents the request body for creating/updating a product
+// ProductInput represents the request body for creating a product
 type ProductInput struct {
-	Name        string  `json:"name" binding:"required" example:"Royal Canin Medium Adult"`
-	Description string  `json:"description" example:"Premium dog food for medium breeds"`
-	Price       float64 `json:"price" binding:"required" example:"1599.00"`
-	Stock       int     `json:"stock" example:"45"`
-	CategoryID  string  `json:"category_id" binding:"required" example:"11111111-1111-1111-1111-111111111111"`
-	Brand       string  `json:"brand" example:"Royal Canin"`
-	Weight      string  `json:"weight" example:"3kg"`
-	ImageURL    string  `json:"image_url" example:"https://example.com/image.jpg"`
+	Name           string                    `json:"name" binding:"required" example:"Royal Canin Medium Adult"`
+	Description    string                    `json:"description" example:"Premium dog food for medium breeds"`
+	Price          models.Money              `json:"price" binding:"min=0" swaggertype:"number" example:"1599.00"` // required unless variants are given
+	Stock          int                       `json:"stock" binding:"min=0" example:"45"`
+	CategoryID     string                    `json:"category_id" binding:"required,uuid" example:"11111111-1111-1111-1111-111111111111"`
+	Brand          string                    `json:"brand" example:"Royal Canin"`
+	Weight         string                    `json:"weight" example:"3kg"`
+	Species        string                    `json:"species" example:"dog"`       // dog, cat, bird, fish, small_pet, reptile
+	LifeStage      string                    `json:"life_stage" example:"adult"`  // puppy, kitten, adult, senior, all_stages
+	BreedSize      string                    `json:"breed_size" example:"medium"` // toy, small, medium, large, giant
+	GrainFree      bool                      `json:"grain_free" example:"false"`
+	Hypoallergenic bool                      `json:"hypoallergenic" example:"false"`
+	Prescription   bool                      `json:"prescription" example:"false"`
+	Ingredients    []string                  `json:"ingredients" example:"Chicken meal,Rice,Chicken fat"`
+	Allergens      []string                  `json:"allergens" example:"chicken"`
+	Analysis       models.GuaranteedAnalysis `json:"guaranteed_analysis"`
+	KcalPerKg      float64                   `json:"kcal_per_kg" example:"3850"`
+	ImageURL       string                    `json:"image_url" example:"https://example.com/image.jpg"`
+	Status         string                    `json:"status" example:"published"`                     // published (default), draft, scheduled, unlisted
+	PublishAt      *time.Time                `json:"publish_at" example:"2026-12-01T09:00:00+07:00"` // required when scheduled
+	Variants       []ProductVariantInput     `json:"variants" binding:"omitempty,dive"`              // pack sizes or flavours; one is made from price, stock and weight when empty
+}
+
+// ProductPatch represents the request body for updating a product. Fields
+// left out keep their current values.
+type ProductPatch struct {
+	Name           *string                    `json:"name" binding:"omitnil,min=1" example:"Royal Canin Medium Adult"`
+	Description    *string                    `json:"description" example:"Premium dog food for medium breeds"`
+	Price          *models.Money              `json:"price" binding:"omitnil,gt=0" swaggertype:"number" example:"1599.00"` // single-variant products only
+	Stock          *int                       `json:"stock" binding:"omitnil,min=0" example:"45"`                          // single-variant products only
+	CategoryID     *string                    `json:"category_id" binding:"omitnil,uuid" example:"11111111-1111-1111-1111-111111111111"`
+	Brand          *string                    `json:"brand" example:"Royal Canin"`
+	Weight         *string                    `json:"weight" example:"3kg"`
+	Species        *string                    `json:"species" example:"dog"`
+	LifeStage      *string                    `json:"life_stage" example:"adult"`
+	BreedSize      *string                    `json:"breed_size" example:"medium"`
+	GrainFree      *bool                      `json:"grain_free" example:"false"`
+	Hypoallergenic *bool                      `json:"hypoallergenic" example:"false"`
+	Prescription   *bool                      `json:"prescription" example:"false"`
+	Ingredients    *[]string                  `json:"ingredients" example:"Chicken meal,Rice,Chicken fat"`
+	Allergens      *[]string                  `json:"allergens" example:"chicken"`
+	Analysis       *models.GuaranteedAnalysis `json:"guaranteed_analysis"` // replaces the whole analysis
+	KcalPerKg      *float64                   `json:"kcal_per_kg" example:"3850"`
+	ImageURL       *string                    `json:"image_url" example:"https://example.com/image.jpg"`
+	Status         *string                    `json:"status" example:"published"`
+	PublishAt      *time.Time                 `json:"publish_at" example:"2026-12-01T09:00:00+07:00"`
+}
+
+// toProduct builds the product and its variants, noting bad variants in fields
+func (input *ProductInput) toProduct(fields fieldErrors) models.Product {
+	categoryID, _ := uuid.Parse(input.CategoryID)
+	product := models.Product{
+		Name:           strings.TrimSpace(input.Name),
+		Description:    input.Description,
+		Price:          input.Price,
+		Stock:          input.Stock,
+		CategoryID:     categoryID,
+		Brand:          input.Brand,
+		Weight:         input.Weight,
+		Species:        input.Species,
+		LifeStage:      input.LifeStage,
+		BreedSize:      input.BreedSize,
+		GrainFree:      input.GrainFree,
+		Hypoallergenic: input.Hypoallergenic,
+		Prescription:   input.Prescription,
+		Ingredients:    input.Ingredients,
+		Allergens:      input.Allergens,
+		Analysis:       input.Analysis,
+		KcalPerKg:      input.KcalPerKg,
+		ImageURL:       input.ImageURL,
+		Status:         strings.ToLower(strings.TrimSpace(input.Status)),
+		PublishAt:      input.PublishAt,
+	}
+
+	if len(input.Variants) == 0 && product.Price <= 0 {
+		fields.add("price", "price must be greater than 0")
+	}
+	for i := range input.Variants {
+		var variant models.ProductVariant
+		if err := input.Variants[i].applyTo(&variant); err != nil {
+			fields.add(fmt.Sprintf("variants[%d].price", i), "%s", err.Error())
+		}
+		product.Variants = append(product.Variants, variant)
+	}
+	return product
+}
+
+// applyTo copies the fields that were sent onto product
+func (patch *ProductPatch) applyTo(product *models.Product) {
+	setIfSent(&product.Description, patch.Description)
+	setIfSent(&product.Brand, patch.Brand)
+	setIfSent(&product.Weight, patch.Weight)
+	setIfSent(&product.Species, patch.Species)
+	setIfSent(&product.LifeStage, patch.LifeStage)
+	setIfSent(&product.BreedSize, patch.BreedSize)
+	setIfSent(&product.GrainFree, patch.GrainFree)
+	setIfSent(&product.Hypoallergenic, patch.Hypoallergenic)
+	setIfSent(&product.Prescription, patch.Prescription)
+	setIfSent(&product.Ingredients, patch.Ingredients)
+	setIfSent(&product.Allergens, patch.Allergens)
+	setIfSent(&product.Analysis, patch.Analysis)
+	setIfSent(&product.KcalPerKg, patch.KcalPerKg)
+	setIfSent(&product.ImageURL, patch.ImageURL)
+	setIfSent(&product.Price, patch.Price)
+	setIfSent(&product.Stock, patch.Stock)
+	if patch.Name != nil {
+		product.Name = strings.TrimSpace(*patch.Name)
+	}
+	if patch.CategoryID != nil {
+		product.CategoryID, _ = uuid.Parse(*patch.CategoryID)
+	}
+	if patch.Status != nil {
+		product.Status = strings.ToLower(strings.TrimSpace(*patch.Status))
+	}
+	if patch.PublishAt != nil {
+		product.PublishAt = patch.PublishAt
+	}
+}
+
+// setIfSent copies a patch field that was sent onto dst
+func setIfSent[T any](dst *T, value *T) {
+	if value != nil {
+		*dst = *value
+	}
+}
+
+var errCategoryNotFound = errors.New("Category not found")
+
+// checkProductCategory makes sure a product is filed under a live category
+func checkProductCategory(db *gorm.DB, categoryID uuid.UUID) error {
+	var count int64
+	if err := db.Model(&models.Category{}).Where("id = ?", categoryID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return errCategoryNotFound
+	}
+	return nil
+}
+
+var (
+	errProductStatus    = errors.New("status must be one of draft, published, scheduled, unlisted")
+	errPublishAtMissing = errors.New("publish_at is required for a scheduled product")
+)
+
+// normalizePublication checks a product's status and publish time, stamping
+// the time a product is published
+func normalizePublication(product *models.Product, now time.Time) error {
+	if product.Status == "" {
+		product.Status = models.ProductPublished
+	}
+	if !slices.Contains(models.ProductStatuses, product.Status) {
+		return errProductStatus
+	}
+	switch product.Status {
+	case models.ProductScheduled:
+		if product.PublishAt == nil {
+			return errPublishAtMissing
+		}
+	case models.ProductPublished:
+		if product.PublishAt == nil || product.PublishAt.After(now) {
+			product.PublishAt = &now
+		}
+	}
+	return nil
+}
+
+// validateProduct checks a product about to be saved, adding to fields, and
+// returns them as an error when any field is wrong
+func validateProduct(db *gorm.DB, product *models.Product, fields fieldErrors) error {
+	if product.Name == "" {
+		fields.add("name", "name cannot be empty")
+	}
+	var attributes fieldErrors
+	if errors.As(normalizeProductAttributes(product), &attributes) {
+		for field, message := range attributes {
+			fields.add(field, "%s", message)
+		}
+	}
+	switch err := normalizePublication(product, time.Now()); {
+	case errors.Is(err, errPublishAtMissing):
+		fields.add("publish_at", "%s", err.Error())
+	case err != nil:
+		fields.add("status", "%s", err.Error())
+	}
+	if err := checkProductCategory(db, product.CategoryID); errors.Is(err, errCategoryNotFound) {
+		fields.add("category_id", "category_id does not match a category")
+	} else if err != nil {
+		return err
+	}
+	return fields.err()
+}
+
+// checkVariantSKUs makes sure new variants' SKUs are unique, in the request and
+// in the catalog
+func checkVariantSKUs(db *gorm.DB, variants []models.ProductVariant, fields fieldErrors) error {
+	seen := make(map[string]bool)
+	for i, variant := range variants {
+		if variant.SKU == "" {
+			continue
+		}
+		field := fmt.Sprintf("variants[%d].sku", i)
+		if seen[variant.SKU] {
+			fields.add(field, "sku %s is given twice", variant.SKU)
+			continue
+		}
+		seen[variant.SKU] = true
+		var count int64
+		if err := db.Model(&models.ProductVariant{}).Where("sku = ?", variant.SKU).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			fields.add(field, "sku %s already exists", variant.SKU)
+		}
+	}
+	return nil
+}
+
+// productInputError answers a failed validateProduct
+func productInputError(c *gin.Context, err error) {
+	var fields fieldErrors
+	if errors.As(err, &fields) {
+		respondInvalid(c, err)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save product"})
+}
+
+// listedProducts keeps the products the storefront lists right now
+func listedProducts(db *gorm.DB) *gorm.DB {
+	return db.Where("(products.status = ? OR (products.status = ? AND products.publish_at <= ?))",
+		models.ProductPublished, models.ProductScheduled, time.Now())
+}
+
+// purchasableProducts also keeps unlisted products, which open by direct link
+func purchasableProducts(db *gorm.DB) *gorm.DB {
+	return db.Where("(products.status IN ? OR (products.status = ? AND products.publish_at <= ?))",
+		[]string{models.ProductPublished, models.ProductUnlisted}, models.ProductScheduled, time.Now())
+}
+
+// withArchived lets a preload reach archived products and categories, for
+// orders and other records that outlive the catalog entry
+func withArchived(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
 }
 
 // GetProducts godoc
 // @Summary Get all products
-// @Description Get a paginated list of products with optional filtering by category and search
+// @Description Get a paginated, filtered and sorted list of published products with facet counts for building filter sidebars. Each facet counts products under every other active filter. A search matches name, brand, description and category name, tolerates typos and Thai text without spaces, and sorts by relevance.
 // @Tags Products
 // @Accept json
 // @Produce json
-// @Param page query int false "Page number" default(1)
-// @Param page_size query int false "Number of items per page" default(12)
-// @Param category_id query string false "Filter by category ID"
-// @Param search query string false "Search by product name"
-// @Success 200 {object} map[string]interface{} "List of products with pagination info"
+// @Param page query int false "Page number" default(1) minimum(1)
+// @Param page_size query int false "Number of items per page" default(12) minimum(1) maximum(100)
+// @Param category_id query string false "Filter by category ID, including its subcategories"
+// @Param search query string false "Full-text search"
+// @Param brand query []string false "Filter by brand; repeat or comma separate for several" collectionFormat(multi)
+// @Param weight query []string false "Filter by pack weight of any variant, e.g. 2kg" collectionFormat(multi)
+// @Param species query []string false "Filter by species: dog, cat, bird, fish, small_pet, reptile" collectionFormat(multi)
+// @Param life_stage query []string false "Filter by life stage; all_stages food always matches" collectionFormat(multi)
+// @Param breed_size query []string false "Filter by breed size: toy, small, medium, large, giant" collectionFormat(multi)
+// @Param grain_free query bool false "Filter by grain-free"
+// @Param hypoallergenic query bool false "Filter by hypoallergenic"
+// @Param prescription query bool false "Filter by veterinary prescription diet"
+// @Param ingredient query []string false "Only products listing every given ingredient" collectionFormat(multi)
+// @Param exclude_allergen query []string false "Leave out products containing any given allergen" collectionFormat(multi)
+// @Param min_protein query number false "Lowest guaranteed protein %"
+// @Param max_fat query number false "Highest guaranteed fat %"
+// @Param min_price query number false "Lowest price"
+// @Param max_price query number false "Highest price"
+// @Param in_stock query bool false "Only products in stock"
+// @Param sort query string false "Sort order" Enums(relevance, newest, price_asc, price_desc, best_selling, rating)
+// @Success 200 {object} map[string]interface{} "List of products with pagination info and facets"
+// @Failure 400 {object} map[string]interface{} "Invalid query parameter"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /products [get]
 func GetProducts(c *gin.Context) {
-	var products []models.Product
-
-	// Pagination
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "12"))
-	offset := (page - 1) * pageSize
+	params, err := parseProductListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	listProducts(c, &params)
+}
 
-	// Filter by category
-	categoryID := c.Query("category_id")
-	query := config.GetDB().Preload("Category")
+// listProducts answers a page of products with facets, for the storefront or,
+// when params.admin is set, for the admin catalog
+func listProducts(c *gin.Context, params *productListParams) {
+	db := config.GetDB()
+	offset := (params.Page - 1) * params.PageSize
 
-	if categoryID != "" {
-		query = query.Where("category_id = ?", categoryID)
+	// A category lists the products of its subcategories too
+	if params.CategoryID != "" {
+		var err error
+		categoryID, _ := uuid.Parse(params.CategoryID)
+		if params.categories, err = categorySubtree(db, categoryID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+			return
+		}
 	}
 
-	// Search by name
-	search := c.Query("search")
-	if search != "" {
-		query = query.Where("name ILIKE ?", "%"+search+"%")
+	// Full-text search over name, brand, description and category
+	var rank map[uuid.UUID]int
+	if params.Search != "" {
+		hits, err := search.Search(db, params.Search, search.MaxResults)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search products"})
+			return
+		}
+		rank = make(map[uuid.UUID]int, len(hits))
+		params.matches = make([]uuid.UUID, len(hits))
+		for i, hit := range hits {
+			rank[hit.ProductID] = i
+			params.matches[i] = hit.ProductID
+		}
 	}
 
 	var total int64
-	query.Model(&models.Product{}).Count(&total)
+	if err := params.filter(db.Model(&models.Product{}), "").Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	var products []models.Product
+	query := params.filter(db.Preload("Category").Preload("Images", imagesInOrder), "")
+	if params.Sort == sortRelevance {
+		// Matches are capped, so page through them in relevance order here
+		if err := query.Find(&products).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+			return
+		}
+		sort.Slice(products, func(i, j int) bool { return rank[products[i].ID] < rank[products[j].ID] })
+		start, end := min(offset, len(products)), min(offset+params.PageSize, len(products))
+		products = products[start:end]
+	} else if err := params.order(db, query).Offset(offset).Limit(params.PageSize).Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	if err := applySalePricesToList(db, products); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
 
-	if err := query.Offset(offset).Limit(pageSize).Find(&products).Error; err != nil {
+	facets, err := productFacets(db, params)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"products": products,
-		"page":     page,
-		"pageSize": pageSize,
+		"page":     params.Page,
+		"pageSize": params.PageSize,
 		"total":    total,
+		"sort":     params.Sort,
+		"facets":   facets,
 	})
 }
 
 // GetProduct godoc
 // @Summary Get a product by ID
-// @Description Get detailed information about a specific product
+// @Description Get detailed information about a specific product, with its variants grouped under it, its published questions and answers, and products frequently bought together, bought by the same customers and related by brand or category. Drafts and products not yet published are not found; unlisted products open by direct link.
 // @Tags Products
 // @Accept json
 // @Produce json
 // @Param id path string true "Product ID"
-// @Success 200 {object} map[string]interface{} "Product details"
+// @Success 200 {object} map[string]interface{} "Product details, Q&A and recommendations"
 // @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /products/{id} [get]
 func GetProduct(c *gin.Context) {
-	productID := c.Param("id")
+	product, err := productDetail(config.GetDB().Scopes(purchasableProducts), c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+		return
+	}
+	questions, err := productQuestions(config.GetDB(), product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+		return
+	}
+	recommendations, err := productRecommendations(config.GetDB(), &product)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"product":         product,
+		"questions":       questions,
+		"recommendations": recommendations,
+	})
+}
 
+// productDetail loads a product the way its product page shows it: category,
+// member prices, variants in display order and any running sale price
+func productDetail(query *gorm.DB, id string) (models.Product, error) {
 	var product models.Product
-	if err := config.GetDB().Preload("Category").Where("id = ?", productID).First(&product).Error; err != nil {
+	if _, err := uuid.Parse(id); err != nil {
+		return product, gorm.ErrRecordNotFound
+	}
+	if err := query.Preload("Category").Preload("TierPrices").Preload("Images", imagesInOrder).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC, price ASC") }).
+		Where("id = ?", id).First(&product).Error; err != nil {
+		return product, err
+	}
+	return product, applySalePrices(config.GetDB(), &product)
+}
+
+// GetAdminProducts godoc
+// @Summary Get all products (Admin only)
+// @Description Get the catalog listing with products of every publication status, taking the same filters, sorting and paging as GET /products. Facets add a count per status.
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query []string false "Filter by status: draft, published, scheduled, unlisted" collectionFormat(multi)
+// @Param search query string false "Full-text search"
+// @Param category_id query string false "Filter by category ID, including its subcategories"
+// @Param page query int false "Page number" default(1) minimum(1)
+// @Param page_size query int false "Number of items per page" default(12) minimum(1) maximum(100)
+// @Param sort query string false "Sort order" Enums(relevance, newest, price_asc, price_desc, best_selling, rating)
+// @Success 200 {object} map[string]interface{} "List of products with pagination info and facets"
+// @Failure 400 {object} map[string]interface{} "Invalid query parameter"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products [get]
+func GetAdminProducts(c *gin.Context) {
+	params, err := parseProductListParams(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	params.admin = true
+	params.Statuses = listParam(c, "status")
+	for _, status := range params.Statuses {
+		if !slices.Contains(models.ProductStatuses, status) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errProductStatus.Error()})
+			return
+		}
+	}
+	listProducts(c, &params)
+}
+
+// PreviewProduct godoc
+// @Summary Preview a product page (Admin only)
+// @Description Get a product of any publication status exactly as its product page would show it, with whether the storefront lists it and lets customers buy it right now
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Success 200 {object} map[string]interface{} "Product details and visibility"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/{id}/preview [get]
+func PreviewProduct(c *gin.Context) {
+	product, err := productDetail(config.GetDB(), c.Param("id"))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
 		return
 	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch product"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"product": product})
+	now := time.Now()
+	c.JSON(http.StatusOK, gin.H{
+		"product":     product,
+		"preview":     true,
+		"listed":      product.Listed(now),
+		"purchasable": product.Purchasable(now),
+	})
 }
 
 // CreateProduct godoc
 // @Summary Create a new product (Admin only)
-// @Description Create a new product in the catalog
+// @Description Create a new product in the catalog. It is published straight away unless another status is given: drafts stay hidden until published, and scheduled products go live at publish_at. Pass variants to sell several pack sizes or flavours; without them the product gets a single default variant from its price, stock and weight. Unknown fields are rejected, and validation errors list each bad field under fields.
 // @Tags Admin - Products
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param product body ProductInput true "Product data"
 // @Success 201 {object} map[string]interface{} "Product created successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 400 {object} map[string]interface{} "Validation failed, with an error per field"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /admin/products [post]
 func CreateProduct(c *gin.Context) {
-	var product models.Product
-	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var input ProductInput
+	if err := bindStrictJSON(c, &input); err != nil {
+		respondInvalid(c, err)
 		return
 	}
 
-	if err := config.GetDB().Create(&product).Error; err != nil {
+	db := config.GetDB()
+	fields := fieldErrors{}
+	product := input.toProduct(fields)
+	if err := checkVariantSKUs(db, product.Variants, fields); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save product"})
+		return
+	}
+	if err := validateProduct(db, &product, fields); err != nil {
+		productInputError(c, err)
+		return
+	}
+	if len(product.Variants) == 0 {
+		product.Variants = []models.ProductVariant{models.DefaultVariant(&product)}
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("TierPrices", "Images").Create(&product).Error; err != nil {
+			return err
+		}
+		if err := syncProductFromVariants(tx, product.ID); err != nil {
+			return err
+		}
+		return search.IndexProduct(tx, product.ID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create product"})
 		return
 	}
 
-	// Reload with category
-	config.GetDB().Preload("Category").First(&product, product.ID)
+	// Reload with category and variants
+	config.GetDB().Preload("Category").Preload("Variants").First(&product, "id = ?", product.ID)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "Product created successfully",
@@ -131,20 +564,20 @@ func CreateProduct(c *gin.Context) {
 
 // UpdateProduct godoc
 // @Summary Update a product (Admin only)
-// @Description Update an existing product's information
+// @Description Update some of a product's fields; fields left out keep their values. Price, stock and weight carry over to a single-variant product; products with several variants take them from their variants. Unknown fields are rejected, and validation errors list each bad field under fields. PUT is accepted as well as PATCH.
 // @Tags Admin - Products
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Product ID"
-// @Param product body ProductInput true "Product data"
+// @Param product body ProductPatch true "Fields to change"
 // @Success 200 {object} map[string]interface{} "Product updated successfully"
-// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 400 {object} map[string]interface{} "Validation failed, with an error per field"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
 // @Failure 404 {object} map[string]interface{} "Product not found"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
-// @Router /admin/products/{id} [put]
+// @Router /admin/products/{id} [patch]
 func UpdateProduct(c *gin.Context) {
 	productID := c.Param("id")
 
@@ -154,18 +587,58 @@ func UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	if err := c.ShouldBindJSON(&product); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var patch ProductPatch
+	if err := bindStrictJSON(c, &patch); err != nil {
+		respondInvalid(c, err)
 		return
 	}
+	patch.applyTo(&product)
+	if err := validateProduct(config.GetDB(), &product, fieldErrors{}); err != nil {
+		productInputError(c, err)
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Variants", "TierPrices", "Images").Save(&product).Error; err != nil {
+			return err
+		}
 
-	if err := config.GetDB().Save(&product).Error; err != nil {
+		var variants []models.ProductVariant
+		if err := tx.Where("product_id = ?", product.ID).Find(&variants).Error; err != nil {
+			return err
+		}
+		if len(variants) == 1 {
+			updates := map[string]interface{}{}
+			if patch.Price != nil {
+				updates["price"] = product.Price
+			}
+			if patch.Stock != nil {
+				updates["stock"] = product.Stock
+			}
+			if patch.Weight != nil {
+				updates["weight"] = product.Weight
+			}
+			if len(updates) > 0 {
+				if err := tx.Model(&variants[0]).Updates(updates).Error; err != nil {
+					return err
+				}
+			}
+		}
+		if err := syncProductFromVariants(tx, product.ID); err != nil {
+			return err
+		}
+		if err := sendProductAlerts(tx, product.ID); err != nil {
+			return err
+		}
+		return search.IndexProduct(tx, product.ID)
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update product"})
 		return
 	}
 
-	// Reload with category
-	config.GetDB().Preload("Category").First(&product, product.ID)
+	// Reload with category and variants
+	config.GetDB().Preload("Category").Preload("Variants").First(&product, "id = ?", product.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"message": "Product updated successfully",
@@ -174,32 +647,145 @@ func UpdateProduct(c *gin.Context) {
 }
 
 // DeleteProduct godoc
-// @Summary Delete a product (Admin only)
-// @Description Remove a product from the catalog
+// @Summary Archive a product (Admin only)
+// @Description Archive a product: it leaves the catalog, search and every cart, and its autoship subscriptions are cancelled. Orders keep showing it.
 // @Tags Admin - Products
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Product ID"
-// @Success 200 {object} map[string]interface{} "Product deleted successfully"
+// @Success 200 {object} map[string]interface{} "Product archived successfully"
 // @Failure 401 {object} map[string]interface{} "Unauthorized"
 // @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Product not found"
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /admin/products/{id} [delete]
 func DeleteProduct(c *gin.Context) {
-	productID := c.Param("id")
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
 
-	if err := config.GetDB().Delete(&models.Product{}, "id = ?", productID).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete product"})
+	var product models.Product
+	if err := config.GetDB().Where("id = ?", id).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	var cartItems, subscriptions int64
+	err = config.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("product_id = ?", id).Delete(&models.Cart{})
+		if result.Error != nil {
+			return result.Error
+		}
+		cartItems = result.RowsAffected
+
+		now := time.Now()
+		result = tx.Model(&models.Subscription{}).
+			Where("product_id = ? AND status <> ?", id, models.SubscriptionCancelled).
+			Updates(map[string]interface{}{
+				"status":       models.SubscriptionCancelled,
+				"cancelled_at": now,
+				"paused_until": nil,
+				"retry_at":     nil,
+				"last_error":   "Product is no longer sold",
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		subscriptions = result.RowsAffected
+
+		if err := tx.Delete(&product).Error; err != nil {
+			return err
+		}
+		return search.RemoveProduct(tx, id)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to archive product"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Product deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":                 "Product archived successfully",
+		"cart_items_removed":      cartItems,
+		"subscriptions_cancelled": subscriptions,
+	})
+}
+
+// GetArchivedProducts godoc
+// @Summary Get archived products (Admin only)
+// @Description Get the products that were deleted from the catalog, most recently archived first
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of archived products"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/archived [get]
+func GetArchivedProducts(c *gin.Context) {
+	var products []models.Product
+	if err := config.GetDB().Unscoped().Preload("Category", withArchived).
+		Where("archived_at IS NOT NULL").Order("archived_at DESC").Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"products": products})
+}
+
+// RestoreProduct godoc
+// @Summary Restore an archived product (Admin only)
+// @Description Put an archived product back in the catalog and search. Its category must not be archived. Cancelled subscriptions stay cancelled.
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Success 200 {object} map[string]interface{} "Product restored"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Archived product not found"
+// @Failure 409 {object} map[string]interface{} "The product's category is archived"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/{id}/restore [post]
+func RestoreProduct(c *gin.Context) {
+	db := config.GetDB()
+
+	var product models.Product
+	if err := db.Unscoped().Where("id = ? AND archived_at IS NOT NULL", c.Param("id")).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Archived product not found"})
+		return
+	}
+	if err := checkProductCategory(db, product.CategoryID); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The product's category is archived; restore the category first"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&product).Update("archived_at", nil).Error; err != nil {
+			return err
+		}
+		return search.IndexProduct(tx, product.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore product"})
+		return
+	}
+
+	db.Preload("Category").Preload("Variants").First(&product, "id = ?", product.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Product restored",
+		"product": product,
+	})
 }
 
 // GetProductsByCategory godoc
 // @Summary Get products by category
-// @Description Get all products belonging to a specific category
+// @Description Get all published products belonging to a specific category or any of its subcategories
 // @Tags Products
 // @Accept json
 // @Produce json
@@ -208,10 +794,25 @@ func DeleteProduct(c *gin.Context) {
 // @Failure 500 {object} map[string]interface{} "Internal server error"
 // @Router /products/category/{categoryId} [get]
 func GetProductsByCategory(c *gin.Context) {
-	categoryID := c.Param("categoryId")
+	categoryID, err := uuid.Parse(c.Param("categoryId"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"products": []models.Product{}})
+		return
+	}
+	categories, err := categorySubtree(config.GetDB(), categoryID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
 
 	var products []models.Product
-	if err := config.GetDB().Preload("Category").Where("category_id = ?", categoryID).Find(&products).Error; err != nil {
+	if err := config.GetDB().Scopes(listedProducts).Preload("Category").Preload("Images", imagesInOrder).
+		Where("category_id IN ?", categories).Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
+		return
+	}
+
+	if err := applySalePricesToList(config.GetDB(), products); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch products"})
 		return
 	}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"fmt"
+	"pet-food-ecommerce/models"
+	"slices"
+	"strings"
+)
+
+// oneOf checks an optional enum value, returning it lower-cased
+func oneOf(field, value string, allowed []string) (string, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value != "" && !slices.Contains(allowed, value) {
+		return "", fmt.Errorf("%s must be one of %s", field, strings.Join(allowed, ", "))
+	}
+	return value, nil
+}
+
+// cleanList trims a list, dropping blanks and case-insensitive duplicates
+func cleanList(values []string, lower bool) []string {
+	seen := make(map[string]bool)
+	out := []string{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if lower {
+			value = strings.ToLower(value)
+		}
+		key := strings.ToLower(value)
+		if value == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		out = append(out, value)
+	}
+	return out
+}
+
+// normalizeProductAttributes validates the pet food attributes an admin sent
+// and stores them in canonical form, returning fieldErrors for the bad ones
+func normalizeProductAttributes(product *models.Product) error {
+	fields := fieldErrors{}
+	var err error
+	if product.Species, err = oneOf("species", product.Species, models.SpeciesValues); err != nil {
+		fields.add("species", "%s", err.Error())
+	}
+	if product.LifeStage, err = oneOf("life_stage", product.LifeStage, models.LifeStageValues); err != nil {
+		fields.add("life_stage", "%s", err.Error())
+	}
+	if product.BreedSize, err = oneOf("breed_size", product.BreedSize, models.BreedSizeValues); err != nil {
+		fields.add("breed_size", "%s", err.Error())
+	}
+
+	product.Ingredients = cleanList(product.Ingredients, false)
+	product.Allergens = cleanList(product.Allergens, true)
+
+	analysis := product.Analysis
+	nutrients := []struct {
+		name  string
+		value *float64
+	}{
+		{"protein", analysis.Protein}, {"fat", analysis.Fat}, {"fibre", analysis.Fibre}, {"moisture", analysis.Moisture},
+	}
+	var sum float64
+	for _, nutrient := range nutrients {
+		if nutrient.value == nil {
+			continue
+		}
+		if *nutrient.value < 0 || *nutrient.value > 100 {
+			field := "guaranteed_analysis." + nutrient.name
+			fields.add(field, "%s must be between 0 and 100", field)
+		}
+		sum += *nutrient.value
+	}
+	if sum > 100 {
+		fields.add("guaranteed_analysis", "guaranteed_analysis adds up to more than 100%%")
+	}
+
+	// Pure fat is about 9000 kcal/kg, so nothing edible is denser
+	if product.KcalPerKg < 0 || product.KcalPerKg > 9000 {
+		fields.add("kcal_per_kg", "kcal_per_kg must be between 0 and 9000")
+	}
+	return fields.err()
+}
@@ -0,0 +1,757 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"pet-food-ecommerce/search"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxProductCSVSize = 5 << 20 // 5 MB
+	maxProductCSVRows = 5000
+
+	// Spreadsheet apps need the byte order mark to open UTF-8, such as Thai names
+	csvByteOrderMark = "\ufeff"
+
+	// csvListSeparator separates ingredients and allergens within a cell
+	csvListSeparator = ";"
+)
+
+// productCSVColumns are the columns of the product CSV, one row per variant.
+// Product columns repeat on every variant row of the product.
+var productCSVColumns = []string{
+	"sku", "product_id", "name", "variant_name", "category", "status", "publish_at",
+	"price", "stock", "weight", "flavour", "barcode", "position",
+	"brand", "description", "image_url",
+	"species", "life_stage", "breed_size", "grain_free", "hypoallergenic", "prescription",
+	"ingredients", "allergens", "protein", "fat", "fibre", "moisture", "kcal_per_kg",
+}
+
+// Import row actions
+const (
+	importCreate     = "create"      // creates a new product with this variant
+	importAddVariant = "add_variant" // adds this variant to a product
+	importUpdate     = "update"      // updates the variant with this SKU and its product
+)
+
+// ProductImportRow is one line of the import report
+type ProductImportRow struct {
+	Row       int        `json:"row"` // line in the file, the header being line 1
+	SKU       string     `json:"sku"`
+	Name      string     `json:"name"`
+	Action    string     `json:"action,omitempty"` // create, add_variant or update
+	ProductID *uuid.UUID `json:"product_id,omitempty"`
+	Errors    []string   `json:"errors,omitempty"`
+}
+
+// ProductImportSummary counts what an import does
+type ProductImportSummary struct {
+	Rows            int `json:"rows"`
+	ProductsCreated int `json:"products_created"`
+	ProductsUpdated int `json:"products_updated"`
+	VariantsCreated int `json:"variants_created"`
+	VariantsUpdated int `json:"variants_updated"`
+	RowsWithErrors  int `json:"rows_with_errors"`
+}
+
+// importProduct is a product the import creates or changes, with its variants
+type importProduct struct {
+	product      models.Product
+	isNew        bool
+	variants     []*importVariant
+	variantCount int // variants it ends up with, existing ones included
+}
+
+type importVariant struct {
+	variant models.ProductVariant
+	isNew   bool
+}
+
+// productImport is the plan for applying a CSV, built and checked before any write
+type productImport struct {
+	rows     []ProductImportRow
+	targets  []*importProduct // the product of each row, nil for rows with errors
+	products []*importProduct // in order of first appearance
+	summary  ProductImportSummary
+}
+
+// csvRow reads the cells of one CSV line by column name, collecting errors
+type csvRow struct {
+	values  []string
+	columns map[string]int
+	errors  []string
+}
+
+// cell returns a trimmed cell and whether the file has the column at all
+func (r *csvRow) cell(column string) (string, bool) {
+	i, ok := r.columns[column]
+	if !ok || i >= len(r.values) {
+		return "", ok
+	}
+	return strings.TrimSpace(r.values[i]), true
+}
+
+func (r *csvRow) fail(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// text sets dst when the file has the column; a blank cell clears it
+func (r *csvRow) text(column string, dst *string) {
+	if value, ok := r.cell(column); ok {
+		*dst = value
+	}
+}
+
+func (r *csvRow) integer(column string, dst *int) {
+	value, ok := r.cell(column)
+	if !ok {
+		return
+	}
+	if value == "" {
+		*dst = 0
+		return
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		r.fail("%s must be a whole number of 0 or more", column)
+		return
+	}
+	*dst = n
+}
+
+func (r *csvRow) boolean(column string, dst *bool) {
+	value, ok := r.cell(column)
+	if !ok {
+		return
+	}
+	switch strings.ToLower(value) {
+	case "", "false", "no", "n", "0":
+		*dst = false
+	case "true", "yes", "y", "1":
+		*dst = true
+	default:
+		r.fail("%s must be true or false", column)
+	}
+}
+
+// number reads an optional decimal; a blank cell clears it
+func (r *csvRow) number(column string, dst **float64) {
+	value, ok := r.cell(column)
+	if !ok {
+		return
+	}
+	if value == "" {
+		*dst = nil
+		return
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		r.fail("%s must be a number", column)
+		return
+	}
+	*dst = &f
+}
+
+// decimal reads a number that is 0 when blank
+func (r *csvRow) decimal(column string, dst *float64) {
+	value, ok := r.cell(column)
+	if !ok {
+		return
+	}
+	if value == "" {
+		*dst = 0
+		return
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		r.fail("%s must be a number", column)
+		return
+	}
+	*dst = f
+}
+
+func (r *csvRow) list(column string, dst *[]string) {
+	if value, ok := r.cell(column); ok {
+		*dst = strings.Split(value, csvListSeparator)
+	}
+}
+
+// price reads a required amount
+func (r *csvRow) price(column string, dst *models.Money) {
+	value, ok := r.cell(column)
+	if !ok {
+		return
+	}
+	if value == "" {
+		r.fail("%s is required", column)
+		return
+	}
+	amount, err := models.ParseMoney(value)
+	if err != nil || amount <= 0 {
+		r.fail("%s must be an amount greater than 0 with at most 2 decimal places", column)
+		return
+	}
+	*dst = amount
+}
+
+// categoryResolver finds a category by name, or by slug when the name is
+// shared by several categories in the tree
+type categoryResolver struct {
+	byName map[string][]models.Category
+	bySlug map[string]models.Category
+}
+
+func newCategoryResolver(db *gorm.DB) (*categoryResolver, error) {
+	var categories []models.Category
+	if err := db.Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	resolver := &categoryResolver{
+		byName: make(map[string][]models.Category),
+		bySlug: make(map[string]models.Category),
+	}
+	for _, category := range categories {
+		key := strings.ToLower(strings.TrimSpace(category.Name))
+		resolver.byName[key] = append(resolver.byName[key], category)
+		resolver.bySlug[category.Slug] = category
+	}
+	return resolver, nil
+}
+
+func (r *categoryResolver) resolve(value string) (uuid.UUID, error) {
+	matches := r.byName[strings.ToLower(value)]
+	switch {
+	case len(matches) == 1:
+		return matches[0].ID, nil
+	case len(matches) > 1:
+		return uuid.Nil, fmt.Errorf("category %q matches %d categories; use its slug instead", value, len(matches))
+	}
+	if category, ok := r.bySlug[strings.ToLower(value)]; ok {
+		return category.ID, nil
+	}
+	return uuid.Nil, fmt.Errorf("category %q not found", value)
+}
+
+// label is the name a category is exported under: its name, or its slug when
+// the name would not resolve back to it
+func (r *categoryResolver) label(category models.Category) string {
+	if len(r.byName[strings.ToLower(strings.TrimSpace(category.Name))]) == 1 {
+		return category.Name
+	}
+	return category.Slug
+}
+
+// readProductCSV reads the header and rows of an uploaded CSV
+func readProductCSV(file io.Reader) (map[string]int, [][]string, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, nil, errors.New("CSV file is empty")
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("CSV file could not be read: %v", err)
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, csvByteOrderMark)
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(productCSVColumns, name) {
+			return nil, nil, fmt.Errorf("unknown column %q; columns are %s", name, strings.Join(productCSVColumns, ", "))
+		}
+		if _, dup := columns[name]; dup {
+			return nil, nil, fmt.Errorf("column %q appears more than once", name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["sku"]; !ok {
+		return nil, nil, errors.New("the sku column is required")
+	}
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, nil, fmt.Errorf("CSV file could not be read: %v", err)
+	}
+	if len(records) > maxProductCSVRows {
+		return nil, nil, fmt.Errorf("CSV file may have at most %d rows", maxProductCSVRows)
+	}
+	return columns, records, nil
+}
+
+// planProductImport matches every row to a product and variant and validates
+// it, without writing anything. An existing SKU updates that variant and its
+// product; a new SKU is added to the product named by product_id, or else to a
+// new product shared with the other new rows of the same name.
+func planProductImport(db *gorm.DB, columns map[string]int, records [][]string) (*productImport, error) {
+	categories, err := newCategoryResolver(db)
+	if err != nil {
+		return nil, err
+	}
+
+	skus := make([]string, 0, len(records))
+	productIDs := []uuid.UUID{}
+	for _, record := range records {
+		row := csvRow{values: record, columns: columns}
+		sku, _ := row.cell("sku")
+		skus = append(skus, strings.ToUpper(sku))
+		if value, _ := row.cell("product_id"); value != "" {
+			if id, err := uuid.Parse(value); err == nil {
+				productIDs = append(productIDs, id)
+			}
+		}
+	}
+
+	var variants []models.ProductVariant
+	if err := db.Where("sku IN ?", skus).Find(&variants).Error; err != nil {
+		return nil, err
+	}
+	variantsBySKU := make(map[string]models.ProductVariant, len(variants))
+	for _, variant := range variants {
+		variantsBySKU[variant.SKU] = variant
+		productIDs = append(productIDs, variant.ProductID)
+	}
+
+	var existing []models.Product
+	if err := db.Preload("Variants").Where("id IN ?", productIDs).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	products := make(map[uuid.UUID]*importProduct, len(existing))
+	for _, product := range existing {
+		products[product.ID] = &importProduct{product: product, variantCount: len(product.Variants)}
+	}
+
+	plan := &productImport{}
+	newProducts := make(map[string]*importProduct) // by lower-cased name
+	planned := make(map[*importProduct]bool)
+	seenSKUs := make(map[string]int)
+	now := time.Now()
+
+	for i, record := range records {
+		line := i + 2
+		if isBlankRecord(record) {
+			continue
+		}
+		row := csvRow{values: record, columns: columns}
+		report := ProductImportRow{Row: line, SKU: skus[i]}
+		report.Name, _ = row.cell("name")
+
+		var target *importProduct
+		var variant *importVariant
+		switch sku, productID := skus[i], cellValue(&row, "product_id"); {
+		case sku == "":
+			row.fail("sku is required")
+		case seenSKUs[sku] != 0:
+			row.fail("sku %s is already on row %d", sku, seenSKUs[sku])
+		default:
+			seenSKUs[sku] = line
+			if existingVariant, ok := variantsBySKU[sku]; ok {
+				target = products[existingVariant.ProductID]
+				if target == nil {
+					row.fail("sku %s belongs to an archived product; restore it first", sku)
+					break
+				}
+				if productID != "" && productID != target.product.ID.String() {
+					row.fail("sku %s belongs to product %s, not %s", sku, target.product.ID, productID)
+					target = nil
+					break
+				}
+				report.Action = importUpdate
+				variant = &importVariant{variant: existingVariant}
+			} else if productID != "" {
+				id, err := uuid.Parse(productID)
+				if err == nil {
+					target = products[id]
+				}
+				if target == nil {
+					row.fail("product_id %s not found", productID)
+					break
+				}
+				report.Action = importAddVariant
+				variant = &importVariant{variant: models.ProductVariant{ProductID: target.product.ID, SKU: sku}, isNew: true}
+			} else {
+				if report.Name == "" {
+					row.fail("name is required for a new product")
+					break
+				}
+				key := strings.ToLower(report.Name)
+				target = newProducts[key]
+				if target == nil {
+					target = &importProduct{isNew: true}
+					newProducts[key] = target
+				}
+				report.Action = importAddVariant
+				if !planned[target] {
+					report.Action = importCreate
+				}
+				variant = &importVariant{variant: models.ProductVariant{SKU: sku}, isNew: true}
+			}
+		}
+
+		if target != nil {
+			// Apply to copies so a row with errors leaves the plan untouched
+			product := target.product
+			applyProductColumns(&row, &product, categories, report.Action == importCreate)
+			applyVariantColumns(&row, &variant.variant, variant.isNew)
+			if len(row.errors) == 0 {
+				var attributes fieldErrors
+				if errors.As(normalizeProductAttributes(&product), &attributes) {
+					row.errors = append(row.errors, attributes.messages()...)
+				} else if err := normalizePublication(&product, now); err != nil {
+					row.fail("%s", err.Error())
+				}
+			}
+			if len(row.errors) == 0 {
+				target.product = product
+				target.variants = append(target.variants, variant)
+				if variant.isNew {
+					target.variantCount++
+				}
+				if !target.isNew {
+					report.ProductID = &target.product.ID
+				}
+				report.Name = target.product.Name
+				if !planned[target] {
+					planned[target] = true
+					plan.products = append(plan.products, target)
+				}
+			}
+		}
+
+		report.Errors = row.errors
+		if len(report.Errors) > 0 {
+			plan.summary.RowsWithErrors++
+			target = nil
+		}
+		plan.rows = append(plan.rows, report)
+		plan.targets = append(plan.targets, target)
+	}
+
+	for _, target := range plan.products {
+		if target.isNew {
+			plan.summary.ProductsCreated++
+		} else {
+			plan.summary.ProductsUpdated++
+		}
+		for _, variant := range target.variants {
+			if variant.isNew {
+				plan.summary.VariantsCreated++
+			} else {
+				plan.summary.VariantsUpdated++
+			}
+		}
+	}
+	plan.summary.Rows = len(plan.rows)
+	return plan, nil
+}
+
+func cellValue(row *csvRow, column string) string {
+	value, _ := row.cell(column)
+	return value
+}
+
+func isBlankRecord(record []string) bool {
+	for _, value := range record {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// applyProductColumns copies the product columns of a row onto product. Columns
+// missing from the file keep their current values; a blank status keeps the
+// current one, or publishes a new product.
+func applyProductColumns(row *csvRow, product *models.Product, categories *categoryResolver, isNew bool) {
+	if name, ok := row.cell("name"); ok || isNew {
+		if name == "" {
+			row.fail("name is required")
+		} else {
+			product.Name = name
+		}
+	}
+	if value, ok := row.cell("category"); ok || isNew {
+		if value == "" {
+			row.fail("category is required")
+		} else if id, err := categories.resolve(value); err != nil {
+			row.fail("%s", err.Error())
+		} else {
+			product.CategoryID = id
+		}
+	}
+	if value := cellValue(row, "status"); value != "" {
+		product.Status = strings.ToLower(value)
+	}
+	if value, ok := row.cell("publish_at"); ok {
+		if value == "" {
+			product.PublishAt = nil
+		} else if at, err := time.Parse(time.RFC3339, value); err != nil {
+			row.fail("publish_at must be a date and time such as 2026-12-01T09:00:00+07:00")
+		} else {
+			product.PublishAt = &at
+		}
+	}
+	row.text("brand", &product.Brand)
+	row.text("description", &product.Description)
+	row.text("image_url", &product.ImageURL)
+	row.text("species", &product.Species)
+	row.text("life_stage", &product.LifeStage)
+	row.text("breed_size", &product.BreedSize)
+	row.boolean("grain_free", &product.GrainFree)
+	row.boolean("hypoallergenic", &product.Hypoallergenic)
+	row.boolean("prescription", &product.Prescription)
+	row.list("ingredients", &product.Ingredients)
+	row.list("allergens", &product.Allergens)
+	row.number("protein", &product.Analysis.Protein)
+	row.number("fat", &product.Analysis.Fat)
+	row.number("fibre", &product.Analysis.Fibre)
+	row.number("moisture", &product.Analysis.Moisture)
+	row.decimal("kcal_per_kg", &product.KcalPerKg)
+}
+
+// applyVariantColumns copies the variant columns of a row onto variant
+func applyVariantColumns(row *csvRow, variant *models.ProductVariant, isNew bool) {
+	if _, ok := row.cell("price"); ok {
+		row.price("price", &variant.Price)
+	} else if isNew {
+		row.fail("price is required")
+	}
+	row.integer("stock", &variant.Stock)
+	row.integer("position", &variant.Position)
+	row.text("weight", &variant.Weight)
+	row.text("flavour", &variant.Flavour)
+	row.text("barcode", &variant.Barcode)
+	row.text("variant_name", &variant.Name)
+	if variant.Name == "" {
+		variant.Name = variant.Weight
+	}
+	if variant.Name == "" {
+		variant.Name = "Standard"
+	}
+}
+
+// apply writes the plan in one transaction, so a failure leaves the catalog as it was
+func (plan *productImport) apply(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, target := range plan.products {
+			product := &target.product
+			// The product's own weight only describes it while it has one pack size
+			if target.variantCount == 1 && len(target.variants) == 1 {
+				product.Weight = target.variants[0].variant.Weight
+			}
+			if target.isNew {
+				product.Variants = nil
+				if err := tx.Omit("Variants", "TierPrices", "Images").Create(product).Error; err != nil {
+					return err
+				}
+			} else if err := tx.Omit("Variants", "TierPrices", "Images").Save(product).Error; err != nil {
+				return err
+			}
+
+			for _, item := range target.variants {
+				item.variant.ProductID = product.ID
+				if item.isNew && !target.isNew {
+					if err := pinProductWidePrices(tx, product.ID); err != nil {
+						return err
+					}
+				}
+				if item.isNew {
+					if err := tx.Create(&item.variant).Error; err != nil {
+						return err
+					}
+				} else if err := tx.Save(&item.variant).Error; err != nil {
+					return err
+				}
+			}
+			if err := syncProductFromVariants(tx, product.ID); err != nil {
+				return err
+			}
+			if err := sendProductAlerts(tx, product.ID); err != nil {
+				return err
+			}
+			if err := search.IndexProduct(tx, product.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// ImportProducts godoc
+// @Summary Import products from CSV (Admin only)
+// @Description Create or update products from a CSV file with one row per variant, as written by the export. A row whose sku exists updates that variant and its product. A new sku is added to the product given by product_id, or else creates a product; new rows with the same name become variants of one product. Columns left out of the file keep their current values, and category takes a category name or slug. With dry_run the rows are only checked. Otherwise the file is applied in one transaction only when every row is valid; each row of the report lists its errors.
+// @Tags Admin - Products
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV file, UTF-8, up to 5 MB and 5000 rows"
+// @Param dry_run query bool false "Only validate and report what would change"
+// @Success 200 {object} map[string]interface{} "Import report"
+// @Failure 400 {object} map[string]interface{} "Missing or unreadable file"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 422 {object} map[string]interface{} "Some rows are invalid; nothing was imported"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/import [post]
+func ImportProducts(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+		return
+	}
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file is required"})
+		return
+	}
+	if header.Size > maxProductCSVSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File must be 5 MB or smaller"})
+		return
+	}
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	columns, records, err := readProductCSV(io.LimitReader(file, maxProductCSVSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	db := config.GetDB()
+	plan, err := planProductImport(db, columns, records)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check import"})
+		return
+	}
+
+	valid := plan.summary.RowsWithErrors == 0
+	if dryRun {
+		c.JSON(http.StatusOK, gin.H{
+			"dry_run": true,
+			"valid":   valid,
+			"summary": plan.summary,
+			"rows":    plan.rows,
+		})
+		return
+	}
+	if !valid {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Some rows are invalid; nothing was imported",
+			"valid":   false,
+			"summary": plan.summary,
+			"rows":    plan.rows,
+		})
+		return
+	}
+
+	if err := plan.apply(db); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import products"})
+		return
+	}
+	// New products have their IDs now
+	for i, target := range plan.targets {
+		plan.rows[i].ProductID = &target.product.ID
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Products imported successfully",
+		"valid":   true,
+		"summary": plan.summary,
+		"rows":    plan.rows,
+	})
+}
+
+// ExportProducts godoc
+// @Summary Export products to CSV (Admin only)
+// @Description Download every product that is not archived, whatever its status, as a CSV with one row per variant. The file can be edited in a spreadsheet and imported again.
+// @Tags Admin - Products
+// @Produce text/csv
+// @Security BearerAuth
+// @Success 200 {file} file "Product CSV"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/export [get]
+func ExportProducts(c *gin.Context) {
+	db := config.GetDB()
+
+	categories, err := newCategoryResolver(db)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export products"})
+		return
+	}
+	var products []models.Product
+	if err := db.Preload("Category", withArchived).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB { return db.Order("position ASC, price ASC") }).
+		Order("name ASC, id ASC").Find(&products).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export products"})
+		return
+	}
+
+	filename := "products-" + time.Now().Format("20060102") + ".csv"
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Status(http.StatusOK)
+
+	io.WriteString(c.Writer, csvByteOrderMark)
+	writer := csv.NewWriter(c.Writer)
+	writer.Write(productCSVColumns)
+	for _, product := range products {
+		for _, variant := range product.Variants {
+			writer.Write(productCSVRecord(&product, &variant, categories.label(product.Category)))
+		}
+	}
+	writer.Flush()
+}
+
+// productCSVRecord lays out a variant and its product in productCSVColumns order
+func productCSVRecord(product *models.Product, variant *models.ProductVariant, category string) []string {
+	optional := func(f *float64) string {
+		if f == nil {
+			return ""
+		}
+		return strconv.FormatFloat(*f, 'f', -1, 64)
+	}
+	publishAt := ""
+	if product.PublishAt != nil {
+		publishAt = product.PublishAt.Format(time.RFC3339)
+	}
+	kcal := ""
+	if product.KcalPerKg != 0 {
+		kcal = strconv.FormatFloat(product.KcalPerKg, 'f', -1, 64)
+	}
+
+	return []string{
+		variant.SKU, product.ID.String(), product.Name, variant.Name, category, product.Status, publishAt,
+		variant.Price.String(), strconv.Itoa(variant.Stock), variant.Weight, variant.Flavour, variant.Barcode, strconv.Itoa(variant.Position),
+		product.Brand, product.Description, product.ImageURL,
+		product.Species, product.LifeStage, product.BreedSize,
+		strconv.FormatBool(product.GrainFree), strconv.FormatBool(product.Hypoallergenic), strconv.FormatBool(product.Prescription),
+		strings.Join(product.Ingredients, csvListSeparator), strings.Join(product.Allergens, csvListSeparator),
+		optional(product.Analysis.Protein), optional(product.Analysis.Fat), optional(product.Analysis.Fibre), optional(product.Analysis.Moisture),
+		kcal,
+	}
+}
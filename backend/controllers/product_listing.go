@@ -0,0 +1,431 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"pet-food-ecommerce/models"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 12
+	maxPageSize     = 100
+)
+
+// Product listing sort orders; relevance is only available with a search
+const (
+	sortRelevance   = "relevance"
+	sortNewest      = "newest"
+	sortPriceAsc    = "price_asc"
+	sortPriceDesc   = "price_desc"
+	sortBestSelling = "best_selling"
+	sortRating      = "rating"
+)
+
+var productSortOrders = map[string]string{
+	sortNewest:      "products.created_at DESC",
+	sortPriceAsc:    "products.price ASC",
+	sortPriceDesc:   "products.price DESC",
+	sortBestSelling: "COALESCE(sales.sold, 0) DESC",
+	sortRating:      "products.rating_average DESC, products.rating_count DESC",
+}
+
+// Diet flags are boolean product columns, each filterable and counted as a facet
+var dietFlags = []string{"grain_free", "hypoallergenic", "prescription"}
+
+// Price facet buckets in baht; the last bucket is open-ended
+var priceFacetBounds = []models.Money{30000, 70000, 150000, 300000}
+
+// Facet names, also used to leave a facet's own filter out of its counts
+const (
+	facetCategory  = "category"
+	facetBrand     = "brand"
+	facetWeight    = "weight"
+	facetSpecies   = "species"
+	facetLifeStage = "life_stage"
+	facetBreedSize = "breed_size"
+	facetPrice     = "price"
+	facetInStock   = "in_stock"
+	facetStatus    = "status" // admin listings only
+)
+
+// productListParams are the validated query parameters of GET /products
+type productListParams struct {
+	Page       int
+	PageSize   int
+	Sort       string
+	Search     string
+	CategoryID string
+	Brands     []string
+	Weights    []string
+	Species    []string
+	LifeStages []string
+	BreedSizes []string
+	MinPrice   *models.Money
+	MaxPrice   *models.Money
+	InStock    bool
+
+	// Pet food attributes
+	Diet             map[string]bool // grain_free, hypoallergenic, prescription
+	Ingredients      []string        // must list every one
+	ExcludeAllergens []string
+	MinProtein       *float64
+	MaxFat           *float64
+
+	// Products matching the search, in relevance order; nil without a search
+	matches []uuid.UUID
+	// CategoryID and its descendants
+	categories []uuid.UUID
+	// Admin listings see every status and may filter by it
+	admin    bool
+	Statuses []string
+}
+
+// listParam reads a multi-value parameter given repeated (brand=a&brand=b) or
+// comma separated (brand=a,b)
+func listParam(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, value := range strings.Split(raw, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+func percentParam(c *gin.Context, key string) (*float64, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	percent, err := strconv.ParseFloat(raw, 64)
+	if err != nil || percent < 0 || percent > 100 {
+		return nil, fmt.Errorf("%s must be a percentage between 0 and 100", key)
+	}
+	return &percent, nil
+}
+
+func priceParam(c *gin.Context, key string) (*models.Money, error) {
+	raw := c.Query(key)
+	if raw == "" {
+		return nil, nil
+	}
+	amount, err := models.ParseMoney(raw)
+	if err != nil || amount < 0 {
+		return nil, fmt.Errorf("%s must be a non-negative amount", key)
+	}
+	return &amount, nil
+}
+
+// parseProductListParams validates the listing's paging, sort and filters
+func parseProductListParams(c *gin.Context) (productListParams, error) {
+	params := productListParams{
+		Page:       1,
+		PageSize:   defaultPageSize,
+		Search:     strings.TrimSpace(c.Query("search")),
+		CategoryID: c.Query("category_id"),
+		Brands:     listParam(c, "brand"),
+		Weights:    listParam(c, "weight"),
+		Species:    listParam(c, "species"),
+		LifeStages: listParam(c, "life_stage"),
+		BreedSizes: listParam(c, "breed_size"),
+		Diet:       make(map[string]bool),
+
+		Ingredients:      listParam(c, "ingredient"),
+		ExcludeAllergens: cleanList(listParam(c, "exclude_allergen"), true),
+	}
+
+	if raw := c.Query("page"); raw != "" {
+		page, err := strconv.Atoi(raw)
+		if err != nil || page < 1 {
+			return params, errors.New("page must be a positive integer")
+		}
+		params.Page = page
+	}
+	if raw := c.Query("page_size"); raw != "" {
+		pageSize, err := strconv.Atoi(raw)
+		if err != nil || pageSize < 1 || pageSize > maxPageSize {
+			return params, fmt.Errorf("page_size must be between 1 and %d", maxPageSize)
+		}
+		params.PageSize = pageSize
+	}
+
+	params.Sort = c.Query("sort")
+	if params.Sort == "" {
+		params.Sort = sortNewest
+		if params.Search != "" {
+			params.Sort = sortRelevance
+		}
+	}
+	if params.Sort == sortRelevance {
+		if params.Search == "" {
+			return params, errors.New("sort=relevance needs a search")
+		}
+	} else if _, ok := productSortOrders[params.Sort]; !ok {
+		return params, errors.New("sort must be one of relevance, newest, price_asc, price_desc, best_selling, rating")
+	}
+
+	if params.CategoryID != "" {
+		if _, err := uuid.Parse(params.CategoryID); err != nil {
+			return params, errors.New("category_id must be a valid ID")
+		}
+	}
+
+	var err error
+	if params.MinPrice, err = priceParam(c, "min_price"); err != nil {
+		return params, err
+	}
+	if params.MaxPrice, err = priceParam(c, "max_price"); err != nil {
+		return params, err
+	}
+	if params.MinPrice != nil && params.MaxPrice != nil && *params.MinPrice > *params.MaxPrice {
+		return params, errors.New("min_price cannot be greater than max_price")
+	}
+
+	for _, attribute := range []struct {
+		key     string
+		values  []string
+		allowed []string
+	}{
+		{"species", params.Species, models.SpeciesValues},
+		{"life_stage", params.LifeStages, models.LifeStageValues},
+		{"breed_size", params.BreedSizes, models.BreedSizeValues},
+	} {
+		for i, value := range attribute.values {
+			if attribute.values[i], err = oneOf(attribute.key, value, attribute.allowed); err != nil {
+				return params, err
+			}
+		}
+	}
+	for _, flag := range dietFlags {
+		if raw := c.Query(flag); raw != "" {
+			value, err := strconv.ParseBool(raw)
+			if err != nil {
+				return params, fmt.Errorf("%s must be true or false", flag)
+			}
+			params.Diet[flag] = value
+		}
+	}
+	if params.MinProtein, err = percentParam(c, "min_protein"); err != nil {
+		return params, err
+	}
+	if params.MaxFat, err = percentParam(c, "max_fat"); err != nil {
+		return params, err
+	}
+
+	if raw := c.Query("in_stock"); raw != "" {
+		inStock, err := strconv.ParseBool(raw)
+		if err != nil {
+			return params, errors.New("in_stock must be true or false")
+		}
+		params.InStock = inStock
+	}
+
+	return params, nil
+}
+
+// filter applies every filter except the named facet's own, so a facet's
+// counts show what selecting another of its values would return
+func (p *productListParams) filter(query *gorm.DB, except string) *gorm.DB {
+	if !p.admin {
+		query = query.Scopes(listedProducts)
+	} else if len(p.Statuses) > 0 && except != facetStatus {
+		query = query.Where("products.status IN ?", p.Statuses)
+	}
+	if p.matches != nil {
+		query = query.Where("products.id IN ?", p.matches)
+	}
+	if p.CategoryID != "" && except != facetCategory {
+		query = query.Where("products.category_id IN ?", p.categories)
+	}
+	if len(p.Brands) > 0 && except != facetBrand {
+		query = query.Where("products.brand IN ?", p.Brands)
+	}
+	if len(p.Weights) > 0 && except != facetWeight {
+		query = query.Where("EXISTS (SELECT 1 FROM product_variants v WHERE v.product_id = products.id AND v.weight IN ?)", p.Weights)
+	}
+	if len(p.Species) > 0 && except != facetSpecies {
+		query = query.Where("products.species IN ?", p.Species)
+	}
+	if len(p.LifeStages) > 0 && except != facetLifeStage {
+		// Food for every life stage suits whichever stage was picked
+		query = query.Where("products.life_stage IN ?", append(slices.Clone(p.LifeStages), models.LifeStageAllStages))
+	}
+	if len(p.BreedSizes) > 0 && except != facetBreedSize {
+		query = query.Where("products.breed_size IN ?", p.BreedSizes)
+	}
+	for _, flag := range dietFlags {
+		if value, ok := p.Diet[flag]; ok && except != flag {
+			query = query.Where("products."+flag+" = ?", value)
+		}
+	}
+	for _, ingredient := range p.Ingredients {
+		query = query.Where("LOWER(products.ingredients) LIKE ?", "%"+strings.ToLower(ingredient)+"%")
+	}
+	for _, allergen := range p.ExcludeAllergens {
+		// Allergens are stored as a JSON list of lower-case strings
+		quoted, _ := json.Marshal(allergen)
+		query = query.Where("(products.allergens IS NULL OR products.allergens NOT LIKE ?)", "%"+string(quoted)+"%")
+	}
+	if p.MinProtein != nil {
+		query = query.Where("products.analysis_protein >= ?", *p.MinProtein)
+	}
+	if p.MaxFat != nil {
+		query = query.Where("products.analysis_fat <= ?", *p.MaxFat)
+	}
+	if except != facetPrice {
+		if p.MinPrice != nil {
+			query = query.Where("products.price >= ?", *p.MinPrice)
+		}
+		if p.MaxPrice != nil {
+			query = query.Where("products.price <= ?", *p.MaxPrice)
+		}
+	}
+	if p.InStock && except != facetInStock {
+		query = query.Where("products.stock > 0")
+	}
+	return query
+}
+
+// order sorts the listing, with the ID as a tie-breaker so pages are stable
+func (p *productListParams) order(db, query *gorm.DB) *gorm.DB {
+	if p.Sort == sortBestSelling {
+		sold := db.Table("order_items").
+			Select("order_items.product_id, SUM(order_items.quantity) AS sold").
+			Joins("JOIN orders ON orders.id = order_items.order_id").
+			Where("orders.status NOT IN ?", []string{"cancelled", "refunded"}).
+			Group("order_items.product_id")
+		query = query.Joins("LEFT JOIN (?) AS sales ON sales.product_id = products.id", sold)
+	}
+	return query.Select("products.*").Order(productSortOrders[p.Sort]).Order("products.id")
+}
+
+type facetCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+type priceFacetBucket struct {
+	Min   models.Money  `json:"min"`
+	Max   *models.Money `json:"max,omitempty"`
+	Count int64         `json:"count"`
+}
+
+// productFacets counts the products behind every filter value
+func productFacets(db *gorm.DB, params *productListParams) (gin.H, error) {
+	products := func(except string) *gorm.DB {
+		return params.filter(db.Model(&models.Product{}), except)
+	}
+	group := func(except, column string) ([]facetCount, error) {
+		counts := []facetCount{}
+		err := products(except).
+			Select(column + " AS value, COUNT(DISTINCT products.id) AS count").
+			Where(column + " <> ''").
+			Group(column).Order("count DESC").Order("value").
+			Scan(&counts).Error
+		return counts, err
+	}
+
+	categories := []facetCount{}
+	if err := products(facetCategory).
+		Select("CAST(products.category_id AS TEXT) AS value, COUNT(*) AS count").
+		Group("products.category_id").Order("count DESC").
+		Scan(&categories).Error; err != nil {
+		return nil, err
+	}
+	brands, err := group(facetBrand, "products.brand")
+	if err != nil {
+		return nil, err
+	}
+	weights := []facetCount{}
+	if err := products(facetWeight).
+		Joins("JOIN product_variants ON product_variants.product_id = products.id").
+		Select("product_variants.weight AS value, COUNT(DISTINCT products.id) AS count").
+		Where("product_variants.weight <> ''").
+		Group("product_variants.weight").Order("count DESC").Order("value").
+		Scan(&weights).Error; err != nil {
+		return nil, err
+	}
+	species, err := group(facetSpecies, "products.species")
+	if err != nil {
+		return nil, err
+	}
+	lifeStages, err := group(facetLifeStage, "products.life_stage")
+	if err != nil {
+		return nil, err
+	}
+	breedSizes, err := group(facetBreedSize, "products.breed_size")
+	if err != nil {
+		return nil, err
+	}
+	diet := make(map[string]int64, len(dietFlags))
+	for _, flag := range dietFlags {
+		var count int64
+		if err := products(flag).Where("products."+flag+" = ?", true).Count(&count).Error; err != nil {
+			return nil, err
+		}
+		diet[flag] = count
+	}
+
+	// One CASE bucket per price range
+	bucket := "CASE"
+	args := make([]interface{}, len(priceFacetBounds))
+	for i, bound := range priceFacetBounds {
+		bucket += fmt.Sprintf(" WHEN products.price < ? THEN %d", i)
+		args[i] = bound
+	}
+	bucket += fmt.Sprintf(" ELSE %d END", len(priceFacetBounds))
+	var bucketCounts []struct {
+		Bucket int
+		Count  int64
+	}
+	if err := products(facetPrice).
+		Select("("+bucket+") AS bucket, COUNT(*) AS count", args...).
+		Group("bucket").Scan(&bucketCounts).Error; err != nil {
+		return nil, err
+	}
+	prices := make([]priceFacetBucket, len(priceFacetBounds)+1)
+	for i := range prices {
+		if i > 0 {
+			prices[i].Min = priceFacetBounds[i-1]
+		}
+		if i < len(priceFacetBounds) {
+			prices[i].Max = &priceFacetBounds[i]
+		}
+	}
+	for _, counted := range bucketCounts {
+		prices[counted.Bucket].Count = counted.Count
+	}
+
+	var inStock int64
+	if err := products(facetInStock).Where("products.stock > 0").Count(&inStock).Error; err != nil {
+		return nil, err
+	}
+
+	facets := gin.H{
+		"category":   categories,
+		"brand":      brands,
+		"weight":     weights,
+		"species":    species,
+		"life_stage": lifeStages,
+		"breed_size": breedSizes,
+		"diet":       diet,
+		"price":      prices,
+		"in_stock":   inStock,
+	}
+	if params.admin {
+		if facets[facetStatus], err = group(facetStatus, "products.status"); err != nil {
+			return nil, err
+		}
+	}
+	return facets, nil
+}
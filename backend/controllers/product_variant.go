@@ -0,0 +1,321 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errVariantRequired = errors.New("variant_id is required for products with several variants")
+	errVariantNotFound = errors.New("Variant not found")
+	errLastVariant     = errors.New("A product needs at least one variant")
+)
+
+// ProductVariantInput represents the request body for creating/updating a product variant
+type ProductVariantInput struct {
+	Name     string       `json:"name" binding:"required" example:"4kg"`
+	SKU      string       `json:"sku" example:"RC-MED-ADULT-4KG"` // generated when left empty
+	Barcode  string       `json:"barcode" example:"3182550402194"`
+	Weight   string       `json:"weight" example:"4kg"`
+	Flavour  string       `json:"flavour" example:"Chicken"`
+	Price    models.Money `json:"price" binding:"required" swaggertype:"number" example:"1599.00"`
+	Stock    int          `json:"stock" binding:"min=0" example:"20"`
+	Position int          `json:"position" example:"1"`
+}
+
+// applyTo copies the input onto a variant
+func (input *ProductVariantInput) applyTo(variant *models.ProductVariant) error {
+	if input.Price <= 0 {
+		return errors.New("price must be greater than 0")
+	}
+	variant.Name = strings.TrimSpace(input.Name)
+	variant.SKU = strings.ToUpper(strings.TrimSpace(input.SKU))
+	variant.Barcode = strings.TrimSpace(input.Barcode)
+	variant.Weight = input.Weight
+	variant.Flavour = input.Flavour
+	variant.Price = input.Price
+	variant.Stock = input.Stock
+	variant.Position = input.Position
+	return nil
+}
+
+// syncProductFromVariants keeps the product's "from" price and total stock in step
+// with its variants
+func syncProductFromVariants(tx *gorm.DB, productID uuid.UUID) error {
+	var summary struct {
+		Price models.Money
+		Stock int
+	}
+	if err := tx.Model(&models.ProductVariant{}).Where("product_id = ?", productID).
+		Select("COALESCE(MIN(price), 0) AS price, COALESCE(SUM(stock), 0) AS stock").
+		Scan(&summary).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.Product{}).Where("id = ?", productID).Updates(map[string]interface{}{
+		"price": summary.Price,
+		"stock": summary.Stock,
+	}).Error
+}
+
+// pinProductWidePrices ties the product-wide tier prices and unfinished flash
+// sales of a single-variant product to that variant before another variant is
+// added, so a fixed price meant for one pack size never reaches the others
+func pinProductWidePrices(tx *gorm.DB, productID uuid.UUID) error {
+	var variants []models.ProductVariant
+	if err := tx.Where("product_id = ?", productID).Limit(2).Find(&variants).Error; err != nil {
+		return err
+	}
+	if len(variants) != 1 {
+		return nil
+	}
+	variantID := variants[0].ID
+
+	var prices []models.TierPrice
+	if err := tx.Where("product_id = ? AND variant_id IS NULL", productID).Find(&prices).Error; err != nil {
+		return err
+	}
+	for _, price := range prices {
+		// Where the variant has its own price for the tier, the lower of the two is kept
+		var own models.TierPrice
+		err := tx.Where("variant_id = ? AND tier = ?", variantID, price.Tier).First(&own).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			err = tx.Model(&price).Update("variant_id", variantID).Error
+		case err == nil:
+			if price.Price < own.Price {
+				if err := tx.Model(&own).Update("price", price.Price).Error; err != nil {
+					return err
+				}
+			}
+			err = tx.Delete(&price).Error
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&models.FlashSale{}).
+		Where("product_id = ? AND variant_id IS NULL AND ends_at > ?", productID, time.Now()).
+		Update("variant_id", variantID).Error
+}
+
+// resolveVariant finds the variant a customer picked, defaulting to the only
+// variant of single-variant products
+func resolveVariant(db *gorm.DB, productID uuid.UUID, variantID string) (models.ProductVariant, error) {
+	var variant models.ProductVariant
+	if variantID != "" {
+		if err := db.Where("id = ? AND product_id = ?", variantID, productID).First(&variant).Error; err != nil {
+			return variant, errVariantNotFound
+		}
+		return variant, nil
+	}
+
+	var variants []models.ProductVariant
+	if err := db.Where("product_id = ?", productID).Limit(2).Find(&variants).Error; err != nil {
+		return variant, err
+	}
+	if len(variants) != 1 {
+		return variant, errVariantRequired
+	}
+	return variants[0], nil
+}
+
+// CreateProductVariant godoc
+// @Summary Add a variant to a product (Admin only)
+// @Description Add a pack size or flavour with its own SKU, barcode, price and stock
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param variant body ProductVariantInput true "Variant data"
+// @Success 201 {object} map[string]interface{} "Variant created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 409 {object} map[string]interface{} "SKU already exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/{id}/variants [post]
+func CreateProductVariant(c *gin.Context) {
+	var product models.Product
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	var input ProductVariantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	variant := models.ProductVariant{ProductID: product.ID}
+	if err := input.applyTo(&variant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var existing models.ProductVariant
+	if variant.SKU != "" {
+		if err := config.GetDB().Where("sku = ?", variant.SKU).First(&existing).Error; err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "SKU already exists"})
+			return
+		}
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := pinProductWidePrices(tx, product.ID); err != nil {
+			return err
+		}
+		if err := tx.Create(&variant).Error; err != nil {
+			return err
+		}
+		if err := syncProductFromVariants(tx, product.ID); err != nil {
+			return err
+		}
+		return sendProductAlerts(tx, product.ID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create variant"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Variant created successfully",
+		"variant": variant,
+	})
+}
+
+// UpdateProductVariant godoc
+// @Summary Update a product variant (Admin only)
+// @Description Update a variant's SKU, barcode, weight, price or stock
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param variantId path string true "Variant ID"
+// @Param variant body ProductVariantInput true "Variant data"
+// @Success 200 {object} map[string]interface{} "Variant updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Variant not found"
+// @Failure 409 {object} map[string]interface{} "SKU already exists"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/{id}/variants/{variantId} [put]
+func UpdateProductVariant(c *gin.Context) {
+	var variant models.ProductVariant
+	if err := config.GetDB().Where("id = ? AND product_id = ?", c.Param("variantId"), c.Param("id")).
+		First(&variant).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errVariantNotFound.Error()})
+		return
+	}
+
+	var input ProductVariantInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	sku := variant.SKU
+	if err := input.applyTo(&variant); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if variant.SKU == "" {
+		variant.SKU = sku
+	}
+
+	var existing models.ProductVariant
+	if err := config.GetDB().Where("sku = ? AND id <> ?", variant.SKU, variant.ID).First(&existing).Error; err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "SKU already exists"})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&variant).Error; err != nil {
+			return err
+		}
+		if err := syncProductFromVariants(tx, variant.ProductID); err != nil {
+			return err
+		}
+		return sendProductAlerts(tx, variant.ProductID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update variant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Variant updated successfully",
+		"variant": variant,
+	})
+}
+
+// DeleteProductVariant godoc
+// @Summary Delete a product variant (Admin only)
+// @Description Remove a variant and take it out of carts. Past orders keep its SKU and name. The last variant of a product cannot be deleted.
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param variantId path string true "Variant ID"
+// @Success 200 {object} map[string]interface{} "Variant deleted successfully"
+// @Failure 400 {object} map[string]interface{} "Last variant of the product"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Variant not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/products/{id}/variants/{variantId} [delete]
+func DeleteProductVariant(c *gin.Context) {
+	var variant models.ProductVariant
+	if err := config.GetDB().Where("id = ? AND product_id = ?", c.Param("variantId"), c.Param("id")).
+		First(&variant).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": errVariantNotFound.Error()})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&models.ProductVariant{}).Where("product_id = ?", variant.ProductID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count <= 1 {
+			return errLastVariant
+		}
+		if err := tx.Where("variant_id = ?", variant.ID).Delete(&models.Cart{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("variant_id = ?", variant.ID).Delete(&models.TierPrice{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&variant).Error; err != nil {
+			return err
+		}
+		if err := syncProductFromVariants(tx, variant.ProductID); err != nil {
+			return err
+		}
+		return sendProductAlerts(tx, variant.ProductID)
+	})
+	if errors.Is(err, errLastVariant) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete variant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Variant deleted successfully"})
+}
@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// PromotionTierInput represents one quantity break of a tiered promotion
+type PromotionTierInput struct {
+	MinQuantity int `json:"min_quantity" binding:"required,min=1" example:"2"`
+	PercentOff  int `json:"percent_off" binding:"required,min=1,max=100" example:"10"`
+}
+
+// PromotionInput represents the request body for creating/updating a promotion
+type PromotionInput struct {
+	Name           string               `json:"name" binding:"required" example:"Buy 3 cans get 1 free"`
+	Description    string               `json:"description" example:"Applies to all wet cat food"`
+	Type           string               `json:"type" binding:"required,oneof=buy_x_get_y bundle tiered" example:"buy_x_get_y"`
+	Priority       int                  `json:"priority" example:"10"`
+	Stackable      bool                 `json:"stackable" example:"false"`
+	BuyQuantity    int                  `json:"buy_quantity" binding:"min=0" example:"3"`
+	GetQuantity    int                  `json:"get_quantity" binding:"min=0" example:"1"`
+	GetPercentOff  int                  `json:"get_percent_off" binding:"min=0,max=100" example:"100"`
+	BundleQuantity int                  `json:"bundle_quantity" binding:"min=0" example:"0"`
+	BundlePrice    models.Money         `json:"bundle_price" swaggertype:"number" example:"0"`
+	Tiers          []PromotionTierInput `json:"tiers" binding:"dive"`
+	StartsAt       *time.Time           `json:"starts_at" example:"2026-01-01T00:00:00+07:00"`
+	EndsAt         *time.Time           `json:"ends_at" example:"2026-01-31T23:59:59+07:00"`
+	Active         *bool                `json:"active" example:"true"`
+	CategoryIDs    []string             `json:"category_ids"` // subcategories are covered too
+	ProductIDs     []string             `json:"product_ids"`
+}
+
+// validate checks the type-specific rules that binding tags cannot express
+func (input *PromotionInput) validate() error {
+	switch input.Type {
+	case models.PromotionTypeBuyXGetY:
+		if input.BuyQuantity < 1 || input.GetQuantity < 1 || input.GetPercentOff < 1 {
+			return errors.New("buy_quantity, get_quantity and get_percent_off are required for buy_x_get_y promotions")
+		}
+	case models.PromotionTypeBundle:
+		if input.BundleQuantity < 2 || input.BundlePrice <= 0 {
+			return errors.New("bundle_quantity of at least 2 and a positive bundle_price are required for bundle promotions")
+		}
+	case models.PromotionTypeTiered:
+		if len(input.Tiers) == 0 {
+			return errors.New("at least one tier is required for tiered promotions")
+		}
+	}
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return errors.New("ends_at must be after starts_at")
+	}
+	return nil
+}
+
+// applyTo copies the input onto a promotion and resolves its restrictions
+func (input *PromotionInput) applyTo(db *gorm.DB, promotion *models.Promotion) error {
+	promotion.Name = input.Name
+	promotion.Description = input.Description
+	promotion.Type = input.Type
+	promotion.Priority = input.Priority
+	promotion.Stackable = input.Stackable
+	promotion.BuyQuantity = input.BuyQuantity
+	promotion.GetQuantity = input.GetQuantity
+	promotion.GetPercentOff = input.GetPercentOff
+	promotion.BundleQuantity = input.BundleQuantity
+	promotion.BundlePrice = input.BundlePrice
+	promotion.StartsAt = input.StartsAt
+	promotion.EndsAt = input.EndsAt
+	promotion.Active = input.Active == nil || *input.Active
+
+	promotion.Tiers = nil
+	for _, tier := range input.Tiers {
+		promotion.Tiers = append(promotion.Tiers, models.PromotionTier{
+			MinQuantity: tier.MinQuantity,
+			PercentOff:  tier.PercentOff,
+		})
+	}
+
+	promotion.Categories = nil
+	if len(input.CategoryIDs) > 0 {
+		if err := db.Where("id IN ?", input.CategoryIDs).Find(&promotion.Categories).Error; err != nil {
+			return err
+		}
+		if len(promotion.Categories) != len(input.CategoryIDs) {
+			return errors.New("one or more category_ids do not exist")
+		}
+	}
+
+	promotion.Products = nil
+	if len(input.ProductIDs) > 0 {
+		if err := db.Where("id IN ?", input.ProductIDs).Find(&promotion.Products).Error; err != nil {
+			return err
+		}
+		if len(promotion.Products) != len(input.ProductIDs) {
+			return errors.New("one or more product_ids do not exist")
+		}
+	}
+
+	return nil
+}
+
+// GetPromotions godoc
+// @Summary Get all promotions (Admin only)
+// @Description Get all automatic promotions in evaluation order
+// @Tags Admin - Promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of promotions"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/promotions [get]
+func GetPromotions(c *gin.Context) {
+	var promotions []models.Promotion
+	if err := config.GetDB().Preload("Tiers").Preload("Categories").Preload("Products").
+		Order("priority DESC, created_at ASC").Find(&promotions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch promotions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"promotions": promotions})
+}
+
+// CreatePromotion godoc
+// @Summary Create a promotion (Admin only)
+// @Description Create a buy-X-get-Y, bundle or tiered quantity promotion that applies automatically
+// @Tags Admin - Promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param promotion body PromotionInput true "Promotion data"
+// @Success 201 {object} map[string]interface{} "Promotion created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/promotions [post]
+func CreatePromotion(c *gin.Context) {
+	var input PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var promotion models.Promotion
+	if err := input.applyTo(config.GetDB(), &promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := config.GetDB().Create(&promotion).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create promotion"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":   "Promotion created successfully",
+		"promotion": promotion,
+	})
+}
+
+// UpdatePromotion godoc
+// @Summary Update a promotion (Admin only)
+// @Description Update an existing promotion's rules, tiers and restrictions
+// @Tags Admin - Promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Param promotion body PromotionInput true "Promotion data"
+// @Success 200 {object} map[string]interface{} "Promotion updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Promotion not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/promotions/{id} [put]
+func UpdatePromotion(c *gin.Context) {
+	promotionID := c.Param("id")
+
+	var promotion models.Promotion
+	if err := config.GetDB().Where("id = ?", promotionID).First(&promotion).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+
+	var input PromotionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.applyTo(config.GetDB(), &promotion); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Tiers", "Categories", "Products").Save(&promotion).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("promotion_id = ?", promotion.ID).Delete(&models.PromotionTier{}).Error; err != nil {
+			return err
+		}
+		for i := range promotion.Tiers {
+			promotion.Tiers[i].PromotionID = promotion.ID
+			if err := tx.Create(&promotion.Tiers[i]).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&promotion).Association("Categories").Replace(promotion.Categories); err != nil {
+			return err
+		}
+		return tx.Model(&promotion).Association("Products").Replace(promotion.Products)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update promotion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Promotion updated successfully",
+		"promotion": promotion,
+	})
+}
+
+// DeletePromotion godoc
+// @Summary Delete a promotion (Admin only)
+// @Description Remove an automatic promotion. Orders keep the discount they were given.
+// @Tags Admin - Promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} map[string]interface{} "Promotion deleted successfully"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Promotion not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/promotions/{id} [delete]
+func DeletePromotion(c *gin.Context) {
+	promotionID := c.Param("id")
+
+	var promotion models.Promotion
+	if err := config.GetDB().Where("id = ?", promotionID).First(&promotion).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Promotion not found"})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("promotion_id = ?", promotion.ID).Delete(&models.PromotionTier{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&promotion).Association("Categories").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(&promotion).Association("Products").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(&promotion).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete promotion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Promotion deleted successfully"})
+}
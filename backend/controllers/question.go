@@ -0,0 +1,450 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errAnswerNotPurchased = errors.New("Only the store and customers who received this product can answer")
+	errQuestionHidden     = errors.New("This question is not open for answers")
+)
+
+// QuestionInput represents the request body for asking about a product
+type QuestionInput struct {
+	Body string `json:"body" binding:"required,min=5,max=1000" example:"Is this suitable for a 3 month old puppy?"`
+}
+
+// AnswerInput represents the request body for answering a question
+type AnswerInput struct {
+	Body string `json:"body" binding:"required,min=2,max=2000" example:"Yes, it is made for puppies from 2 months."`
+}
+
+// ModerateQuestionRequest represents the request body for moderating a question or answer
+type ModerateQuestionRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending approved hidden" example:"approved"`
+}
+
+// approvedAnswers preloads only the answers shown on the product page, the
+// store's first
+func approvedAnswers(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ?", models.QuestionApproved).Order("from_store DESC, created_at ASC")
+}
+
+// productQuestions loads a product's public Q&A, newest questions first
+func productQuestions(db *gorm.DB, productID uuid.UUID) ([]models.ProductQuestion, error) {
+	questions := []models.ProductQuestion{}
+	err := db.Preload("User").Preload("Answers", approvedAnswers).Preload("Answers.User").
+		Where("product_id = ? AND status = ?", productID, models.QuestionApproved).
+		Order("created_at DESC").Find(&questions).Error
+	return questions, err
+}
+
+// notifyAnswered tells the asker their question has a published answer,
+// unless they answered it themselves
+func notifyAnswered(tx *gorm.DB, question *models.ProductQuestion, answer *models.ProductAnswer) error {
+	if answer.UserID == question.UserID {
+		return nil
+	}
+	var product models.Product
+	if err := tx.Unscoped().Select("id", "name").First(&product, "id = ?", question.ProductID).Error; err != nil {
+		return err
+	}
+	body := answer.Body
+	if runes := []rune(body); len(runes) > 140 {
+		body = string(runes[:140]) + "…"
+	}
+	return notifyUser(tx, &models.Notification{
+		UserID: question.UserID,
+		Type:   models.NotificationQuestionAnswered,
+		Title:  "Your question about " + product.Name + " was answered",
+		Body:   body,
+		Link:   "/products/" + product.ID.String(),
+	})
+}
+
+// AskQuestion godoc
+// @Summary Ask about a product
+// @Description Ask a question about a product. It is shown on the product page once a moderator approves it, and you are notified when it is answered.
+// @Tags Q&A
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param request body QuestionInput true "Question"
+// @Success 201 {object} map[string]interface{} "Question submitted"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /products/{id}/questions [post]
+func AskQuestion(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var input QuestionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var product models.Product
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if err := db.Scopes(purchasableProducts).Where("id = ?", c.Param("id")).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	question := models.ProductQuestion{
+		ProductID: product.ID,
+		UserID:    userID,
+		Body:      strings.TrimSpace(input.Body),
+		Status:    models.QuestionPending,
+	}
+	if err := db.Omit("Answers").Create(&question).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit question"})
+		return
+	}
+	db.Preload("User").Preload("Answers").First(&question, "id = ?", question.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Question submitted and waiting for moderation",
+		"question": question,
+	})
+}
+
+// GetMyQuestions godoc
+// @Summary Get my questions
+// @Description List the questions the current user asked, with their moderation status and published answers
+// @Tags Q&A
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of questions"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /questions [get]
+func GetMyQuestions(c *gin.Context) {
+	var questions []models.ProductQuestion
+	if err := config.GetDB().Preload("User").Preload("Product", withArchived).
+		Preload("Answers", approvedAnswers).Preload("Answers.User").
+		Where("user_id = ?", c.GetString("user_id")).Order("created_at DESC").Find(&questions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch questions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"questions": questions})
+}
+
+// AnswerQuestion godoc
+// @Summary Answer a question
+// @Description Answer a product question. Admins answer for the store: the answer is published at once, approving the question too, and the asker is notified. Customers who received the product can answer approved questions; their answers are published after moderation.
+// @Tags Q&A
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Question ID"
+// @Param request body AnswerInput true "Answer"
+// @Success 201 {object} map[string]interface{} "Answer posted"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Not the store or a verified buyer"
+// @Failure 404 {object} map[string]interface{} "Question not found"
+// @Failure 409 {object} map[string]interface{} "Question is hidden"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /questions/{id}/answers [post]
+func AnswerQuestion(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+	fromStore := c.GetString("user_role") == "admin"
+
+	var input AnswerInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var question models.ProductQuestion
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+	if err := db.Where("id = ?", c.Param("id")).First(&question).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	answer := models.ProductAnswer{
+		QuestionID: question.ID,
+		UserID:     userID,
+		Body:       strings.TrimSpace(input.Body),
+		FromStore:  fromStore,
+		Status:     models.QuestionPending,
+	}
+	if fromStore {
+		answer.Status = models.QuestionApproved
+		answer.ModeratedBy = &userID
+		now := time.Now()
+		answer.ModeratedAt = &now
+	} else {
+		if question.Status != models.QuestionApproved {
+			c.JSON(http.StatusConflict, gin.H{"error": errQuestionHidden.Error()})
+			return
+		}
+		_, err := deliveredOrderItem(db, userID, question.ProductID)
+		if errors.Is(err, errReviewNotPurchased) {
+			c.JSON(http.StatusForbidden, gin.H{"error": errAnswerNotPurchased.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post answer"})
+			return
+		}
+		answer.VerifiedBuyer = true
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&answer).Error; err != nil {
+			return err
+		}
+		if answer.Status != models.QuestionApproved {
+			return nil
+		}
+		if question.Status == models.QuestionPending {
+			if err := tx.Model(&question).Updates(map[string]interface{}{
+				"status":       models.QuestionApproved,
+				"moderated_by": userID,
+				"moderated_at": time.Now(),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return notifyAnswered(tx, &question, &answer)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to post answer"})
+		return
+	}
+	db.Preload("User").First(&answer, "id = ?", answer.ID)
+
+	message := "Answer posted"
+	if answer.Status == models.QuestionPending {
+		message = "Answer submitted and waiting for moderation"
+	}
+	c.JSON(http.StatusCreated, gin.H{
+		"message": message,
+		"answer":  answer,
+	})
+}
+
+// GetQuestionQueue godoc
+// @Summary Question moderation queue (Admin only)
+// @Description List questions oldest first with all their answers, pending ones by default
+// @Tags Admin - Q&A
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Moderation status, default pending" Enums(pending, approved, hidden)
+// @Param product_id query string false "Only questions about this product"
+// @Success 200 {object} map[string]interface{} "Questions"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/questions [get]
+func GetQuestionQueue(c *gin.Context) {
+	query := config.GetDB().Preload("User").Preload("Product", withArchived).
+		Preload("Answers", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC") }).Preload("Answers.User").
+		Where("status = ?", c.DefaultQuery("status", models.QuestionPending))
+	if productID := c.Query("product_id"); productID != "" {
+		if _, err := uuid.Parse(productID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "product_id must be a UUID"})
+			return
+		}
+		query = query.Where("product_id = ?", productID)
+	}
+
+	var questions []models.ProductQuestion
+	if err := query.Order("created_at ASC").Find(&questions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch questions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"questions": questions})
+}
+
+// GetAnswerQueue godoc
+// @Summary Answer moderation queue (Admin only)
+// @Description List customers' answers oldest first with the question they answer, pending ones by default
+// @Tags Admin - Q&A
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Moderation status, default pending" Enums(pending, approved, hidden)
+// @Success 200 {object} map[string]interface{} "Answers"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/answers [get]
+func GetAnswerQueue(c *gin.Context) {
+	var answers []models.ProductAnswer
+	if err := config.GetDB().Preload("User").
+		Where("status = ? AND from_store = ?", c.DefaultQuery("status", models.QuestionPending), false).
+		Order("created_at ASC").Find(&answers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch answers"})
+		return
+	}
+
+	questionIDs := make([]uuid.UUID, len(answers))
+	for i, answer := range answers {
+		questionIDs[i] = answer.QuestionID
+	}
+	var questions []models.ProductQuestion
+	if err := config.GetDB().Preload("User").Preload("Product", withArchived).
+		Preload("Answers", approvedAnswers).Preload("Answers.User").
+		Where("id IN ?", questionIDs).Find(&questions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch answers"})
+		return
+	}
+	byID := make(map[uuid.UUID]models.ProductQuestion, len(questions))
+	for _, question := range questions {
+		byID[question.ID] = question
+	}
+	type queuedAnswer struct {
+		models.ProductAnswer
+		Question models.ProductQuestion `json:"question"`
+	}
+	queue := make([]queuedAnswer, len(answers))
+	for i, answer := range answers {
+		queue[i] = queuedAnswer{answer, byID[answer.QuestionID]}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"answers": queue})
+}
+
+// ModerateQuestion godoc
+// @Summary Moderate a question (Admin only)
+// @Description Approve a question to show it on the product page, hide it, or send it back to pending
+// @Tags Admin - Q&A
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Question ID"
+// @Param request body ModerateQuestionRequest true "New status"
+// @Success 200 {object} map[string]interface{} "Question moderated"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Question not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/questions/{id}/status [put]
+func ModerateQuestion(c *gin.Context) {
+	var req ModerateQuestionRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		respondInvalid(c, err)
+		return
+	}
+
+	db := config.GetDB()
+	var question models.ProductQuestion
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+	if err := db.Where("id = ?", c.Param("id")).First(&question).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Question not found"})
+		return
+	}
+
+	moderatorID, _ := uuid.Parse(c.GetString("user_id"))
+	if err := db.Model(&question).Updates(map[string]interface{}{
+		"status":       req.Status,
+		"moderated_by": moderatorID,
+		"moderated_at": time.Now(),
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate question"})
+		return
+	}
+	db.Preload("User").Preload("Answers.User").First(&question, "id = ?", question.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Question " + req.Status,
+		"question": question,
+	})
+}
+
+// ModerateAnswer godoc
+// @Summary Moderate an answer (Admin only)
+// @Description Approve a customer's answer to publish it, hide it, or send it back to pending. The asker is notified when a pending answer is approved.
+// @Tags Admin - Q&A
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Answer ID"
+// @Param request body ModerateQuestionRequest true "New status"
+// @Success 200 {object} map[string]interface{} "Answer moderated"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Answer not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/answers/{id}/status [put]
+func ModerateAnswer(c *gin.Context) {
+	var req ModerateQuestionRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		respondInvalid(c, err)
+		return
+	}
+
+	db := config.GetDB()
+	var answer models.ProductAnswer
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Answer not found"})
+		return
+	}
+	if err := db.Where("id = ?", c.Param("id")).First(&answer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Answer not found"})
+		return
+	}
+
+	moderatorID, _ := uuid.Parse(c.GetString("user_id"))
+	publish := answer.Status == models.QuestionPending && req.Status == models.QuestionApproved
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&answer).Updates(map[string]interface{}{
+			"status":       req.Status,
+			"moderated_by": moderatorID,
+			"moderated_at": time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		if !publish {
+			return nil
+		}
+		var question models.ProductQuestion
+		if err := tx.First(&question, "id = ?", answer.QuestionID).Error; err != nil {
+			return err
+		}
+		return notifyAnswered(tx, &question, &answer)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate answer"})
+		return
+	}
+	db.Preload("User").First(&answer, "id = ?", answer.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Answer " + req.Status,
+		"answer":  answer,
+	})
+}
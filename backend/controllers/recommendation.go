@@ -0,0 +1,322 @@
+package controllers
+
+import (
+	"log"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// recommendationLimit is how many products each product page list shows
+const recommendationLimit = 6
+
+// recommendationSettings holds the recommendation job's rules, each overridable through the environment
+type recommendationSettings struct {
+	LookbackDays int           // RECOMMENDATION_LOOKBACK_DAYS: how far back orders count
+	MinCount     int           // RECOMMENDATION_MIN_COUNT: orders or customers a pairing needs to be kept
+	MaxPerKind   int           // RECOMMENDATION_MAX_PAIRINGS: pairings kept per product and kind
+	JobPeriod    time.Duration // RECOMMENDATION_JOB_HOURS: how often pairings are rebuilt
+}
+
+func loadRecommendationSettings() recommendationSettings {
+	return recommendationSettings{
+		LookbackDays: max(envInt("RECOMMENDATION_LOOKBACK_DAYS", 365), 1),
+		MinCount:     max(envInt("RECOMMENDATION_MIN_COUNT", 2), 1),
+		MaxPerKind:   max(envInt("RECOMMENDATION_MAX_PAIRINGS", 20), recommendationLimit),
+		JobPeriod:    time.Duration(max(envInt("RECOMMENDATION_JOB_HOURS", 6), 1)) * time.Hour,
+	}
+}
+
+// recommendationRunSummary counts what one recommendation job run built
+type recommendationRunSummary struct {
+	Products       int       `json:"products"`
+	BoughtTogether int       `json:"bought_together"`
+	AlsoBought     int       `json:"also_bought"`
+	ComputedAt     time.Time `json:"computed_at"`
+}
+
+// ProductRecommendations are the suggestion lists shown on a product page
+type ProductRecommendations struct {
+	BoughtTogether []models.Product `json:"frequently_bought_together"`
+	AlsoBought     []models.Product `json:"customers_also_bought"`
+	Related        []models.Product `json:"related"` // same brand or category
+}
+
+// StartRecommendationJob rebuilds product pairings from order history every
+// RECOMMENDATION_JOB_HOURS until the process exits. Run it in its own goroutine.
+func StartRecommendationJob(db *gorm.DB) {
+	ticker := time.NewTicker(loadRecommendationSettings().JobPeriod)
+	defer ticker.Stop()
+	for {
+		summary, err := ComputeProductPairings(db, time.Now())
+		if err != nil {
+			log.Println("Recommendation job:", err)
+		} else {
+			log.Printf("Recommendation job: %d products, %d bought together, %d also bought",
+				summary.Products, summary.BoughtTogether, summary.AlsoBought)
+		}
+		<-ticker.C
+	}
+}
+
+// pairingCount is one row of a co-occurrence count
+type pairingCount struct {
+	ProductID uuid.UUID
+	RelatedID uuid.UUID
+	Count     int
+}
+
+// ComputeProductPairings counts, over orders that were not cancelled or
+// refunded, which products were bought in the same order and which by the
+// same customer, then replaces the stored pairings with the result
+func ComputeProductPairings(db *gorm.DB, now time.Time) (recommendationRunSummary, error) {
+	settings := loadRecommendationSettings()
+	summary := recommendationRunSummary{ComputedAt: now}
+	since := now.AddDate(0, 0, -settings.LookbackDays)
+	skipped := []string{"cancelled", "refunded"}
+
+	// How many orders and customers bought each product, to weigh the pairs by
+	var totals []struct {
+		ProductID uuid.UUID
+		Orders    int
+		Customers int
+	}
+	if err := db.Table("order_items").
+		Select("order_items.product_id, COUNT(DISTINCT orders.id) AS orders, COUNT(DISTINCT orders.user_id) AS customers").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.status NOT IN ? AND orders.created_at >= ?", skipped, since).
+		Group("order_items.product_id").Scan(&totals).Error; err != nil {
+		return summary, err
+	}
+	orders := make(map[uuid.UUID]int, len(totals))
+	customers := make(map[uuid.UUID]int, len(totals))
+	for _, total := range totals {
+		orders[total.ProductID] = total.Orders
+		customers[total.ProductID] = total.Customers
+	}
+
+	var together []pairingCount
+	if err := db.Table("order_items AS a").
+		Select("a.product_id, b.product_id AS related_id, COUNT(DISTINCT a.order_id) AS count").
+		Joins("JOIN order_items AS b ON b.order_id = a.order_id AND b.product_id <> a.product_id").
+		Joins("JOIN orders ON orders.id = a.order_id").
+		Where("orders.status NOT IN ? AND orders.created_at >= ?", skipped, since).
+		Group("a.product_id, b.product_id").
+		Having("COUNT(DISTINCT a.order_id) >= ?", settings.MinCount).Scan(&together).Error; err != nil {
+		return summary, err
+	}
+
+	var alsoBought []pairingCount
+	if err := db.Table("order_items AS a").
+		Select("a.product_id, b.product_id AS related_id, COUNT(DISTINCT oa.user_id) AS count").
+		Joins("JOIN orders AS oa ON oa.id = a.order_id").
+		Joins("JOIN orders AS ob ON ob.user_id = oa.user_id").
+		Joins("JOIN order_items AS b ON b.order_id = ob.id AND b.product_id <> a.product_id").
+		Where("oa.status NOT IN ? AND oa.created_at >= ?", skipped, since).
+		Where("ob.status NOT IN ? AND ob.created_at >= ?", skipped, since).
+		Group("a.product_id, b.product_id").
+		Having("COUNT(DISTINCT oa.user_id) >= ?", settings.MinCount).Scan(&alsoBought).Error; err != nil {
+		return summary, err
+	}
+
+	pairings := topPairings(together, models.PairingBoughtTogether, orders, settings.MaxPerKind, now)
+	summary.BoughtTogether = len(pairings)
+	also := topPairings(alsoBought, models.PairingAlsoBought, customers, settings.MaxPerKind, now)
+	summary.AlsoBought = len(also)
+	pairings = append(pairings, also...)
+
+	paired := make(map[uuid.UUID]bool)
+	for _, pairing := range pairings {
+		paired[pairing.ProductID] = true
+	}
+	summary.Products = len(paired)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.ProductPairing{}).Error; err != nil {
+			return err
+		}
+		if len(pairings) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(pairings, 500).Error
+	})
+	return summary, err
+}
+
+// topPairings keeps each product's strongest pairs, weighing each count by
+// how often the product itself was bought
+func topPairings(counts []pairingCount, kind string, totals map[uuid.UUID]int, limit int, now time.Time) []models.ProductPairing {
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].ProductID != counts[j].ProductID {
+			return counts[i].ProductID.String() < counts[j].ProductID.String()
+		}
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].RelatedID.String() < counts[j].RelatedID.String()
+	})
+
+	var pairings []models.ProductPairing
+	kept := 0
+	for i, count := range counts {
+		if i == 0 || count.ProductID != counts[i-1].ProductID {
+			kept = 0
+		}
+		if kept == limit {
+			continue
+		}
+		kept++
+		confidence := 0.0
+		if total := totals[count.ProductID]; total > 0 {
+			confidence = float64(count.Count) / float64(total)
+		}
+		pairings = append(pairings, models.ProductPairing{
+			ProductID:  count.ProductID,
+			Kind:       kind,
+			RelatedID:  count.RelatedID,
+			Count:      count.Count,
+			Confidence: confidence,
+			ComputedAt: now,
+		})
+	}
+	return pairings
+}
+
+// bestSelling orders products by units sold, then rating
+func bestSelling(db *gorm.DB) *gorm.DB {
+	sold := db.Session(&gorm.Session{NewDB: true}).Table("order_items").
+		Select("order_items.product_id, SUM(order_items.quantity) AS sold").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.status NOT IN ?", []string{"cancelled", "refunded"}).
+		Group("order_items.product_id")
+	return db.Joins("LEFT JOIN (?) AS sales ON sales.product_id = products.id", sold).
+		Order("COALESCE(sales.sold, 0) DESC").Order("products.rating_average DESC").Order("products.id")
+}
+
+// recommender fills a product page's lists, never showing a product twice
+type recommender struct {
+	db   *gorm.DB
+	seen []uuid.UUID
+}
+
+// fill tops up list to recommendationLimit with listed, in stock products
+// from query, in its order
+func (r *recommender) fill(list *[]models.Product, query func(*gorm.DB) *gorm.DB) error {
+	if len(*list) >= recommendationLimit {
+		return nil
+	}
+	var products []models.Product
+	if err := query(r.db.Model(&models.Product{}).Select("products.*")).Scopes(listedProducts).
+		Where("products.stock > 0 AND products.id NOT IN ?", r.seen).
+		Limit(recommendationLimit - len(*list)).Find(&products).Error; err != nil {
+		return err
+	}
+	for _, product := range products {
+		r.seen = append(r.seen, product.ID)
+	}
+	*list = append(*list, products...)
+	return nil
+}
+
+// paired lists the products stored as paired with productID, strongest first
+func paired(productID uuid.UUID, kind string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Joins("JOIN product_pairings ON product_pairings.related_id = products.id").
+			Where("product_pairings.product_id = ? AND product_pairings.kind = ?", productID, kind).
+			Order("product_pairings.count DESC").Order("product_pairings.confidence DESC").Order("products.id")
+	}
+}
+
+// productRecommendations builds the product page's lists from the stored
+// pairings. Products without enough order history fall back to best sellers:
+// for the same species for bought together, in the same category for also
+// bought. Related products share the brand and category, then either one.
+// Prescription diets come up only through pairings or next to one another.
+func productRecommendations(db *gorm.DB, product *models.Product) (ProductRecommendations, error) {
+	recs := ProductRecommendations{
+		BoughtTogether: []models.Product{},
+		AlsoBought:     []models.Product{},
+		Related:        []models.Product{},
+	}
+	r := recommender{db: db, seen: []uuid.UUID{product.ID}}
+	// Veterinary diets are only suggested without order history next to other veterinary diets
+	prescribable := func(db *gorm.DB) *gorm.DB {
+		if product.Prescription {
+			return db
+		}
+		return db.Where("products.prescription = ?", false)
+	}
+	fallback := func(where func(*gorm.DB) *gorm.DB) func(*gorm.DB) *gorm.DB {
+		return func(db *gorm.DB) *gorm.DB { return bestSelling(where(prescribable(db))) }
+	}
+	sameSpecies := func(db *gorm.DB) *gorm.DB {
+		if product.Species == "" {
+			return db
+		}
+		return db.Where("products.species = ?", product.Species)
+	}
+	sameCategory := func(db *gorm.DB) *gorm.DB { return db.Where("products.category_id = ?", product.CategoryID) }
+	anywhere := func(db *gorm.DB) *gorm.DB { return db }
+	sameBrand := func(db *gorm.DB) *gorm.DB {
+		return db.Where("products.brand = ? AND products.brand <> ''", product.Brand)
+	}
+	byRating := func(where func(*gorm.DB) *gorm.DB) func(*gorm.DB) *gorm.DB {
+		return func(db *gorm.DB) *gorm.DB {
+			return where(prescribable(db)).Order("products.rating_average DESC").Order("products.rating_count DESC").Order("products.id")
+		}
+	}
+
+	steps := []struct {
+		list  *[]models.Product
+		query func(*gorm.DB) *gorm.DB
+	}{
+		{&recs.BoughtTogether, paired(product.ID, models.PairingBoughtTogether)},
+		{&recs.AlsoBought, paired(product.ID, models.PairingAlsoBought)},
+		{&recs.BoughtTogether, fallback(sameSpecies)},
+		{&recs.AlsoBought, fallback(sameCategory)},
+		{&recs.AlsoBought, fallback(anywhere)},
+		{&recs.Related, byRating(func(db *gorm.DB) *gorm.DB { return sameBrand(sameCategory(db)) })},
+		{&recs.Related, byRating(sameCategory)},
+		{&recs.Related, byRating(sameBrand)},
+	}
+	for _, step := range steps {
+		if err := r.fill(step.list, step.query); err != nil {
+			return recs, err
+		}
+	}
+
+	for _, list := range [][]models.Product{recs.BoughtTogether, recs.AlsoBought, recs.Related} {
+		if err := applySalePricesToList(db, list); err != nil {
+			return recs, err
+		}
+	}
+	return recs, nil
+}
+
+// RunRecommendations godoc
+// @Summary Rebuild product recommendations now (Admin only)
+// @Description Recount which products are bought together and by the same customers without waiting for the next job run
+// @Tags Admin - Products
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Run summary"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/recommendations/run [post]
+func RunRecommendations(c *gin.Context) {
+	summary, err := ComputeProductPairings(config.GetDB(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute recommendations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"summary": summary})
+}
@@ -0,0 +1,532 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"os"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errReferralNotFound   = errors.New("Referral code not found")
+	errSelfReferral       = errors.New("You cannot use your own referral code")
+	errAlreadyReferred    = errors.New("A referral code has already been applied to this account")
+	errReferralTooLate    = errors.New("Referral codes can only be applied before your first order")
+	errReferralUsed       = errors.New("Referral discount has already been used")
+	errReferralNotPending = errors.New("Referral is not awaiting review")
+)
+
+// referralSettings holds the two-sided referral rewards. Each value can be
+// overridden through the environment; money values are in baht.
+type referralSettings struct {
+	RefereeDiscount models.Money // REFERRAL_REFEREE_DISCOUNT: off the referee's first order
+	RewardType      string       // REFERRAL_REWARD_TYPE: "credit" or "points"
+	RewardCredit    models.Money // REFERRAL_REWARD_CREDIT: store credit for the referrer
+	RewardPoints    int          // REFERRAL_REWARD_POINTS: loyalty points for the referrer
+}
+
+func loadReferralSettings() referralSettings {
+	rewardType := os.Getenv("REFERRAL_REWARD_TYPE")
+	if rewardType != "points" {
+		rewardType = "credit"
+	}
+	return referralSettings{
+		RefereeDiscount: envMoney("REFERRAL_REFEREE_DISCOUNT", 10000),
+		RewardType:      rewardType,
+		RewardCredit:    envMoney("REFERRAL_REWARD_CREDIT", 10000),
+		RewardPoints:    envInt("REFERRAL_REWARD_POINTS", 400),
+	}
+}
+
+// ApplyReferralRequest represents the request body for applying a referral code after registration
+type ApplyReferralRequest struct {
+	Code string `json:"code" binding:"required" example:"K7QX2MPA"`
+}
+
+// normalizePhone keeps only the digits of a phone number
+func normalizePhone(phone string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+}
+
+// normalizeAddress lowercases an address and collapses its whitespace
+func normalizeAddress(address string) string {
+	return strings.Join(strings.Fields(strings.ToLower(address)), " ")
+}
+
+// referralFlags lists the contact details a referee shares with the referrer
+func referralFlags(referrer, referee *models.User, shippingAddress string) []string {
+	var flags []string
+	if phone := normalizePhone(referrer.Phone); phone != "" && phone == normalizePhone(referee.Phone) {
+		flags = append(flags, "same phone as referrer")
+	}
+	address := normalizeAddress(referrer.Address)
+	if address != "" && address == normalizeAddress(referee.Address) {
+		flags = append(flags, "same address as referrer")
+	}
+	if address != "" && address == normalizeAddress(shippingAddress) {
+		flags = append(flags, "first order ships to referrer's address")
+	}
+	return flags
+}
+
+// flagReferral marks a referral for review, keeping earlier reasons
+func flagReferral(referral *models.Referral, flags []string) {
+	if len(flags) == 0 {
+		return
+	}
+	referral.Flagged = true
+	if referral.FlagReason != "" {
+		flags = append([]string{referral.FlagReason}, flags...)
+	}
+	referral.FlagReason = strings.Join(flags, "; ")
+}
+
+// attachReferral links a customer to the owner of a referral code. Referrals that
+// share a phone or address with the referrer are accepted but flagged for review.
+func attachReferral(tx *gorm.DB, referee *models.User, code string) (*models.Referral, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+
+	var referrer models.User
+	if err := tx.Where("referral_code = ?", code).First(&referrer).Error; err != nil {
+		return nil, errReferralNotFound
+	}
+	if referrer.ID == referee.ID {
+		return nil, errSelfReferral
+	}
+	if referee.ReferredByID != nil {
+		return nil, errAlreadyReferred
+	}
+
+	var orders int64
+	if err := tx.Model(&models.Order{}).Where("user_id = ?", referee.ID).Count(&orders).Error; err != nil {
+		return nil, err
+	}
+	if orders > 0 {
+		return nil, errReferralTooLate
+	}
+
+	referral := models.Referral{
+		ReferrerID: referrer.ID,
+		RefereeID:  referee.ID,
+		Code:       code,
+	}
+	flagReferral(&referral, referralFlags(&referrer, referee, ""))
+	if err := tx.Create(&referral).Error; err != nil {
+		return nil, err
+	}
+
+	referee.ReferredByID = &referrer.ID
+	if err := tx.Model(referee).Update("referred_by_id", referrer.ID).Error; err != nil {
+		return nil, err
+	}
+	return &referral, nil
+}
+
+// referralDiscount returns the referee's open referral and first-order discount,
+// or nil when the user was not referred or has already placed a first order
+func referralDiscount(db *gorm.DB, userID uuid.UUID) (*models.Referral, models.Money, error) {
+	var referral models.Referral
+	err := db.Where("referee_id = ? AND status <> ? AND first_order_id IS NULL", userID, models.ReferralRejected).
+		First(&referral).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var orders int64
+	if err := db.Model(&models.Order{}).
+		Where("user_id = ? AND status NOT IN ?", userID, []string{"cancelled", "refunded"}).
+		Count(&orders).Error; err != nil {
+		return nil, 0, err
+	}
+	if orders > 0 {
+		return nil, 0, nil
+	}
+
+	return &referral, loadReferralSettings().RefereeDiscount, nil
+}
+
+// claimReferralDiscount ties the referral to the order that used its discount. The
+// first_order_id guard stops two concurrent checkouts both taking the discount.
+func claimReferralDiscount(tx *gorm.DB, referral *models.Referral, orderID uuid.UUID) error {
+	result := tx.Model(&models.Referral{}).
+		Where("id = ? AND first_order_id IS NULL", referral.ID).
+		Update("first_order_id", orderID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errReferralUsed
+	}
+	return nil
+}
+
+// releaseReferral frees the first-order discount again when that order is cancelled
+// before the referral converted
+func releaseReferral(tx *gorm.DB, order *models.Order) error {
+	return tx.Model(&models.Referral{}).
+		Where("first_order_id = ? AND status = ?", order.ID, models.ReferralPending).
+		Update("first_order_id", nil).Error
+}
+
+// convertReferral rewards the referrer once the referee's first order is delivered;
+// flagged referrals wait for an admin instead
+func convertReferral(tx *gorm.DB, order *models.Order, actorID *uuid.UUID) error {
+	var referral models.Referral
+	err := tx.Preload("Referrer").Preload("Referee").
+		Where("first_order_id = ? AND status = ?", order.ID, models.ReferralPending).
+		First(&referral).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if address := normalizeAddress(referral.Referrer.Address); address != "" && address == normalizeAddress(order.ShippingAddress) {
+		flagReferral(&referral, []string{"first order ships to referrer's address"})
+	}
+	if referral.Flagged {
+		return tx.Model(&referral).Updates(map[string]interface{}{
+			"status":      models.ReferralReview,
+			"flagged":     true,
+			"flag_reason": referral.FlagReason,
+		}).Error
+	}
+
+	return rewardReferrer(tx, &referral, actorID)
+}
+
+// rewardReferrer issues the referrer's store credit or points and closes the referral
+func rewardReferrer(tx *gorm.DB, referral *models.Referral, actorID *uuid.UUID) error {
+	settings := loadReferralSettings()
+	reason := "Referral reward for " + referral.Referee.Name
+
+	if settings.RewardType == "points" {
+		if settings.RewardPoints > 0 {
+			if err := creditPoints(tx, &models.LoyaltyEntry{
+				UserID:  referral.ReferrerID,
+				Type:    models.LoyaltyEntryReferral,
+				Points:  settings.RewardPoints,
+				ActorID: actorID,
+				Reason:  reason,
+			}); err != nil {
+				return err
+			}
+		}
+		referral.RewardPoints = settings.RewardPoints
+	} else {
+		if settings.RewardCredit > 0 {
+			if err := creditWallet(tx, &models.WalletEntry{
+				UserID:  referral.ReferrerID,
+				Amount:  settings.RewardCredit,
+				Reason:  reason,
+				ActorID: actorID,
+			}); err != nil {
+				return err
+			}
+		}
+		referral.RewardCredit = settings.RewardCredit
+	}
+
+	now := time.Now()
+	referral.Status = models.ReferralRewarded
+	referral.RewardedAt = &now
+	return tx.Model(referral).Updates(map[string]interface{}{
+		"status":        referral.Status,
+		"reward_credit": referral.RewardCredit,
+		"reward_points": referral.RewardPoints,
+		"rewarded_at":   now,
+	}).Error
+}
+
+// GetReferrals godoc
+// @Summary Get my referrals
+// @Description Get the authenticated user's referral code, the customers who joined with it and the rewards earned
+// @Tags Referrals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Referral code and referrals"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /referrals [get]
+func GetReferrals(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var user models.User
+	if err := config.GetDB().Where("id = ?", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	// Accounts from before referral codes get theirs on first visit
+	if user.ReferralCode == "" {
+		if err := config.GetDB().Save(&user).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create referral code"})
+			return
+		}
+	}
+
+	var referrals []models.Referral
+	if err := config.GetDB().Preload("Referee").Where("referrer_id = ?", user.ID).
+		Order("created_at DESC").Find(&referrals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referrals"})
+		return
+	}
+
+	// Referees only see each other by first name
+	rows := make([]gin.H, 0, len(referrals))
+	var earnedCredit models.Money
+	var earnedPoints int
+	for _, referral := range referrals {
+		name := strings.Fields(referral.Referee.Name)
+		firstName := ""
+		if len(name) > 0 {
+			firstName = name[0]
+		}
+		status := referral.Status
+		if status == models.ReferralReview {
+			status = models.ReferralPending
+		}
+		rows = append(rows, gin.H{
+			"referee_name":  firstName,
+			"status":        status,
+			"joined_at":     referral.CreatedAt,
+			"reward_credit": referral.RewardCredit,
+			"reward_points": referral.RewardPoints,
+			"rewarded_at":   referral.RewardedAt,
+		})
+		earnedCredit += referral.RewardCredit
+		earnedPoints += referral.RewardPoints
+	}
+
+	settings := loadReferralSettings()
+	c.JSON(http.StatusOK, gin.H{
+		"referral_code":    user.ReferralCode,
+		"referee_discount": settings.RefereeDiscount,
+		"reward_type":      settings.RewardType,
+		"reward_credit":    settings.RewardCredit,
+		"reward_points":    settings.RewardPoints,
+		"referrals":        rows,
+		"earned_credit":    earnedCredit,
+		"earned_points":    earnedPoints,
+	})
+}
+
+// ApplyReferralCode godoc
+// @Summary Apply a referral code
+// @Description Apply a friend's referral code to an account that has not ordered yet, unlocking the first-order discount
+// @Tags Referrals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ApplyReferralRequest true "Referral code"
+// @Success 201 {object} map[string]interface{} "Referral code applied"
+// @Failure 400 {object} map[string]interface{} "Invalid, own or late referral code"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /referrals/apply [post]
+func ApplyReferralCode(c *gin.Context) {
+	var req ApplyReferralRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var user models.User
+	if err := config.GetDB().Where("id = ?", c.GetString("user_id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		_, err := attachReferral(tx, &user, req.Code)
+		return err
+	})
+	switch {
+	case errors.Is(err, errReferralNotFound), errors.Is(err, errSelfReferral),
+		errors.Is(err, errAlreadyReferred), errors.Is(err, errReferralTooLate):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to apply referral code"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":          "Referral code applied successfully",
+		"referee_discount": loadReferralSettings().RefereeDiscount,
+	})
+}
+
+// GetReferralReport godoc
+// @Summary Referral conversion report (Admin only)
+// @Description List referrals with referrer and referee, plus conversion totals. Filter by status or flagged.
+// @Tags Admin - Referrals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Referral status" Enums(pending, review, rewarded, rejected)
+// @Param flagged query bool false "Only flagged referrals"
+// @Success 200 {object} map[string]interface{} "Referrals and conversion summary"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/referrals [get]
+func GetReferralReport(c *gin.Context) {
+	db := config.GetDB()
+
+	var all []models.Referral
+	if err := db.Find(&all).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referrals"})
+		return
+	}
+
+	summary := gin.H{}
+	var ordered, rewarded, flagged, rejected, review int
+	var creditIssued models.Money
+	var pointsIssued int
+	for _, referral := range all {
+		if referral.FirstOrderID != nil {
+			ordered++
+		}
+		if referral.Flagged {
+			flagged++
+		}
+		switch referral.Status {
+		case models.ReferralRewarded:
+			rewarded++
+		case models.ReferralRejected:
+			rejected++
+		case models.ReferralReview:
+			review++
+		}
+		creditIssued += referral.RewardCredit
+		pointsIssued += referral.RewardPoints
+	}
+	conversionRate := 0.0
+	if len(all) > 0 {
+		conversionRate = float64(rewarded) / float64(len(all))
+	}
+	summary["signups"] = len(all)
+	summary["first_orders"] = ordered
+	summary["rewarded"] = rewarded
+	summary["awaiting_review"] = review
+	summary["rejected"] = rejected
+	summary["flagged"] = flagged
+	summary["conversion_rate"] = conversionRate
+	summary["credit_issued"] = creditIssued
+	summary["points_issued"] = pointsIssued
+
+	query := db.Preload("Referrer").Preload("Referee").Order("created_at DESC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if c.Query("flagged") == "true" {
+		query = query.Where("flagged = ?", true)
+	}
+
+	var referrals []models.Referral
+	if err := query.Find(&referrals).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch referrals"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summary":   summary,
+		"referrals": referrals,
+	})
+}
+
+// ApproveReferral godoc
+// @Summary Approve a flagged referral (Admin only)
+// @Description Issue the referrer reward for a flagged referral whose first order was delivered
+// @Tags Admin - Referrals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Referral ID"
+// @Success 200 {object} map[string]interface{} "Referral approved"
+// @Failure 400 {object} map[string]interface{} "Referral is not awaiting review"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Referral not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/referrals/{id}/approve [post]
+func ApproveReferral(c *gin.Context) {
+	var referral models.Referral
+	if err := config.GetDB().Preload("Referee").Where("id = ?", c.Param("id")).First(&referral).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Referral not found"})
+		return
+	}
+	if referral.Status != models.ReferralReview {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errReferralNotPending.Error()})
+		return
+	}
+
+	adminID, _ := uuid.Parse(c.GetString("user_id"))
+	if err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		return rewardReferrer(tx, &referral, &adminID)
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to approve referral"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Referral approved and reward issued",
+		"referral": referral,
+	})
+}
+
+// RejectReferral godoc
+// @Summary Reject a referral (Admin only)
+// @Description Reject a pending or flagged referral. The referee loses an unused first-order discount and no reward is issued.
+// @Tags Admin - Referrals
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Referral ID"
+// @Success 200 {object} map[string]interface{} "Referral rejected"
+// @Failure 400 {object} map[string]interface{} "Referral already rewarded or rejected"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Referral not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/referrals/{id}/reject [post]
+func RejectReferral(c *gin.Context) {
+	var referral models.Referral
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&referral).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Referral not found"})
+		return
+	}
+	if referral.Status != models.ReferralPending && referral.Status != models.ReferralReview {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Referral has already been " + referral.Status})
+		return
+	}
+
+	referral.Status = models.ReferralRejected
+	if err := config.GetDB().Model(&referral).Update("status", referral.Status).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reject referral"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Referral rejected",
+		"referral": referral,
+	})
+}
@@ -0,0 +1,623 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxReviewPhotos       = 5
+	defaultReviewPageSize = 10
+	maxReviewPageSize     = 50
+)
+
+// Review sort orders, with the ID as a tie-breaker so pages are stable
+var reviewSortOrders = map[string]string{
+	"newest":      "reviews.created_at DESC, reviews.id",
+	"helpful":     "reviews.helpful_count DESC, reviews.created_at DESC, reviews.id",
+	"rating_high": "reviews.rating DESC, reviews.created_at DESC, reviews.id",
+	"rating_low":  "reviews.rating ASC, reviews.created_at DESC, reviews.id",
+}
+
+var (
+	errReviewNotPurchased = errors.New("Only customers who received this product can review it")
+	errReviewExists       = errors.New("You have already reviewed this product")
+	errReviewOwnVote      = errors.New("You cannot vote on your own review")
+)
+
+// ReviewInput represents the fields of a new review, sent as JSON or, with
+// photos, as a multipart form
+type ReviewInput struct {
+	Rating int    `json:"rating" form:"rating" binding:"required,min=1,max=5" example:"5"`
+	Title  string `json:"title" form:"title" binding:"max=120" example:"My picky cat loves it"`
+	Body   string `json:"body" form:"body" binding:"max=5000" example:"Switched from another brand and she finished the bowl on day one."`
+}
+
+// ModerateReviewRequest represents the request body for moderating a review
+type ModerateReviewRequest struct {
+	Status string `json:"status" binding:"required,oneof=pending approved hidden" example:"approved"`
+}
+
+// ReviewReplyRequest represents the request body for the store's reply to a review
+type ReviewReplyRequest struct {
+	Reply string `json:"reply" binding:"max=2000" example:"Thank you! We're glad she likes it."` // empty removes the reply
+}
+
+// ReviewSummary is the rating breakdown shown above a product's reviews
+type ReviewSummary struct {
+	Average float64     `json:"average"`
+	Count   int         `json:"count"`
+	Stars   map[int]int `json:"stars"` // number of reviews per rating, 1 to 5
+}
+
+// deliveredOrderItem finds the user's delivered purchase of a product, which
+// is what allows them to review it
+func deliveredOrderItem(db *gorm.DB, userID, productID uuid.UUID) (*models.OrderItem, error) {
+	var item models.OrderItem
+	err := db.Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.product_id = ?", userID, "delivered", productID).
+		Order("orders.delivered_at DESC").First(&item).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, errReviewNotPurchased
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// updateProductRating recomputes a product's average rating and count from its
+// approved reviews; call it whenever a review enters or leaves that status
+func updateProductRating(tx *gorm.DB, productID uuid.UUID) error {
+	var result struct {
+		Average float64
+		Count   int
+	}
+	if err := tx.Model(&models.Review{}).Select("COALESCE(AVG(rating), 0) AS average, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, models.ReviewApproved).Scan(&result).Error; err != nil {
+		return err
+	}
+	return tx.Unscoped().Model(&models.Product{}).Where("id = ?", productID).UpdateColumns(map[string]interface{}{
+		"rating_average": math.Round(result.Average*100) / 100,
+		"rating_count":   result.Count,
+	}).Error
+}
+
+// reviewSummary counts a product's approved reviews by rating
+func reviewSummary(db *gorm.DB, productID uuid.UUID) (ReviewSummary, error) {
+	summary := ReviewSummary{Stars: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}}
+	var rows []struct {
+		Rating int
+		Count  int
+	}
+	if err := db.Model(&models.Review{}).Select("rating, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, models.ReviewApproved).
+		Group("rating").Scan(&rows).Error; err != nil {
+		return summary, err
+	}
+	total := 0
+	for _, row := range rows {
+		summary.Stars[row.Rating] = row.Count
+		summary.Count += row.Count
+		total += row.Rating * row.Count
+	}
+	if summary.Count > 0 {
+		summary.Average = math.Round(float64(total)/float64(summary.Count)*100) / 100
+	}
+	return summary, nil
+}
+
+// GetProductReviews godoc
+// @Summary Get a product's reviews
+// @Description List the approved reviews of a product with a rating summary. Reviewers are shown by first name and last initial.
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Param id path string true "Product ID"
+// @Param rating query int false "Only reviews with this many stars" minimum(1) maximum(5)
+// @Param sort query string false "Sort order, default newest" Enums(newest, helpful, rating_high, rating_low)
+// @Param page query int false "Page number" default(1) minimum(1)
+// @Param page_size query int false "Number of reviews per page" default(10) minimum(1) maximum(50)
+// @Success 200 {object} map[string]interface{} "Reviews with pagination info and summary"
+// @Failure 400 {object} map[string]interface{} "Invalid query parameter"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /products/{id}/reviews [get]
+func GetProductReviews(c *gin.Context) {
+	db := config.GetDB()
+
+	var product models.Product
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if err := db.Scopes(purchasableProducts).Where("id = ?", c.Param("id")).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	page, pageSize := 1, defaultReviewPageSize
+	if raw := c.Query("page"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "page must be a positive integer"})
+			return
+		}
+		page = n
+	}
+	if raw := c.Query("page_size"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxReviewPageSize {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("page_size must be between 1 and %d", maxReviewPageSize)})
+			return
+		}
+		pageSize = n
+	}
+	sort := c.DefaultQuery("sort", "newest")
+	order, ok := reviewSortOrders[sort]
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of newest, helpful, rating_high, rating_low"})
+		return
+	}
+
+	query := db.Model(&models.Review{}).Where("product_id = ? AND status = ?", product.ID, models.ReviewApproved)
+	if raw := c.Query("rating"); raw != "" {
+		rating, err := strconv.Atoi(raw)
+		if err != nil || rating < 1 || rating > 5 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "rating must be between 1 and 5"})
+			return
+		}
+		query = query.Where("rating = ?", rating)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	var reviews []models.Review
+	if err := query.Preload("User").Preload("Photos", imagesInOrder).Order(order).
+		Offset((page - 1) * pageSize).Limit(pageSize).Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+	summary, err := reviewSummary(db, product.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"reviews":  reviews,
+		"page":     page,
+		"pageSize": pageSize,
+		"total":    total,
+		"sort":     sort,
+		"summary":  summary,
+	})
+}
+
+// GetMyReviews godoc
+// @Summary Get my reviews
+// @Description List the reviews the current user wrote, with their moderation status
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of reviews"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /reviews [get]
+func GetMyReviews(c *gin.Context) {
+	var reviews []models.Review
+	if err := config.GetDB().Preload("User").Preload("Product", withArchived).Preload("Photos", imagesInOrder).
+		Where("user_id = ?", c.GetString("user_id")).Order("created_at DESC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+}
+
+// CreateReview godoc
+// @Summary Review a product
+// @Description Rate a product 1 to 5 stars with optional text and up to 5 photos. Only customers with a delivered order of the product can review it, once. Reviews are shown after moderation. Send JSON, or a multipart form to attach photos.
+// @Tags Reviews
+// @Accept json,multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Product ID"
+// @Param rating formData int true "Stars, 1 to 5"
+// @Param title formData string false "Headline"
+// @Param body formData string false "Review text"
+// @Param photos formData file false "Photos (JPEG, PNG or WebP, up to 10 MB each)"
+// @Success 201 {object} map[string]interface{} "Review submitted"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Product was not delivered to the user"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 409 {object} map[string]interface{} "Product already reviewed"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /products/{id}/reviews [post]
+func CreateReview(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var product models.Product
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	if err := db.Where("id = ?", c.Param("id")).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	var input ReviewInput
+	if err := c.ShouldBind(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	item, err := deliveredOrderItem(db, userID, product.ID)
+	if errors.Is(err, errReviewNotPurchased) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		return
+	}
+	var existing int64
+	if err := db.Model(&models.Review{}).Where("product_id = ? AND user_id = ?", product.ID, userID).
+		Count(&existing).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		return
+	}
+	if existing > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": errReviewExists.Error()})
+		return
+	}
+
+	review := models.Review{
+		ID:          uuid.New(),
+		ProductID:   product.ID,
+		UserID:      userID,
+		OrderItemID: item.ID,
+		Rating:      input.Rating,
+		Title:       strings.TrimSpace(input.Title),
+		Body:        strings.TrimSpace(input.Body),
+		Status:      models.ReviewPending,
+	}
+
+	var headers []*multipart.FileHeader
+	if form, err := c.MultipartForm(); err == nil {
+		headers = form.File["photos"]
+	}
+	if len(headers) > maxReviewPhotos {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A review can have at most %d photos", maxReviewPhotos)})
+		return
+	}
+
+	ctx := c.Request.Context()
+	stored := make([]models.Image, 0, len(headers))
+	discard := func() {
+		for i := range stored {
+			deleteImageFiles(ctx, &stored[i])
+		}
+	}
+	for i, header := range headers {
+		data, decoded, contentType, err := readImageFile(header)
+		if err != nil {
+			discard()
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("photos[%d]: %v", i, err)})
+			return
+		}
+		img := models.Image{
+			ID:          uuid.New(),
+			OwnerID:     review.ID,
+			OwnerType:   models.ImageOwnerReview,
+			FileName:    filepath.Base(header.Filename),
+			ContentType: contentType,
+			FileSize:    int64(len(data)),
+			Width:       decoded.Bounds().Dx(),
+			Height:      decoded.Bounds().Dy(),
+			Position:    i,
+		}
+		if err := storeImage(ctx, &img, data, decoded); err != nil {
+			log.Printf("Image %s: failed to store: %v", img.ID, err)
+			discard()
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store image"})
+			return
+		}
+		stored = append(stored, img)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Photos").Create(&review).Error; err != nil {
+			return err
+		}
+		if len(stored) > 0 {
+			return tx.Create(&stored).Error
+		}
+		return nil
+	})
+	if err != nil {
+		discard()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create review"})
+		return
+	}
+	db.Preload("User").Preload("Photos", imagesInOrder).First(&review, "id = ?", review.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Review submitted and waiting for moderation",
+		"review":  review,
+	})
+}
+
+// approvedReview loads a review customers can see, answering the request
+// itself when there is none
+func approvedReview(c *gin.Context) (*models.Review, bool) {
+	var review models.Review
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return nil, false
+	}
+	if err := config.GetDB().Where("id = ? AND status = ?", c.Param("id"), models.ReviewApproved).
+		First(&review).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return nil, false
+	}
+	return &review, true
+}
+
+// MarkReviewHelpful godoc
+// @Summary Mark a review as helpful
+// @Description Vote for an approved review as helpful, once per customer. Voting again changes nothing.
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Success 200 {object} map[string]interface{} "Vote recorded"
+// @Failure 400 {object} map[string]interface{} "Cannot vote on your own review"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Review not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /reviews/{id}/helpful [post]
+func MarkReviewHelpful(c *gin.Context) {
+	review, ok := approvedReview(c)
+	if !ok {
+		return
+	}
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+	if review.UserID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errReviewOwnVote.Error()})
+		return
+	}
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		var voted int64
+		if err := tx.Model(&models.ReviewVote{}).Where("review_id = ? AND user_id = ?", review.ID, userID).
+			Count(&voted).Error; err != nil || voted > 0 {
+			return err
+		}
+		if err := tx.Create(&models.ReviewVote{ReviewID: review.ID, UserID: userID}).Error; err != nil {
+			return err
+		}
+		return tx.Model(review).UpdateColumn("helpful_count", gorm.Expr("helpful_count + 1")).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to record vote"})
+		return
+	}
+	config.GetDB().Select("helpful_count").First(review, "id = ?", review.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Marked as helpful",
+		"helpful_count": review.HelpfulCount,
+	})
+}
+
+// UnmarkReviewHelpful godoc
+// @Summary Take back a helpful vote
+// @Description Remove the current user's helpful vote from a review
+// @Tags Reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Success 200 {object} map[string]interface{} "Vote removed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Review not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /reviews/{id}/helpful [delete]
+func UnmarkReviewHelpful(c *gin.Context) {
+	review, ok := approvedReview(c)
+	if !ok {
+		return
+	}
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("review_id = ? AND user_id = ?", review.ID, userID).Delete(&models.ReviewVote{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(review).UpdateColumn("helpful_count", gorm.Expr("helpful_count - 1")).Error
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove vote"})
+		return
+	}
+	config.GetDB().Select("helpful_count").First(review, "id = ?", review.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Vote removed",
+		"helpful_count": review.HelpfulCount,
+	})
+}
+
+// GetReviewQueue godoc
+// @Summary Review moderation queue (Admin only)
+// @Description List reviews oldest first, pending ones by default
+// @Tags Admin - Reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Moderation status, default pending" Enums(pending, approved, hidden)
+// @Param product_id query string false "Only reviews of this product"
+// @Success 200 {object} map[string]interface{} "Reviews"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/reviews [get]
+func GetReviewQueue(c *gin.Context) {
+	query := config.GetDB().Preload("User").Preload("Product", withArchived).Preload("Photos", imagesInOrder).
+		Where("status = ?", c.DefaultQuery("status", models.ReviewPending))
+	if productID := c.Query("product_id"); productID != "" {
+		if _, err := uuid.Parse(productID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "product_id must be a UUID"})
+			return
+		}
+		query = query.Where("product_id = ?", productID)
+	}
+
+	var reviews []models.Review
+	if err := query.Order("created_at ASC").Find(&reviews).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch reviews"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"reviews": reviews})
+}
+
+// findReview loads any review for moderation, answering the request itself
+// when it is not found
+func findReview(c *gin.Context) (*models.Review, bool) {
+	var review models.Review
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return nil, false
+	}
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&review).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Review not found"})
+		return nil, false
+	}
+	return &review, true
+}
+
+// ModerateReview godoc
+// @Summary Moderate a review (Admin only)
+// @Description Approve a review to show it and count it in the product's rating, hide it, or send it back to pending
+// @Tags Admin - Reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Param request body ModerateReviewRequest true "New status"
+// @Success 200 {object} map[string]interface{} "Review moderated"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Review not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/reviews/{id}/status [put]
+func ModerateReview(c *gin.Context) {
+	var req ModerateReviewRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		respondInvalid(c, err)
+		return
+	}
+
+	review, ok := findReview(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	moderatorID, _ := uuid.Parse(c.GetString("user_id"))
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(review).Updates(map[string]interface{}{
+			"status":       req.Status,
+			"moderated_by": moderatorID,
+			"moderated_at": now,
+		}).Error; err != nil {
+			return err
+		}
+		return updateProductRating(tx, review.ProductID)
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to moderate review"})
+		return
+	}
+	config.GetDB().Preload("User").Preload("Photos", imagesInOrder).First(review, "id = ?", review.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Review " + req.Status,
+		"review":  review,
+	})
+}
+
+// ReplyToReview godoc
+// @Summary Reply to a review (Admin only)
+// @Description Set the store's public reply shown under a review; an empty reply removes it
+// @Tags Admin - Reviews
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Review ID"
+// @Param request body ReviewReplyRequest true "Reply"
+// @Success 200 {object} map[string]interface{} "Reply saved"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "Review not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/reviews/{id}/reply [put]
+func ReplyToReview(c *gin.Context) {
+	var req ReviewReplyRequest
+	if err := bindStrictJSON(c, &req); err != nil {
+		respondInvalid(c, err)
+		return
+	}
+
+	review, ok := findReview(c)
+	if !ok {
+		return
+	}
+
+	reply := strings.TrimSpace(req.Reply)
+	var repliedAt *time.Time
+	if reply != "" {
+		now := time.Now()
+		repliedAt = &now
+	}
+	if err := config.GetDB().Model(review).Updates(map[string]interface{}{
+		"admin_reply": reply,
+		"replied_at":  repliedAt,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save reply"})
+		return
+	}
+	config.GetDB().Preload("User").Preload("Photos", imagesInOrder).First(review, "id = ?", review.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reply saved",
+		"review":  review,
+	})
+}
@@ -0,0 +1,654 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	errProductNotFound       = errors.New("Product not found")
+	errSubscriptionCancelled = errors.New("Subscription is cancelled")
+	errShippingAddress       = errors.New("shipping_address is required when your profile has no address")
+	errDeliveryDate          = errors.New("Delivery date must be between today and one year ahead")
+)
+
+// SubscriptionInput represents the request body for creating/updating an autoship subscription
+type SubscriptionInput struct {
+	ProductID       string `json:"product_id" binding:"required" example:"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"`
+	VariantID       string `json:"variant_id" example:"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"` // optional for single-variant products
+	Quantity        int    `json:"quantity" binding:"required,min=1,max=20" example:"1"`
+	IntervalDays    int    `json:"interval_days" binding:"required,min=7,max=180" example:"30"`
+	ShippingAddress string `json:"shipping_address" example:"123 Main St, Bangkok 10110"` // defaults to the profile address
+	PaymentMethod   string `json:"payment_method" example:"cod" enums:"cod,bank_transfer,promptpay,card"`
+	UseStoreCredit  bool   `json:"use_store_credit" example:"false"`
+	FirstDelivery   string `json:"first_delivery" binding:"omitempty,datetime=2006-01-02" example:"2026-11-01"` // create only, defaults to today
+}
+
+// RescheduleSubscriptionRequest represents the request body for moving the next delivery
+type RescheduleSubscriptionRequest struct {
+	NextDelivery string `json:"next_delivery" binding:"required,datetime=2006-01-02" example:"2026-11-15"`
+}
+
+// PauseSubscriptionRequest represents the request body for pausing a subscription
+type PauseSubscriptionRequest struct {
+	ResumeOn string `json:"resume_on" binding:"omitempty,datetime=2006-01-02" example:"2026-12-01"` // optional, empty = until resumed
+}
+
+// applyTo validates the input and copies it onto a subscription
+func (input *SubscriptionInput) applyTo(db *gorm.DB, sub *models.Subscription) error {
+	var product models.Product
+	if err := db.Scopes(purchasableProducts).Where("id = ?", input.ProductID).First(&product).Error; err != nil {
+		return errProductNotFound
+	}
+	variant, err := resolveVariant(db, product.ID, input.VariantID)
+	if err != nil {
+		return err
+	}
+	if err := checkPrescription(db, sub.UserID, &product); err != nil {
+		return err
+	}
+
+	method := input.PaymentMethod
+	if method == "" {
+		method = models.PaymentMethodCOD
+	}
+	if !isExternalPaymentMethod(method) {
+		return errInvalidPaymentMethod
+	}
+
+	address := strings.TrimSpace(input.ShippingAddress)
+	if address == "" {
+		var user models.User
+		if err := db.Where("id = ?", sub.UserID).First(&user).Error; err != nil {
+			return err
+		}
+		address = strings.TrimSpace(user.Address)
+	}
+	if address == "" {
+		return errShippingAddress
+	}
+
+	sub.ProductID = product.ID
+	sub.Product = product
+	sub.VariantID = variant.ID
+	sub.Variant = variant
+	sub.Quantity = input.Quantity
+	sub.IntervalDays = input.IntervalDays
+	sub.ShippingAddress = address
+	sub.PaymentMethod = method
+	sub.UseStoreCredit = input.UseStoreCredit
+	return nil
+}
+
+// parseDeliveryDate reads a YYYY-MM-DD date between today and a year ahead as
+// the start of that day, local time
+func parseDeliveryDate(value string, now time.Time) (time.Time, error) {
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+	if err != nil {
+		return time.Time{}, errDeliveryDate
+	}
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	if date.Before(today) || date.After(today.AddDate(1, 0, 0)) {
+		return time.Time{}, errDeliveryDate
+	}
+	if date.Equal(today) {
+		return now, nil
+	}
+	return date, nil
+}
+
+// subscriptionInputError answers a failed SubscriptionInput.applyTo
+func subscriptionInputError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errProductNotFound), errors.Is(err, errVariantNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, errVariantRequired), errors.Is(err, errInvalidPaymentMethod), errors.Is(err, errShippingAddress):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, errPrescriptionRequired):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save subscription"})
+	}
+}
+
+// subscriptionResponse adds the price the next delivery is expected to cost
+func subscriptionResponse(db *gorm.DB, sub *models.Subscription) gin.H {
+	response := gin.H{"subscription": sub}
+	if sub.Status == models.SubscriptionCancelled {
+		return response
+	}
+	items := []models.Cart{{
+		UserID:    sub.UserID,
+		ProductID: sub.ProductID,
+		Product:   sub.Product,
+		VariantID: &sub.VariantID,
+		Variant:   sub.Variant,
+		Quantity:  sub.Quantity,
+	}}
+	if totals, err := priceCart(db, sub.UserID.String(), items, pricingOptions{Autoship: true}); err == nil {
+		response["estimate"] = gin.H{
+			"subtotal":            totals.Subtotal,
+			"subscriber_discount": totals.SubscriberDiscount,
+			"discount":            totals.Discount,
+			"shipping_fee":        totals.ShippingFee,
+			"total":               totals.Total,
+			"currency":            totals.Currency,
+		}
+	}
+	return response
+}
+
+// GetSubscriptions godoc
+// @Summary Get my autoship subscriptions
+// @Description List the current user's subscriptions with the estimated price of each next delivery
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "List of subscriptions"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /subscriptions [get]
+func GetSubscriptions(c *gin.Context) {
+	db := config.GetDB()
+
+	var subs []models.Subscription
+	if err := db.Preload("Product", withArchived).Preload("Variant").Where("user_id = ?", c.GetString("user_id")).
+		Order("created_at DESC").Find(&subs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscriptions"})
+		return
+	}
+
+	response := make([]gin.H, len(subs))
+	for i := range subs {
+		response[i] = subscriptionResponse(db, &subs[i])
+	}
+	c.JSON(http.StatusOK, gin.H{"subscriptions": response})
+}
+
+// GetSubscription godoc
+// @Summary Get an autoship subscription
+// @Description Get one of the current user's subscriptions with its delivery history
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} map[string]interface{} "Subscription, next delivery estimate and runs"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Subscription not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /subscriptions/{id} [get]
+func GetSubscription(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	sub, err := subscriptionOwner(db, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+
+	var runs []models.SubscriptionRun
+	if err := db.Where("subscription_id = ?", sub.ID).Order("created_at DESC").Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscription runs"})
+		return
+	}
+
+	response := subscriptionResponse(db, &sub)
+	response["runs"] = runs
+	c.JSON(http.StatusOK, response)
+}
+
+// CreateSubscription godoc
+// @Summary Subscribe to autoship
+// @Description Have a product delivered every interval_days. Orders are placed automatically at the subscriber discount; the first one on first_delivery, or today when not given.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param subscription body SubscriptionInput true "Subscription data"
+// @Success 201 {object} map[string]interface{} "Subscription created successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Prescription diet without an approved prescription"
+// @Failure 404 {object} map[string]interface{} "Product or variant not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /subscriptions [post]
+func CreateSubscription(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var input SubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	now := time.Now()
+	sub := models.Subscription{UserID: userID, Status: models.SubscriptionActive, NextRunAt: now}
+	if input.FirstDelivery != "" {
+		date, err := parseDeliveryDate(input.FirstDelivery, now)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sub.NextRunAt = date
+	}
+	if err := input.applyTo(db, &sub); err != nil {
+		subscriptionInputError(c, err)
+		return
+	}
+
+	if err := db.Omit("Product", "Variant").Create(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create subscription"})
+		return
+	}
+
+	response := subscriptionResponse(db, &sub)
+	response["message"] = "Subscription created successfully"
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpdateSubscription godoc
+// @Summary Update an autoship subscription
+// @Description Change the product, quantity, interval, address or payment method. The next delivery date stays; use reschedule to move it.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Param subscription body SubscriptionInput true "Subscription data"
+// @Success 200 {object} map[string]interface{} "Subscription updated successfully"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Prescription diet without an approved prescription"
+// @Failure 404 {object} map[string]interface{} "Subscription, product or variant not found"
+// @Failure 409 {object} map[string]interface{} "Subscription is cancelled"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /subscriptions/{id} [put]
+func UpdateSubscription(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	sub, err := subscriptionOwner(db, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	if sub.Status == models.SubscriptionCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": errSubscriptionCancelled.Error()})
+		return
+	}
+
+	var input SubscriptionInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := input.applyTo(db, &sub); err != nil {
+		subscriptionInputError(c, err)
+		return
+	}
+
+	if err := db.Omit("Product", "Variant").Save(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update subscription"})
+		return
+	}
+
+	response := subscriptionResponse(db, &sub)
+	response["message"] = "Subscription updated successfully"
+	c.JSON(http.StatusOK, response)
+}
+
+// SkipSubscription godoc
+// @Summary Skip the next autoship delivery
+// @Description Skip the upcoming delivery; the one after it follows on the usual interval
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} map[string]interface{} "Delivery skipped"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Subscription not found"
+// @Failure 409 {object} map[string]interface{} "Subscription is cancelled"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /subscriptions/{id}/skip [post]
+func SkipSubscription(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	sub, err := subscriptionOwner(db, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	if sub.Status == models.SubscriptionCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": errSubscriptionCancelled.Error()})
+		return
+	}
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return skipSubscription(tx, &sub, time.Now())
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to skip delivery"})
+		return
+	}
+
+	response := subscriptionResponse(db, &sub)
+	response["message"] = "Delivery skipped"
+	c.JSON(http.StatusOK, response)
+}
+
+// RescheduleSubscription godoc
+// @Summary Reschedule the next autoship delivery
+// @Description Move the next delivery to another date within a year; later deliveries follow on the usual interval from there
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Param request body RescheduleSubscriptionRequest true "Next delivery date"
+// @Success 200 {object} map[string]interface{} "Delivery rescheduled"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Subscription not found"
+// @Failure 409 {object} map[string]interface{} "Subscription is cancelled"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /subscriptions/{id}/reschedule [post]
+func RescheduleSubscription(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	sub, err := subscriptionOwner(db, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	if sub.Status == models.SubscriptionCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": errSubscriptionCancelled.Error()})
+		return
+	}
+
+	var req RescheduleSubscriptionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	date, err := parseDeliveryDate(req.NextDelivery, time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// A new date is a fresh delivery, so earlier failed attempts no longer count
+	sub.NextRunAt = date
+	sub.FailedAttempts = 0
+	sub.RetryAt = nil
+	if err := db.Omit("Product", "Variant").Save(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reschedule delivery"})
+		return
+	}
+
+	response := subscriptionResponse(db, &sub)
+	response["message"] = "Delivery rescheduled"
+	c.JSON(http.StatusOK, response)
+}
+
+// PauseSubscription godoc
+// @Summary Pause an autoship subscription
+// @Description Stop deliveries until resumed, or until resume_on when given. Deliveries that fall due while paused are ordered once it resumes.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Param request body PauseSubscriptionRequest false "Optional resume date"
+// @Success 200 {object} map[string]interface{} "Subscription paused"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Subscription not found"
+// @Failure 409 {object} map[string]interface{} "Subscription is cancelled"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /subscriptions/{id}/pause [post]
+func PauseSubscription(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	sub, err := subscriptionOwner(db, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	if sub.Status == models.SubscriptionCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": errSubscriptionCancelled.Error()})
+		return
+	}
+
+	var req PauseSubscriptionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	sub.PausedUntil = nil
+	if req.ResumeOn != "" {
+		date, err := parseDeliveryDate(req.ResumeOn, time.Now())
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		sub.PausedUntil = &date
+	}
+	sub.Status = models.SubscriptionPaused
+
+	if err := db.Omit("Product", "Variant").Save(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to pause subscription"})
+		return
+	}
+
+	response := subscriptionResponse(db, &sub)
+	response["message"] = "Subscription paused"
+	c.JSON(http.StatusOK, response)
+}
+
+// ResumeSubscription godoc
+// @Summary Resume an autoship subscription
+// @Description Restart deliveries of a paused subscription. A delivery that fell due while paused is ordered on the next scheduler run.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} map[string]interface{} "Subscription resumed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Subscription not found"
+// @Failure 409 {object} map[string]interface{} "Subscription is cancelled"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /subscriptions/{id}/resume [post]
+func ResumeSubscription(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	sub, err := subscriptionOwner(db, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	if sub.Status == models.SubscriptionCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": errSubscriptionCancelled.Error()})
+		return
+	}
+
+	sub.Status = models.SubscriptionActive
+	sub.PausedUntil = nil
+	if err := db.Omit("Product", "Variant").Save(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resume subscription"})
+		return
+	}
+
+	response := subscriptionResponse(db, &sub)
+	response["message"] = "Subscription resumed"
+	c.JSON(http.StatusOK, response)
+}
+
+// CancelSubscription godoc
+// @Summary Cancel an autoship subscription
+// @Description Stop all future deliveries. Orders already placed are not affected.
+// @Tags Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Subscription ID"
+// @Success 200 {object} map[string]interface{} "Subscription cancelled"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Subscription not found"
+// @Failure 409 {object} map[string]interface{} "Subscription is already cancelled"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /subscriptions/{id}/cancel [post]
+func CancelSubscription(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	sub, err := subscriptionOwner(db, c.Param("id"), userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subscription not found"})
+		return
+	}
+	if sub.Status == models.SubscriptionCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": errSubscriptionCancelled.Error()})
+		return
+	}
+
+	now := time.Now()
+	sub.Status = models.SubscriptionCancelled
+	sub.CancelledAt = &now
+	sub.PausedUntil = nil
+	sub.RetryAt = nil
+	if err := db.Omit("Product", "Variant").Save(&sub).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel subscription"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Subscription cancelled",
+		"subscription": sub,
+	})
+}
+
+// GetSubscriptionReport godoc
+// @Summary Autoship subscription report (Admin only)
+// @Description List subscriptions with their last error, plus counts by status. Filter by status, or failing=true for subscriptions whose current delivery is being retried.
+// @Tags Admin - Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Subscription status" Enums(active, paused, cancelled)
+// @Param failing query bool false "Only subscriptions with failed attempts pending a retry"
+// @Success 200 {object} map[string]interface{} "Subscriptions and summary"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/subscriptions [get]
+func GetSubscriptionReport(c *gin.Context) {
+	db := config.GetDB()
+
+	type statusCount struct {
+		Status string
+		Count  int
+	}
+	var counts []statusCount
+	if err := db.Model(&models.Subscription{}).Select("status, COUNT(*) AS count").
+		Group("status").Scan(&counts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscriptions"})
+		return
+	}
+	summary := gin.H{models.SubscriptionActive: 0, models.SubscriptionPaused: 0, models.SubscriptionCancelled: 0}
+	for _, count := range counts {
+		summary[count.Status] = count.Count
+	}
+
+	query := db.Preload("Product", withArchived).Preload("Variant").Order("next_run_at ASC")
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if c.Query("failing") == "true" {
+		query = query.Where("failed_attempts > 0")
+	}
+
+	var subs []models.Subscription
+	if err := query.Find(&subs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summary":       summary,
+		"subscriptions": subs,
+	})
+}
+
+// GetSubscriptionRuns godoc
+// @Summary Autoship run history (Admin only)
+// @Description List scheduled delivery attempts, newest first. Filter by status to review failed runs and deliveries that were given up.
+// @Tags Admin - Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Run status" Enums(succeeded, failed, gave_up, skipped)
+// @Param subscription_id query string false "Only runs of this subscription"
+// @Success 200 {object} map[string]interface{} "Subscription runs"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/subscriptions/runs [get]
+func GetSubscriptionRuns(c *gin.Context) {
+	query := config.GetDB().Order("created_at DESC").Limit(500)
+	if status := c.Query("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if subscriptionID := c.Query("subscription_id"); subscriptionID != "" {
+		query = query.Where("subscription_id = ?", subscriptionID)
+	}
+
+	var runs []models.SubscriptionRun
+	if err := query.Find(&runs).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch subscription runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// RunSubscriptions godoc
+// @Summary Run the autoship scheduler now (Admin only)
+// @Description Place orders for every due subscription without waiting for the next scheduler pass
+// @Tags Admin - Subscriptions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Run summary"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/subscriptions/run [post]
+func RunSubscriptions(c *gin.Context) {
+	summary, err := RunDueSubscriptions(config.GetDB(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to run subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"summary": summary})
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"errors"
+	"log"
+	"pet-food-ecommerce/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// errSubscriptionClaimed means another scheduler already handled this delivery
+var errSubscriptionClaimed = errors.New("subscription delivery already handled")
+
+// subscriptionSettings holds the autoship rules, each overridable through the environment
+type subscriptionSettings struct {
+	DiscountBps     int64         // AUTOSHIP_DISCOUNT_PERCENT: subscriber discount on goods
+	MaxAttempts     int           // AUTOSHIP_MAX_ATTEMPTS: tries per delivery before it is given up
+	RetryDelay      time.Duration // AUTOSHIP_RETRY_HOURS: wait between tries
+	SchedulerPeriod time.Duration // AUTOSHIP_SCHEDULER_MINUTES: how often due subscriptions are checked
+}
+
+func loadSubscriptionSettings() subscriptionSettings {
+	return subscriptionSettings{
+		DiscountBps:     int64(min(envInt("AUTOSHIP_DISCOUNT_PERCENT", 5), 100)) * 100,
+		MaxAttempts:     max(envInt("AUTOSHIP_MAX_ATTEMPTS", 3), 1),
+		RetryDelay:      time.Duration(envInt("AUTOSHIP_RETRY_HOURS", 24)) * time.Hour,
+		SchedulerPeriod: time.Duration(max(envInt("AUTOSHIP_SCHEDULER_MINUTES", 15), 1)) * time.Minute,
+	}
+}
+
+// subscriptionRunSummary counts what one scheduler pass did
+type subscriptionRunSummary struct {
+	Resumed int `json:"resumed"`
+	Due     int `json:"due"`
+	Placed  int `json:"placed"`
+	Failed  int `json:"failed"`
+	GaveUp  int `json:"gave_up"`
+}
+
+// StartSubscriptionScheduler places due autoship orders every AUTOSHIP_SCHEDULER_MINUTES
+// until the process exits. Run it in its own goroutine.
+func StartSubscriptionScheduler(db *gorm.DB) {
+	ticker := time.NewTicker(loadSubscriptionSettings().SchedulerPeriod)
+	defer ticker.Stop()
+	for {
+		summary, err := RunDueSubscriptions(db, time.Now())
+		if err != nil {
+			log.Println("Autoship scheduler:", err)
+		} else if summary.Due > 0 || summary.Resumed > 0 {
+			log.Printf("Autoship scheduler: %d due, %d placed, %d failed, %d given up, %d resumed",
+				summary.Due, summary.Placed, summary.Failed, summary.GaveUp, summary.Resumed)
+		}
+		<-ticker.C
+	}
+}
+
+// RunDueSubscriptions resumes paused subscriptions whose pause has ended and
+// places an order for every active subscription that is due
+func RunDueSubscriptions(db *gorm.DB, now time.Time) (subscriptionRunSummary, error) {
+	var summary subscriptionRunSummary
+	settings := loadSubscriptionSettings()
+
+	resumed := db.Model(&models.Subscription{}).
+		Where("status = ? AND paused_until IS NOT NULL AND paused_until <= ?", models.SubscriptionPaused, now).
+		Updates(map[string]interface{}{"status": models.SubscriptionActive, "paused_until": nil})
+	if resumed.Error != nil {
+		return summary, resumed.Error
+	}
+	summary.Resumed = int(resumed.RowsAffected)
+
+	var due []models.Subscription
+	if err := db.Preload("Product").Preload("Variant").
+		Where("status = ? AND next_run_at <= ? AND (retry_at IS NULL OR retry_at <= ?)", models.SubscriptionActive, now, now).
+		Order("next_run_at ASC").Find(&due).Error; err != nil {
+		return summary, err
+	}
+
+	for i := range due {
+		status, err := runSubscription(db, &due[i], now, settings)
+		if errors.Is(err, errSubscriptionClaimed) {
+			continue
+		}
+		summary.Due++
+		if err != nil {
+			log.Printf("Autoship %s: %v", due[i].ID, err)
+		}
+		switch status {
+		case models.SubscriptionRunSucceeded:
+			summary.Placed++
+		case models.SubscriptionRunFailed:
+			summary.Failed++
+		case models.SubscriptionRunGaveUp:
+			summary.GaveUp++
+		}
+	}
+	return summary, nil
+}
+
+// nextDelivery is the first delivery date after now on the subscription's cycle,
+// so deliveries missed while the scheduler was down are not all ordered at once
+func nextDelivery(sub *models.Subscription, now time.Time) time.Time {
+	next := sub.NextRunAt.AddDate(0, 0, sub.IntervalDays)
+	for !next.After(now) {
+		next = next.AddDate(0, 0, sub.IntervalDays)
+	}
+	return next
+}
+
+// claimSubscription scopes an update to the delivery attempt that was loaded, so
+// concurrent schedulers never order the same delivery twice
+func claimSubscription(tx *gorm.DB, sub *models.Subscription) *gorm.DB {
+	return tx.Model(&models.Subscription{}).
+		Where("id = ? AND status = ? AND next_run_at = ? AND failed_attempts = ?",
+			sub.ID, models.SubscriptionActive, sub.NextRunAt, sub.FailedAttempts)
+}
+
+// runSubscription places the order for one due subscription (Product and Variant
+// preloaded) and records the run. A failed attempt is retried after the retry
+// delay; once the attempts run out the delivery is given up and the subscription
+// moves on to its next cycle. It returns the run status.
+func runSubscription(db *gorm.DB, sub *models.Subscription, now time.Time, settings subscriptionSettings) (string, error) {
+	attempt := sub.FailedAttempts + 1
+	next := nextDelivery(sub, now)
+
+	var order models.Order
+	err := db.Transaction(func(tx *gorm.DB) error {
+		claim := claimSubscription(tx, sub).Updates(map[string]interface{}{
+			"next_run_at":     next,
+			"failed_attempts": 0,
+			"retry_at":        nil,
+			"last_error":      "",
+			"last_run_at":     now,
+		})
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return errSubscriptionClaimed
+		}
+
+		// Earlier runs in this pass may have taken the stock the due list was loaded with
+		var variant models.ProductVariant
+		if err := tx.Where("id = ?", sub.VariantID).Limit(1).Find(&variant).Error; err != nil {
+			return err
+		}
+
+		items := []models.Cart{{
+			UserID:    sub.UserID,
+			ProductID: sub.ProductID,
+			Product:   sub.Product,
+			VariantID: &sub.VariantID,
+			Variant:   variant,
+			Quantity:  sub.Quantity,
+		}}
+		order = models.Order{UserID: sub.UserID, SubscriptionID: &sub.ID}
+		req := CreateOrderRequest{
+			ShippingAddress: sub.ShippingAddress,
+			PaymentMethod:   sub.PaymentMethod,
+			UseStoreCredit:  sub.UseStoreCredit,
+		}
+		if err := placeOrder(tx, &order, items, &req, pricingOptions{Autoship: true}); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.Subscription{}).Where("id = ?", sub.ID).
+			Update("last_order_id", order.ID).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.SubscriptionRun{
+			SubscriptionID: sub.ID,
+			UserID:         sub.UserID,
+			ScheduledFor:   sub.NextRunAt,
+			Attempt:        attempt,
+			Status:         models.SubscriptionRunSucceeded,
+			OrderID:        &order.ID,
+		}).Error
+	})
+	if err == nil {
+		return models.SubscriptionRunSucceeded, nil
+	}
+	if errors.Is(err, errSubscriptionClaimed) {
+		return "", err
+	}
+
+	// Customers see checkout problems as they are; anything else stays in the log
+	reason := "Could not place the order"
+	var checkoutErr *checkoutError
+	if errors.As(err, &checkoutErr) {
+		reason = checkoutErr.Error()
+	}
+
+	status := models.SubscriptionRunFailed
+	updates := map[string]interface{}{
+		"failed_attempts": attempt,
+		"retry_at":        now.Add(settings.RetryDelay),
+		"last_error":      reason,
+		"last_run_at":     now,
+	}
+	if attempt >= settings.MaxAttempts {
+		status = models.SubscriptionRunGaveUp
+		updates = map[string]interface{}{
+			"next_run_at":     next,
+			"failed_attempts": 0,
+			"retry_at":        nil,
+			"last_error":      reason,
+			"last_run_at":     now,
+		}
+	}
+
+	recordErr := db.Transaction(func(tx *gorm.DB) error {
+		claim := claimSubscription(tx, sub).Updates(updates)
+		if claim.Error != nil {
+			return claim.Error
+		}
+		if claim.RowsAffected == 0 {
+			return errSubscriptionClaimed
+		}
+		return tx.Create(&models.SubscriptionRun{
+			SubscriptionID: sub.ID,
+			UserID:         sub.UserID,
+			ScheduledFor:   sub.NextRunAt,
+			Attempt:        attempt,
+			Status:         status,
+			Error:          reason,
+		}).Error
+	})
+	if recordErr != nil {
+		return "", recordErr
+	}
+	return status, err
+}
+
+// skipSubscription moves a subscription past its next delivery and records the skip
+func skipSubscription(tx *gorm.DB, sub *models.Subscription, now time.Time) error {
+	if err := tx.Create(&models.SubscriptionRun{
+		SubscriptionID: sub.ID,
+		UserID:         sub.UserID,
+		ScheduledFor:   sub.NextRunAt,
+		Attempt:        sub.FailedAttempts + 1,
+		Status:         models.SubscriptionRunSkipped,
+	}).Error; err != nil {
+		return err
+	}
+
+	sub.NextRunAt = nextDelivery(sub, now)
+	sub.FailedAttempts = 0
+	sub.RetryAt = nil
+	sub.LastError = ""
+	return tx.Omit("Product", "Variant").Save(sub).Error
+}
+
+// subscriptionOwner loads one of the user's subscriptions
+func subscriptionOwner(db *gorm.DB, id string, userID uuid.UUID) (models.Subscription, error) {
+	var sub models.Subscription
+	err := db.Preload("Product", withArchived).Preload("Variant").
+		Where("id = ? AND user_id = ?", id, userID).First(&sub).Error
+	return sub, err
+}
@@ -0,0 +1,98 @@
+package controllers
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/jpeg"
+	_ "image/png" // register the PNG decoder
+	"net/http"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp" // register the WebP decoder
+)
+
+const (
+	maxImageFileSize     = 10 << 20   // 10 MB
+	maxImagePixels       = 40_000_000 // keeps a small file from decoding into gigabytes
+	thumbnailJPEGQuality = 85
+)
+
+// imageFileTypes maps the accepted image types to the extension they are stored with
+var imageFileTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+}
+
+// thumbnailSizes are made for every uploaded image, fitting the longest side
+var thumbnailSizes = []struct {
+	Name string
+	Side int
+}{
+	{"small", 320},
+	{"medium", 960},
+}
+
+var (
+	errImageType       = errors.New("File must be a JPEG, PNG or WebP image")
+	errImageTooLarge   = errors.New("Image must be 10 MB or smaller")
+	errImageDimensions = errors.New("Image must be 40 megapixels or smaller")
+	errImageUnreadable = errors.New("Image could not be read")
+)
+
+// decodeImage checks that data holds an accepted image and decodes it,
+// trusting the bytes rather than the name or type the client sent
+func decodeImage(data []byte) (image.Image, string, error) {
+	contentType := http.DetectContentType(data)
+	if _, ok := imageFileTypes[contentType]; !ok {
+		return nil, "", errImageType
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errImageUnreadable
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, "", errImageDimensions
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", errImageUnreadable
+	}
+	return img, contentType, nil
+}
+
+// fitImage scales img down so its longest side is at most side pixels; smaller
+// images are kept as they are
+func fitImage(img image.Image, side int) image.Image {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width <= side && height <= side {
+		return img
+	}
+	if width >= height {
+		width, height = side, max(1, height*side/width)
+	} else {
+		width, height = max(1, width*side/height), side
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, bounds, draw.Src, nil)
+	return dst
+}
+
+// encodeJPEG encodes a JPEG, putting transparent images on white since JPEG
+// has no alpha channel
+func encodeJPEG(img image.Image) ([]byte, error) {
+	if opaque, ok := img.(interface{ Opaque() bool }); !ok || !opaque.Opaque() {
+		bounds := img.Bounds()
+		flat := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+		draw.Draw(flat, flat.Bounds(), img, bounds.Min, draw.Over)
+		img = flat
+	}
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: thumbnailJPEGQuality}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,17 @@
+//go:build !cgo
+
+package controllers
+
+import (
+	"errors"
+	"image"
+)
+
+// webpThumbnails is set when WebP thumbnails can be encoded, which needs libwebp
+// through cgo; without it thumbnails are JPEG only
+const webpThumbnails = false
+
+// encodeWebP is never called without cgo, since webpThumbnails is false
+func encodeWebP(img image.Image) ([]byte, error) {
+	return nil, errors.New("WebP encoding needs a build with cgo")
+}
@@ -0,0 +1,19 @@
+//go:build cgo
+
+package controllers
+
+import (
+	"image"
+
+	"github.com/chai2010/webp"
+)
+
+// webpThumbnails is set when WebP thumbnails can be encoded, which needs libwebp through cgo
+const webpThumbnails = true
+
+const thumbnailWebPQuality = 80
+
+// encodeWebP encodes a lossy WebP, keeping transparency
+func encodeWebP(img image.Image) ([]byte, error) {
+	return webp.EncodeRGBA(img, thumbnailWebPQuality)
+}
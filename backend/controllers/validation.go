@@ -0,0 +1,153 @@
+package controllers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// fieldErrors maps an input field, by its JSON path such as
+// guaranteed_analysis.protein or variants[0].price, to what is wrong with it
+type fieldErrors map[string]string
+
+func (e fieldErrors) add(field, format string, args ...interface{}) {
+	if _, ok := e[field]; !ok {
+		e[field] = fmt.Sprintf(format, args...)
+	}
+}
+
+// err returns e as an error, or nil when there is nothing wrong
+func (e fieldErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// messages lists the messages in field order
+func (e fieldErrors) messages() []string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = e[field]
+	}
+	return messages
+}
+
+func (e fieldErrors) Error() string {
+	return strings.Join(e.messages(), "; ")
+}
+
+// inputValidator checks the binding tags of strict DTOs, naming fields by
+// their JSON names rather than Go's
+var inputValidator = func() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.SetTagName("binding")
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}()
+
+// bindStrictJSON decodes the request body into obj, rejecting fields obj does
+// not have, then checks its binding tags. Problems come back as fieldErrors
+// where they can be pinned to a field.
+func bindStrictJSON(c *gin.Context, obj interface{}) error {
+	decoder := json.NewDecoder(c.Request.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(obj); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.As(err, &typeErr) && typeErr.Field != "":
+			return fieldErrors{typeErr.Field: fmt.Sprintf("%s must be a %s", typeErr.Field, jsonTypeName(typeErr.Type))}
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+			return fieldErrors{field: fmt.Sprintf("%s is not a field that can be set", field)}
+		default:
+			return fmt.Errorf("Invalid JSON body: %v", err)
+		}
+	}
+
+	err := inputValidator.Struct(obj)
+	var invalid validator.ValidationErrors
+	if !errors.As(err, &invalid) {
+		return err
+	}
+	fields := fieldErrors{}
+	for _, fe := range invalid {
+		// Drop the struct name the namespace starts with
+		_, field, _ := strings.Cut(fe.Namespace(), ".")
+		fields.add(field, "%s", validationMessage(field, fe))
+	}
+	return fields
+}
+
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	}
+	return "object"
+}
+
+func validationMessage(field string, fe validator.FieldError) string {
+	numeric := jsonTypeName(fe.Type()) == "number"
+	switch fe.Tag() {
+	case "required":
+		return field + " is required"
+	case "uuid":
+		return field + " must be a UUID"
+	case "oneof":
+		return field + " must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "gt":
+		return field + " must be greater than " + fe.Param()
+	case "min", "gte":
+		if numeric {
+			return field + " must be at least " + fe.Param()
+		}
+		if fe.Param() == "1" {
+			return field + " cannot be empty"
+		}
+		return fmt.Sprintf("%s must have at least %s characters", field, fe.Param())
+	case "max", "lte":
+		if numeric {
+			return field + " must be at most " + fe.Param()
+		}
+		return fmt.Sprintf("%s must have at most %s characters", field, fe.Param())
+	}
+	return fmt.Sprintf("%s is invalid (%s)", field, fe.Tag())
+}
+
+// respondInvalid answers a request whose input failed validation, listing the
+// fields at fault when known
+func respondInvalid(c *gin.Context, err error) {
+	var fields fieldErrors
+	if errors.As(err, &fields) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Validation failed", "fields": fields})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,166 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WalletAdjustmentRequest represents the request body for adjusting a user's store credit
+type WalletAdjustmentRequest struct {
+	Amount    models.Money `json:"amount" binding:"required" swaggertype:"number" example:"200.00"` // positive to credit, negative to debit
+	Reason    string       `json:"reason" binding:"required" example:"Compensation for late delivery"`
+	ExpiresAt *time.Time   `json:"expires_at" example:"2027-01-01T00:00:00+07:00"` // credits only
+}
+
+// walletResponse expires lapsed credits and renders the balance with the full ledger
+func walletResponse(db *gorm.DB, userID uuid.UUID) (gin.H, error) {
+	now := time.Now()
+
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		return expireWalletCredits(tx, userID, now)
+	}); err != nil {
+		return nil, err
+	}
+
+	balance, err := walletBalance(db, userID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []models.WalletEntry
+	if err := db.Where("user_id = ?", userID).Order("created_at DESC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	return gin.H{
+		"balance":  balance,
+		"currency": models.DefaultCurrency,
+		"entries":  entries,
+	}, nil
+}
+
+// GetWallet godoc
+// @Summary Get my store credit
+// @Description Get the authenticated user's store credit balance and ledger
+// @Tags Wallet
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Balance and ledger entries"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /wallet [get]
+func GetWallet(c *gin.Context) {
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	response, err := walletResponse(config.GetDB(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// GetUserWallet godoc
+// @Summary Get a user's store credit (Admin only)
+// @Description Get a customer's store credit balance and full ledger
+// @Tags Admin - Wallet
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Success 200 {object} map[string]interface{} "Balance and ledger entries"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/users/{id}/wallet [get]
+func GetUserWallet(c *gin.Context) {
+	var user models.User
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	response, err := walletResponse(config.GetDB(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet"})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AdjustUserWallet godoc
+// @Summary Adjust a user's store credit (Admin only)
+// @Description Issue store credit (positive amount) or take it back (negative amount). Every adjustment is kept in the ledger with the admin who made it.
+// @Tags Admin - Wallet
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID"
+// @Param request body WalletAdjustmentRequest true "Adjustment"
+// @Success 201 {object} map[string]interface{} "Store credit adjusted"
+// @Failure 400 {object} map[string]interface{} "Bad request or insufficient store credit"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Forbidden - Admin access required"
+// @Failure 404 {object} map[string]interface{} "User not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /admin/users/{id}/wallet/adjustments [post]
+func AdjustUserWallet(c *gin.Context) {
+	var user models.User
+	if err := config.GetDB().Where("id = ?", c.Param("id")).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	var req WalletAdjustmentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+
+	adminID, _ := uuid.Parse(c.GetString("user_id"))
+
+	err := config.GetDB().Transaction(func(tx *gorm.DB) error {
+		if req.Amount > 0 {
+			return creditWallet(tx, &models.WalletEntry{
+				UserID:    user.ID,
+				Amount:    req.Amount,
+				ExpiresAt: req.ExpiresAt,
+				Reason:    req.Reason,
+				ActorID:   &adminID,
+			})
+		}
+		return debitWallet(tx, user.ID, -req.Amount, req.Reason, nil, &adminID)
+	})
+	if errors.Is(err, errInsufficientStoreCredit) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to adjust store credit"})
+		return
+	}
+
+	response, err := walletResponse(config.GetDB(), user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wallet"})
+		return
+	}
+	response["message"] = "Store credit adjusted successfully"
+
+	c.JSON(http.StatusCreated, response)
+}
@@ -0,0 +1,414 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/models"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var errAlertInStock = errors.New("Product is in stock; back in stock alerts are for sold out products")
+
+// WishlistRequest represents the request body for saving a product to the wishlist
+type WishlistRequest struct {
+	ProductID string `json:"product_id" binding:"required,uuid" example:"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"`
+	VariantID string `json:"variant_id" binding:"omitempty,uuid" example:"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"` // optional pack size
+}
+
+// MoveToCartRequest represents the optional request body for moving a wishlist item to the cart
+type MoveToCartRequest struct {
+	VariantID string `json:"variant_id" binding:"omitempty,uuid" example:"bbbbbbbb-bbbb-bbbb-bbbb-bbbbbbbbbbbb"` // defaults to the saved pack size
+	Quantity  int    `json:"quantity" binding:"omitempty,min=1" example:"1"`                                     // defaults to 1
+}
+
+// ProductAlertRequest represents the request body for subscribing to a product alert
+type ProductAlertRequest struct {
+	ProductID string `json:"product_id" binding:"required,uuid" example:"aaaaaaaa-aaaa-aaaa-aaaa-aaaaaaaaaaaa"`
+	Type      string `json:"type" binding:"required,oneof=back_in_stock price_drop" example:"back_in_stock"`
+}
+
+// sendProductAlerts notifies the customers waiting on a product once it is
+// back in stock or cheaper than when they last heard. Call it in the
+// transaction that changed the product's stock or price. Alerts for products
+// not on sale wait until they are.
+func sendProductAlerts(tx *gorm.DB, productID uuid.UUID) error {
+	var product models.Product
+	err := tx.Scopes(purchasableProducts).Select("id", "name", "price", "stock").
+		First(&product, "id = ?", productID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	link := "/products/" + product.ID.String()
+	now := time.Now()
+
+	if product.Stock > 0 {
+		var restocked []models.ProductAlert
+		if err := tx.Where("product_id = ? AND type = ?", product.ID, models.ProductAlertBackInStock).
+			Find(&restocked).Error; err != nil {
+			return err
+		}
+		for _, alert := range restocked {
+			if err := notifyUser(tx, &models.Notification{
+				UserID: alert.UserID,
+				Type:   models.NotificationBackInStock,
+				Title:  product.Name + " is back in stock",
+				Body:   "Order now while it lasts.",
+				Link:   link,
+			}); err != nil {
+				return err
+			}
+			if err := tx.Delete(&alert).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	var cheaper []models.ProductAlert
+	if err := tx.Where("product_id = ? AND type = ? AND price > ?", product.ID, models.ProductAlertPriceDrop, product.Price).
+		Find(&cheaper).Error; err != nil {
+		return err
+	}
+	for _, alert := range cheaper {
+		if err := notifyUser(tx, &models.Notification{
+			UserID: alert.UserID,
+			Type:   models.NotificationPriceDrop,
+			Title:  "Price drop on " + product.Name,
+			Body:   "Now ฿" + product.Price.String() + ", down from ฿" + alert.Price.String() + ".",
+			Link:   link,
+		}); err != nil {
+			return err
+		}
+		if err := tx.Model(&alert).Updates(map[string]interface{}{
+			"price":       product.Price,
+			"notified_at": now,
+		}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetWishlist godoc
+// @Summary Get my wishlist
+// @Description List the products the current user saved, newest first. price_when_added shows how the price moved since.
+// @Tags Wishlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Wishlist items"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /wishlist [get]
+func GetWishlist(c *gin.Context) {
+	var items []models.WishlistItem
+	if err := config.GetDB().Preload("Product", withArchived).Preload("Product.Category", withArchived).Preload("Variant").
+		Where("user_id = ?", c.GetString("user_id")).Order("created_at DESC").Find(&items).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch wishlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"wishlist": items})
+}
+
+// AddToWishlist godoc
+// @Summary Save a product to my wishlist
+// @Description Save a product, optionally in one pack size. Saving a product already in the wishlist updates its pack size.
+// @Tags Wishlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body WishlistRequest true "Product to save"
+// @Success 201 {object} map[string]interface{} "Saved to wishlist"
+// @Success 200 {object} map[string]interface{} "Already in the wishlist"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product or variant not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /wishlist [post]
+func AddToWishlist(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var req WishlistRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var product models.Product
+	if err := db.Scopes(purchasableProducts).Where("id = ?", req.ProductID).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+	var variantID *uuid.UUID
+	price := product.Price
+	if req.VariantID != "" {
+		variant, err := resolveVariant(db, product.ID, req.VariantID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": errVariantNotFound.Error()})
+			return
+		}
+		variantID = &variant.ID
+		price = variant.Price
+	}
+
+	var item models.WishlistItem
+	err := db.Where("user_id = ? AND product_id = ?", userID, product.ID).First(&item).Error
+	if err == nil {
+		if req.VariantID != "" {
+			if err := db.Model(&item).Update("variant_id", variantID).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wishlist"})
+				return
+			}
+		}
+		db.Preload("Product").Preload("Variant").First(&item, "id = ?", item.ID)
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Already in your wishlist",
+			"item":    item,
+		})
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wishlist"})
+		return
+	}
+
+	item = models.WishlistItem{
+		UserID:         userID,
+		ProductID:      product.ID,
+		VariantID:      variantID,
+		PriceWhenAdded: price,
+	}
+	if err := db.Omit("Product", "Variant").Create(&item).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wishlist"})
+		return
+	}
+	db.Preload("Product").Preload("Variant").First(&item, "id = ?", item.ID)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Saved to wishlist",
+		"item":    item,
+	})
+}
+
+// RemoveFromWishlist godoc
+// @Summary Remove a product from my wishlist
+// @Tags Wishlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param productId path string true "Product ID"
+// @Success 200 {object} map[string]interface{} "Removed from wishlist"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product not in wishlist"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /wishlist/{productId} [delete]
+func RemoveFromWishlist(c *gin.Context) {
+	if _, err := uuid.Parse(c.Param("productId")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not in wishlist"})
+		return
+	}
+	result := config.GetDB().Where("user_id = ? AND product_id = ?", c.GetString("user_id"), c.Param("productId")).
+		Delete(&models.WishlistItem{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update wishlist"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not in wishlist"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Removed from wishlist"})
+}
+
+// MoveWishlistItemToCart godoc
+// @Summary Move a wishlist item to the cart
+// @Description Add a saved product to the cart, in the saved pack size unless another is given, and take it off the wishlist. The usual cart checks on stock and prescriptions apply.
+// @Tags Wishlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param productId path string true "Product ID"
+// @Param request body MoveToCartRequest false "Pack size and quantity"
+// @Success 200 {object} map[string]interface{} "Moved to cart"
+// @Failure 400 {object} map[string]interface{} "Bad request or insufficient stock"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 403 {object} map[string]interface{} "Prescription diet without an approved prescription"
+// @Failure 404 {object} map[string]interface{} "Product not in wishlist or no longer sold"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /wishlist/{productId}/move-to-cart [post]
+func MoveWishlistItemToCart(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var req MoveToCartRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if req.Quantity == 0 {
+		req.Quantity = 1
+	}
+
+	var item models.WishlistItem
+	if _, err := uuid.Parse(c.Param("productId")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not in wishlist"})
+		return
+	}
+	if err := db.Where("user_id = ? AND product_id = ?", userID, c.Param("productId")).First(&item).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not in wishlist"})
+		return
+	}
+	if req.VariantID == "" && item.VariantID != nil {
+		req.VariantID = item.VariantID.String()
+	}
+
+	var cartItem models.Cart
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if cartItem, _, err = addCartItem(tx, userID, item.ProductID.String(), req.VariantID, req.Quantity); err != nil {
+			return err
+		}
+		return tx.Delete(&item).Error
+	})
+	if err != nil {
+		cartItemError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Moved to cart",
+		"cart_item": cartItem,
+	})
+}
+
+// GetProductAlerts godoc
+// @Summary Get my product alerts
+// @Description List the back in stock and price drop alerts the current user is waiting on
+// @Tags Wishlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]interface{} "Alerts"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /alerts [get]
+func GetProductAlerts(c *gin.Context) {
+	var alerts []models.ProductAlert
+	if err := config.GetDB().Preload("Product", withArchived).
+		Where("user_id = ?", c.GetString("user_id")).Order("created_at DESC").Find(&alerts).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch alerts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"alerts": alerts})
+}
+
+// CreateProductAlert godoc
+// @Summary Subscribe to a product alert
+// @Description Get a notification when a sold out product is back in stock (once), or whenever its price drops below the last price you were told about. Subscribing again changes nothing.
+// @Tags Wishlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body ProductAlertRequest true "Product and alert type"
+// @Success 201 {object} map[string]interface{} "Alert created"
+// @Success 200 {object} map[string]interface{} "Already subscribed"
+// @Failure 400 {object} map[string]interface{} "Bad request"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Product not found"
+// @Failure 409 {object} map[string]interface{} "Product is in stock"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /alerts [post]
+func CreateProductAlert(c *gin.Context) {
+	db := config.GetDB()
+	userID, _ := uuid.Parse(c.GetString("user_id"))
+
+	var req ProductAlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var product models.Product
+	if err := db.Scopes(purchasableProducts).Where("id = ?", req.ProductID).First(&product).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Product not found"})
+		return
+	}
+
+	var alert models.ProductAlert
+	err := db.Where("user_id = ? AND product_id = ? AND type = ?", userID, product.ID, req.Type).First(&alert).Error
+	if err == nil {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Already subscribed",
+			"alert":   alert,
+		})
+		return
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert"})
+		return
+	}
+	if req.Type == models.ProductAlertBackInStock && product.Stock > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": errAlertInStock.Error()})
+		return
+	}
+
+	alert = models.ProductAlert{
+		UserID:    userID,
+		ProductID: product.ID,
+		Type:      req.Type,
+		Price:     product.Price,
+	}
+	if err := db.Omit("Product").Create(&alert).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create alert"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Alert created",
+		"alert":   alert,
+	})
+}
+
+// DeleteProductAlert godoc
+// @Summary Unsubscribe from a product alert
+// @Tags Wishlist
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Alert ID"
+// @Success 200 {object} map[string]interface{} "Alert removed"
+// @Failure 401 {object} map[string]interface{} "Unauthorized"
+// @Failure 404 {object} map[string]interface{} "Alert not found"
+// @Failure 500 {object} map[string]interface{} "Internal server error"
+// @Router /alerts/{id} [delete]
+func DeleteProductAlert(c *gin.Context) {
+	if _, err := uuid.Parse(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+	result := config.GetDB().Where("id = ? AND user_id = ?", c.Param("id"), c.GetString("user_id")).
+		Delete(&models.ProductAlert{})
+	if result.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove alert"})
+		return
+	}
+	if result.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Alert not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Alert removed"})
+}
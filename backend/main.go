@@ -5,8 +5,11 @@ import (
 	"log"
 	"os"
 	"pet-food-ecommerce/config"
+	"pet-food-ecommerce/controllers"
 	"pet-food-ecommerce/models"
 	"pet-food-ecommerce/routes"
+	"pet-food-ecommerce/search"
+	"pet-food-ecommerce/storage"
 
 	_ "pet-food-ecommerce/docs"
 
@@ -53,13 +56,71 @@ func main() {
 		&models.Cart{},
 		&models.Order{},
 		&models.OrderItem{},
+		&models.Coupon{},
+		&models.CouponRedemption{},
+		&models.CartCoupon{},
+		&models.Promotion{},
+		&models.PromotionTier{},
+		&models.FlashSale{},
+		&models.OrderPayment{},
+		&models.WalletEntry{},
+		&models.GiftCard{},
+		&models.GiftCardTransaction{},
+		&models.LoyaltyEntry{},
+		&models.TierPrice{},
+		&models.Referral{},
+		&models.ProductVariant{},
+		&models.Pet{},
+		&models.Subscription{},
+		&models.SubscriptionRun{},
+		&models.Prescription{},
+		&models.Image{},
+		&models.Review{},
+		&models.ReviewVote{},
+		&models.ProductQuestion{},
+		&models.ProductAnswer{},
+		&models.Notification{},
+		&models.WishlistItem{},
+		&models.ProductAlert{},
+		&models.ProductPairing{},
 	)
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	// Give products from before variants their default variant
+	if err := models.MigrateProductVariants(db); err != nil {
+		log.Fatal("Failed to migrate product variants:", err)
+	}
+
+	// Give categories from before slugs their slug
+	if err := models.MigrateCategorySlugs(db); err != nil {
+		log.Fatal("Failed to migrate category slugs:", err)
+	}
+
+	// Apply delete rules to foreign keys created without them
+	if err := models.MigrateForeignKeys(db); err != nil {
+		log.Fatal("Failed to migrate foreign keys:", err)
+	}
+
+	// Create the product search index and index products missing from it
+	if err := search.Setup(db); err != nil {
+		log.Fatal("Failed to set up product search:", err)
+	}
+
+	// Keep uploaded images on local disk or in an S3 bucket
+	if err := storage.Setup(); err != nil {
+		log.Fatal("Failed to set up file storage:", err)
+	}
+
 	fmt.Println("Database migration completed!")
 
+	// Place autoship orders as they fall due
+	go controllers.StartSubscriptionScheduler(db)
+
+	// Rebuild product recommendations from order history
+	go controllers.StartRecommendationJob(db)
+
 	// Create Gin router
 	router := gin.Default()
 
@@ -68,7 +129,7 @@ func main() {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
 		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
-		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE")
+		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, PATCH, DELETE")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -66,3 +66,16 @@ func AdminMiddleware() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// VetMiddleware lets vets and admins through, for reviewing prescriptions
+func VetMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role, exists := c.Get("user_role")
+		if !exists || (role != "vet" && role != "admin") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Vet or admin access required"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -8,13 +8,15 @@ import (
 )
 
 type Cart struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID    uuid.UUID `gorm:"type:uuid;not null" json:"user_id"`
-	User      User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	ProductID uuid.UUID `gorm:"type:uuid;not null" json:"product_id"`
-	Product   Product   `gorm:"foreignKey:ProductID" json:"product,omitempty"`
-	Quantity  int       `gorm:"not null;default:1" json:"quantity"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
+	User      User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	ProductID uuid.UUID      `gorm:"type:uuid;not null" json:"product_id"`
+	Product   Product        `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"product,omitempty"`
+	VariantID *uuid.UUID     `gorm:"type:uuid;index" json:"variant_id"`
+	Variant   ProductVariant `gorm:"foreignKey:VariantID;constraint:OnDelete:CASCADE" json:"variant,omitempty"`
+	Quantity  int            `gorm:"not null;default:1" json:"quantity"`
+	CreatedAt time.Time      `json:"created_at"`
 }
 
 func (c *Cart) BeforeCreate(tx *gorm.DB) error {
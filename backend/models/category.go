@@ -1,18 +1,29 @@
 package models
 
 import (
+	"fmt"
+	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
 )
 
+// Category is a node in the category tree. Deleting one archives it; its slug
+// stays reserved so old links never point at a different category.
 type Category struct {
-	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	ImageURL    string    `json:"image_url"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID          uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ParentID    *uuid.UUID     `gorm:"type:uuid;index" json:"parent_id"` // nil for top-level categories
+	Parent      *Category      `gorm:"foreignKey:ParentID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"-"`
+	Name        string         `gorm:"not null" json:"name"`
+	Slug        string         `gorm:"uniqueIndex" json:"slug"`            // URL name, unique across the tree
+	Position    int            `gorm:"not null;default:0" json:"position"` // order among siblings
+	Description string         `json:"description"`
+	ImageURL    string         `json:"image_url"` // cover image, the first of Images once any are uploaded
+	Images      []Image        `gorm:"polymorphic:Owner;polymorphicValue:categories" json:"images,omitempty"`
+	CreatedAt   time.Time      `json:"created_at"`
+	ArchivedAt  gorm.DeletedAt `gorm:"index" json:"archived_at"`
 }
 
 func (c *Category) BeforeCreate(tx *gorm.DB) error {
@@ -21,3 +32,57 @@ func (c *Category) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// Slugify turns a name into a URL slug: lower-case letters and digits in any
+// script, with runs of anything else collapsed to a hyphen
+func Slugify(name string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) {
+			b.WriteRune(r)
+			hyphen = false
+		} else if !hyphen && b.Len() > 0 {
+			b.WriteByte('-')
+			hyphen = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// UniqueCategorySlug returns base, or base with a number appended, that no
+// category other than exceptID uses yet, archived ones included
+func UniqueCategorySlug(db *gorm.DB, base string, exceptID uuid.UUID) (string, error) {
+	if base == "" {
+		base = "category"
+	}
+	slug := base
+	for n := 2; ; n++ {
+		var count int64
+		if err := db.Unscoped().Model(&Category{}).Where("slug = ? AND id <> ?", slug, exceptID).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return slug, nil
+		}
+		slug = fmt.Sprintf("%s-%d", base, n)
+	}
+}
+
+// MigrateCategorySlugs gives categories from before slugs one made from their name
+func MigrateCategorySlugs(db *gorm.DB) error {
+	var categories []Category
+	if err := db.Where("slug IS NULL OR slug = ''").Order("created_at").Find(&categories).Error; err != nil {
+		return err
+	}
+	for _, category := range categories {
+		slug, err := UniqueCategorySlug(db, Slugify(category.Name), category.ID)
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&Category{}).Where("id = ?", category.ID).Update("slug", slug).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Coupon types
+const (
+	CouponTypePercentage   = "percentage"
+	CouponTypeFixed        = "fixed"
+	CouponTypeFreeShipping = "free_shipping"
+)
+
+type Coupon struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Code         string     `gorm:"uniqueIndex;not null" json:"code"`
+	Description  string     `json:"description"`
+	Type         string     `gorm:"not null" json:"type"`                     // percentage, fixed, free_shipping
+	PercentOff   int        `gorm:"not null;default:0" json:"percent_off"`    // 1-100, percentage coupons only
+	AmountOff    Money      `gorm:"not null;default:0" json:"amount_off"`     // fixed coupons only
+	MaxDiscount  Money      `gorm:"not null;default:0" json:"max_discount"`   // cap for percentage coupons, 0 = no cap
+	MinSpend     Money      `gorm:"not null;default:0" json:"min_spend"`      // checked against the eligible subtotal
+	UsageLimit   int        `gorm:"not null;default:0" json:"usage_limit"`    // total redemptions, 0 = unlimited
+	PerUserLimit int        `gorm:"not null;default:0" json:"per_user_limit"` // redemptions per user, 0 = unlimited
+	UsedCount    int        `gorm:"not null;default:0" json:"used_count"`
+	StartsAt     *time.Time `json:"starts_at"`
+	EndsAt       *time.Time `json:"ends_at"`
+	Active       bool       `gorm:"not null" json:"active"`
+	Categories   []Category `gorm:"many2many:coupon_categories" json:"categories,omitempty"` // restrict to these categories
+	Products     []Product  `gorm:"many2many:coupon_products" json:"products,omitempty"`     // restrict to these products
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// CouponRedemption records each use of a coupon by an order
+type CouponRedemption struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	CouponID  uuid.UUID `gorm:"type:uuid;not null;index" json:"coupon_id"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index" json:"user_id"`
+	OrderID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex" json:"order_id"`
+	Discount  Money     `gorm:"not null" json:"discount"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CartCoupon is the coupon a user has applied to their current cart
+type CartCoupon struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primary_key" json:"user_id"`
+	CouponID  uuid.UUID `gorm:"type:uuid;not null" json:"coupon_id"`
+	Coupon    Coupon    `gorm:"foreignKey:CouponID" json:"coupon,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AppliesTo reports whether a product falls under the coupon's restrictions.
+// categoryIDs holds the product's category and every category above it, so a
+// coupon on a parent category covers the products in its subcategories.
+func (c *Coupon) AppliesTo(product *Product, categoryIDs []uuid.UUID) bool {
+	if len(c.Products) == 0 && len(c.Categories) == 0 {
+		return true
+	}
+	for _, p := range c.Products {
+		if p.ID == product.ID {
+			return true
+		}
+	}
+	for _, cat := range c.Categories {
+		if slices.Contains(categoryIDs, cat.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Coupon) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+func (r *CouponRedemption) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
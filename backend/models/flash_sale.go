@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// FlashSale is a time-boxed sale price for a product
+type FlashSale struct {
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ProductID        uuid.UUID  `gorm:"type:uuid;not null;index" json:"product_id"`
+	VariantID        *uuid.UUID `gorm:"type:uuid;index" json:"variant_id,omitempty"` // nil = every variant
+	Product          Product    `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	Name             string     `json:"name"`
+	SalePrice        Money      `gorm:"not null" json:"sale_price"`
+	StartsAt         time.Time  `gorm:"not null;index" json:"starts_at"`
+	EndsAt           time.Time  `gorm:"not null;index" json:"ends_at"`
+	PerCustomerLimit int        `gorm:"not null;default:0" json:"per_customer_limit"` // units per customer, 0 = unlimited
+	StockLimit       int        `gorm:"not null;default:0" json:"stock_limit"`        // units sold at the sale price, 0 = unlimited
+	SoldCount        int        `gorm:"not null;default:0" json:"sold_count"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// CoversVariant reports whether the sale applies to the given variant
+func (s *FlashSale) CoversVariant(variantID *uuid.UUID) bool {
+	return s.VariantID == nil || (variantID != nil && *s.VariantID == *variantID)
+}
+
+// IsRunning reports whether the sale window covers the given time
+func (s *FlashSale) IsRunning(now time.Time) bool {
+	return !now.Before(s.StartsAt) && now.Before(s.EndsAt)
+}
+
+// Remaining returns the units still available at the sale price, or -1 if unlimited
+func (s *FlashSale) Remaining() int {
+	if s.StockLimit == 0 {
+		return -1
+	}
+	if s.SoldCount >= s.StockLimit {
+		return 0
+	}
+	return s.StockLimit - s.SoldCount
+}
+
+func (s *FlashSale) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// foreignKeys are the relations whose delete rules matter: catalog rows that
+// order history points at are restricted, cart rows go with what they hold
+var foreignKeys = []struct {
+	model interface{}
+	field string
+}{
+	{&Product{}, "Category"},
+	{&Product{}, "Variants"},
+	{&Category{}, "Parent"},
+	{&Cart{}, "Product"},
+	{&Cart{}, "Variant"},
+	{&OrderItem{}, "Product"},
+}
+
+// MigrateForeignKeys recreates foreign keys that AutoMigrate made before
+// their delete rules were set, since it never alters an existing constraint.
+// Only Postgres needs this; SQLite does not enforce them for local testing.
+func MigrateForeignKeys(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	for _, fk := range foreignKeys {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(fk.model); err != nil {
+			return err
+		}
+		rel, ok := stmt.Schema.Relationships.Relations[fk.field]
+		if !ok {
+			return fmt.Errorf("%s has no relation %s", stmt.Schema.Name, fk.field)
+		}
+		constraint := rel.ParseConstraint()
+		if constraint == nil {
+			continue
+		}
+
+		var rule string
+		if err := db.Raw("SELECT delete_rule FROM information_schema.referential_constraints WHERE constraint_name = ?",
+			constraint.Name).Scan(&rule).Error; err != nil {
+			return err
+		}
+		if rule == constraint.OnDelete {
+			continue
+		}
+		if rule != "" {
+			if err := db.Migrator().DropConstraint(fk.model, fk.field); err != nil {
+				return err
+			}
+		}
+		if err := db.Migrator().CreateConstraint(fk.model, fk.field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Gift card transaction types
+const (
+	GiftCardTxIssue  = "issue"
+	GiftCardTxRedeem = "redeem"
+	GiftCardTxRefund = "refund"
+	GiftCardTxAdjust = "adjust"
+	GiftCardTxStatus = "status" // activation or expiry change, no balance movement
+)
+
+type GiftCard struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Code           string     `gorm:"uniqueIndex;not null" json:"code"`
+	InitialBalance Money      `gorm:"not null" json:"initial_balance"`
+	Balance        Money      `gorm:"not null" json:"balance"`
+	Currency       string     `gorm:"size:3;not null;default:'THB'" json:"currency"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	Active         bool       `gorm:"not null" json:"active"`
+	RecipientEmail string     `json:"recipient_email,omitempty"`
+	Note           string     `json:"note,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// GiftCardTransaction is the audit trail of every balance change on a gift card
+type GiftCardTransaction struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	GiftCardID uuid.UUID  `gorm:"type:uuid;not null;index" json:"gift_card_id"`
+	Type       string     `gorm:"not null" json:"type"`    // issue, redeem, refund, adjust
+	Amount     Money      `gorm:"not null" json:"amount"`  // signed change to the balance
+	Balance    Money      `gorm:"not null" json:"balance"` // balance after the change
+	OrderID    *uuid.UUID `gorm:"type:uuid;index" json:"order_id,omitempty"`
+	ActorID    *uuid.UUID `gorm:"type:uuid" json:"actor_id,omitempty"` // admin or customer who made the change
+	Reason     string     `json:"reason"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// IsUsable reports whether the card can pay for an order at the given time
+func (g *GiftCard) IsUsable(now time.Time) bool {
+	if !g.Active || g.Balance <= 0 {
+		return false
+	}
+	return g.ExpiresAt == nil || now.Before(*g.ExpiresAt)
+}
+
+func (g *GiftCard) BeforeCreate(tx *gorm.DB) error {
+	if g.ID == uuid.Nil {
+		g.ID = uuid.New()
+	}
+	if g.Currency == "" {
+		g.Currency = DefaultCurrency
+	}
+	return nil
+}
+
+func (t *GiftCardTransaction) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Image owner types, also the folder their files are stored in
+const (
+	ImageOwnerProduct  = "products"
+	ImageOwnerCategory = "categories"
+	ImageOwnerReview   = "reviews"
+)
+
+// Thumbnail is a resized copy of an image, encoded as JPEG and, in builds with
+// cgo, also as WebP
+type Thumbnail struct {
+	Size    string `json:"size"` // small, medium
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	WebPURL string `json:"webp_url,omitempty"`
+	JPEGURL string `json:"jpeg_url"`
+}
+
+// Image is a picture in a product's or category's gallery, shown in Position
+// order; the first one is the owner's cover ImageURL.
+type Image struct {
+	ID          uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OwnerID     uuid.UUID   `gorm:"type:uuid;not null;index:idx_images_owner" json:"owner_id"`
+	OwnerType   string      `gorm:"not null;index:idx_images_owner" json:"owner_type"` // products, categories or reviews
+	URL         string      `gorm:"not null" json:"url"`                               // the original upload
+	Thumbnails  []Thumbnail `gorm:"type:text;serializer:json" json:"thumbnails"`
+	Files       []string    `gorm:"type:text;serializer:json" json:"-"` // storage keys of the original and thumbnails
+	FileName    string      `json:"file_name"`                          // name the file was uploaded with
+	ContentType string      `gorm:"not null" json:"content_type"`
+	FileSize    int64       `gorm:"not null" json:"file_size"`
+	Width       int         `gorm:"not null" json:"width"`
+	Height      int         `gorm:"not null" json:"height"`
+	AltText     string      `json:"alt_text"`
+	Position    int         `gorm:"not null;default:0" json:"position"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+}
+
+func (i *Image) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Member tiers, computed from the last 12 months' delivered spend
+const (
+	TierMember = "member"
+	TierSilver = "silver"
+	TierGold   = "gold"
+)
+
+// Loyalty entry types
+const (
+	LoyaltyEntryEarn     = "earn"
+	LoyaltyEntryBurn     = "burn"
+	LoyaltyEntryReverse  = "reverse" // earned points taken back after cancel/refund
+	LoyaltyEntryRestore  = "restore" // burned points given back after cancel/refund
+	LoyaltyEntryExpire   = "expire"
+	LoyaltyEntryAdjust   = "adjust"
+	LoyaltyEntryReferral = "referral" // reward for referring a customer
+)
+
+// TierRank orders tiers so higher tiers also get lower tiers' prices
+func TierRank(tier string) int {
+	switch tier {
+	case TierSilver:
+		return 1
+	case TierGold:
+		return 2
+	}
+	return 0
+}
+
+// LoyaltyEntry is one line of a user's points ledger. Entries that add points keep
+// their unspent Remaining so burns can consume them soonest-expiring first.
+type LoyaltyEntry struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Type      string     `gorm:"not null" json:"type"`   // earn, burn, reverse, restore, expire, adjust, referral
+	Points    int        `gorm:"not null" json:"points"` // signed change
+	Remaining int        `gorm:"not null;default:0" json:"remaining"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	OrderID   *uuid.UUID `gorm:"type:uuid;index" json:"order_id,omitempty"`
+	ActorID   *uuid.UUID `gorm:"type:uuid" json:"actor_id,omitempty"`
+	Reason    string     `json:"reason"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TierPrice is a member-only price for a product
+type TierPrice struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ProductID uuid.UUID  `gorm:"type:uuid;not null;index" json:"product_id"`
+	VariantID *uuid.UUID `gorm:"type:uuid;uniqueIndex:idx_tier_price_variant_tier" json:"variant_id,omitempty"` // nil = every variant
+	Tier      string     `gorm:"not null;uniqueIndex:idx_tier_price_variant_tier" json:"tier"`                  // silver, gold
+	Price     Money      `gorm:"not null" json:"price"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// CoversVariant reports whether the tier price applies to the given variant
+func (t *TierPrice) CoversVariant(variantID *uuid.UUID) bool {
+	return t.VariantID == nil || (variantID != nil && *t.VariantID == *variantID)
+}
+
+func (l *LoyaltyEntry) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == uuid.Nil {
+		l.ID = uuid.New()
+	}
+	return nil
+}
+
+func (t *TierPrice) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
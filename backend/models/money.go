@@ -0,0 +1,166 @@
+package models
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// DefaultCurrency is the ISO 4217 code used for all catalog prices
+const DefaultCurrency = "THB"
+
+// VATRateBasisPoints is the Thai VAT rate (7%) included in shelf prices
+const VATRateBasisPoints = 700
+
+// Money is an exact amount in minor units (satang for THB).
+// It is stored as numeric(12,2) and serialized to JSON as a plain number,
+// so existing clients that read "price": 1599.5 keep working.
+type Money int64
+
+var errInvalidMoney = errors.New("invalid money amount")
+
+// ParseMoney parses a decimal string such as "1599.50" without going through float64
+func ParseMoney(s string) (Money, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, errInvalidMoney
+	}
+
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, errInvalidMoney
+	}
+
+	r.Mul(r, big.NewRat(100, 1))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("%w: at most 2 decimal places allowed", errInvalidMoney)
+	}
+	if !r.Num().IsInt64() {
+		return 0, fmt.Errorf("%w: out of range", errInvalidMoney)
+	}
+
+	return Money(r.Num().Int64()), nil
+}
+
+// MoneyFromFloat converts a float amount in major units, rounding to the nearest satang
+func MoneyFromFloat(f float64) Money {
+	return Money(math.Round(f * 100))
+}
+
+// Mul returns the amount multiplied by a quantity
+func (m Money) Mul(qty int) Money {
+	return m * Money(qty)
+}
+
+// Percent returns the given share of the amount in basis points (1% = 100),
+// rounded half away from zero
+func (m Money) Percent(basisPoints int64) Money {
+	return Money(roundDiv(int64(m)*basisPoints, 10000))
+}
+
+// IncludedTax returns the tax portion already contained in a tax-inclusive amount
+func (m Money) IncludedTax(basisPoints int64) Money {
+	return Money(roundDiv(int64(m)*basisPoints, 10000+basisPoints))
+}
+
+// Min returns the smaller of two amounts
+func (m Money) Min(o Money) Money {
+	if o < m {
+		return o
+	}
+	return m
+}
+
+// Float64 returns the amount in major units; use only for display or statistics
+func (m Money) Float64() float64 {
+	return float64(m) / 100
+}
+
+// String formats the amount with two decimal places, e.g. "1599.50"
+func (m Money) String() string {
+	sign := ""
+	v := int64(m)
+	if v < 0 {
+		sign = "-"
+		v = -v
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, v/100, v%100)
+}
+
+// MarshalJSON writes the amount as a JSON number
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalJSON accepts both JSON numbers and numeric strings
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// Scan implements sql.Scanner
+func (m *Money) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*m = 0
+	case int64:
+		*m = Money(v * 100)
+	case float64:
+		*m = MoneyFromFloat(v)
+	case []byte:
+		parsed, err := ParseMoney(string(v))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	case string:
+		parsed, err := ParseMoney(v)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+	default:
+		return fmt.Errorf("cannot scan %T into Money", value)
+	}
+	return nil
+}
+
+// Value implements driver.Valuer
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+// GormDBDataType makes AutoMigrate convert the old float columns to an exact decimal
+func (Money) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	return "numeric(12,2)"
+}
+
+// roundDiv divides a by b, rounding half away from zero
+func roundDiv(a, b int64) int64 {
+	if b < 0 {
+		a, b = -a, -b
+	}
+	if a >= 0 {
+		return (a + b/2) / b
+	}
+	return -((-a + b/2) / b)
+}
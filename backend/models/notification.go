@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Notification types
+const (
+	NotificationQuestionAnswered = "question_answered"
+	NotificationBackInStock      = "back_in_stock"
+	NotificationPriceDrop        = "price_drop"
+)
+
+// Notification is a message for a user, shown in their inbox until read
+type Notification struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index:idx_notification_user" json:"user_id"`
+	Type      string     `gorm:"not null" json:"type"`
+	Title     string     `gorm:"not null" json:"title"`
+	Body      string     `json:"body"`
+	Link      string     `json:"link,omitempty"` // storefront path it is about, e.g. /products/{id}
+	ReadAt    *time.Time `json:"read_at,omitempty"`
+	CreatedAt time.Time  `gorm:"index:idx_notification_user" json:"created_at"`
+}
+
+func (n *Notification) BeforeCreate(tx *gorm.DB) error {
+	if n.ID == uuid.Nil {
+		n.ID = uuid.New()
+	}
+	return nil
+}
@@ -8,30 +8,80 @@ import (
 )
 
 type Order struct {
-	ID              uuid.UUID   `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	UserID          uuid.UUID   `gorm:"type:uuid;not null" json:"user_id"`
-	User            User        `gorm:"foreignKey:UserID" json:"user,omitempty"`
-	TotalAmount     float64     `gorm:"not null" json:"total_amount"`
-	Status          string      `gorm:"default:'pending'" json:"status"` // pending, processing, shipped, delivered, cancelled
-	ShippingAddress string      `gorm:"not null" json:"shipping_address"`
-	OrderItems      []OrderItem `gorm:"foreignKey:OrderID" json:"order_items,omitempty"`
-	CreatedAt       time.Time   `json:"created_at"`
-	UpdatedAt       time.Time   `json:"updated_at"`
+	ID                 uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID             uuid.UUID      `gorm:"type:uuid;not null" json:"user_id"`
+	User               User           `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	SubtotalAmount     Money          `gorm:"not null;default:0" json:"subtotal_amount"`
+	DiscountAmount     Money          `gorm:"not null;default:0" json:"discount_amount"` // promotion + subscriber + coupon + referral + points discounts; flash sale and member prices are already in the subtotal
+	PromotionDiscount  Money          `gorm:"not null;default:0" json:"promotion_discount"`
+	CouponDiscount     Money          `gorm:"not null;default:0" json:"coupon_discount"`
+	ReferralDiscount   Money          `gorm:"not null;default:0" json:"referral_discount"`   // referee first-order discount
+	SubscriberDiscount Money          `gorm:"not null;default:0" json:"subscriber_discount"` // autoship discount
+	PointsRedeemed     int            `gorm:"not null;default:0" json:"points_redeemed"`
+	PointsDiscount     Money          `gorm:"not null;default:0" json:"points_discount"`
+	PointsEarned       int            `gorm:"not null;default:0" json:"points_earned"` // awarded on delivery
+	ShippingFee        Money          `gorm:"not null;default:0" json:"shipping_fee"`
+	TaxAmount          Money          `gorm:"not null;default:0" json:"tax_amount"` // VAT included in the total
+	TotalAmount        Money          `gorm:"not null" json:"total_amount"`
+	Currency           string         `gorm:"size:3;not null;default:'THB'" json:"currency"`
+	Status             string         `gorm:"default:'pending'" json:"status"` // pending, processing, shipped, delivered, cancelled, refunded
+	ShippingAddress    string         `gorm:"not null" json:"shipping_address"`
+	CouponID           *uuid.UUID     `gorm:"type:uuid" json:"coupon_id,omitempty"`
+	CouponCode         string         `json:"coupon_code,omitempty"`
+	SubscriptionID     *uuid.UUID     `gorm:"type:uuid;index" json:"subscription_id,omitempty"` // autoship that placed the order
+	PaymentMethod      string         `gorm:"not null;default:'cod'" json:"payment_method"`     // method for the amount due
+	AmountDue          Money          `gorm:"not null;default:0" json:"amount_due"`             // total minus store credit and gift cards
+	Payments           []OrderPayment `gorm:"foreignKey:OrderID" json:"payments,omitempty"`
+	OrderItems         []OrderItem    `gorm:"foreignKey:OrderID" json:"order_items,omitempty"`
+	DeliveredAt        *time.Time     `json:"delivered_at,omitempty"`
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
 }
 
 type OrderItem struct {
-	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	OrderID   uuid.UUID `gorm:"type:uuid;not null" json:"order_id"`
-	ProductID uuid.UUID `gorm:"type:uuid;not null" json:"product_id"`
-	Product   Product   `gorm:"foreignKey:ProductID" json:"product,omitempty"`
-	Quantity  int       `gorm:"not null" json:"quantity"`
-	Price     float64   `gorm:"not null" json:"price"` // Price at time of purchase
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrderID        uuid.UUID  `gorm:"type:uuid;not null" json:"order_id"`
+	ProductID      uuid.UUID  `gorm:"type:uuid;not null" json:"product_id"`
+	Product        Product    `gorm:"foreignKey:ProductID;constraint:OnDelete:RESTRICT" json:"product,omitempty"`
+	VariantID      *uuid.UUID `gorm:"type:uuid;index" json:"variant_id,omitempty"`
+	SKU            string     `json:"sku,omitempty"`          // Variant SKU at time of purchase
+	VariantName    string     `json:"variant_name,omitempty"` // Variant name at time of purchase
+	Quantity       int        `gorm:"not null" json:"quantity"`
+	Price          Money      `gorm:"not null" json:"price"`                      // Price at time of purchase
+	Discount       Money      `gorm:"not null;default:0" json:"discount"`         // Automatic promotions on this line
+	Promotion      string     `json:"promotion,omitempty"`                        // Names of the applied promotions
+	SaleID         *uuid.UUID `gorm:"type:uuid;index" json:"sale_id,omitempty"`   // Flash sale the price came from
+	PrescriptionID *uuid.UUID `gorm:"type:uuid" json:"prescription_id,omitempty"` // Approval a prescription diet was sold under
+}
+
+// Payment methods
+const (
+	PaymentMethodCOD          = "cod"
+	PaymentMethodBankTransfer = "bank_transfer"
+	PaymentMethodPromptPay    = "promptpay"
+	PaymentMethodCard         = "card"
+	PaymentMethodStoreCredit  = "store_credit"
+	PaymentMethodGiftCard     = "gift_card"
+)
+
+// OrderPayment is one tender of a (possibly split) order payment
+type OrderPayment struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	OrderID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"order_id"`
+	Method     string     `gorm:"not null" json:"method"`
+	Amount     Money      `gorm:"not null" json:"amount"`
+	GiftCardID *uuid.UUID `gorm:"type:uuid" json:"gift_card_id,omitempty"`
+	Refunded   bool       `gorm:"not null;default:false" json:"refunded"`
+	CreatedAt  time.Time  `json:"created_at"`
 }
 
 func (o *Order) BeforeCreate(tx *gorm.DB) error {
 	if o.ID == uuid.Nil {
 		o.ID = uuid.New()
 	}
+	if o.Currency == "" {
+		o.Currency = DefaultCurrency
+	}
 	return nil
 }
 
@@ -41,3 +91,10 @@ func (oi *OrderItem) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+func (p *OrderPayment) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Activity levels, used to size daily portions
+const (
+	ActivityLow    = "low"
+	ActivityNormal = "normal"
+	ActivityHigh   = "high"
+)
+
+var ActivityLevelValues = []string{ActivityLow, ActivityNormal, ActivityHigh}
+
+// Pet is a customer's pet, used to recommend food that suits it
+type Pet struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Name      string     `gorm:"not null" json:"name"`
+	Species   string     `gorm:"not null" json:"species"` // dog, cat, ...
+	Breed     string     `json:"breed"`
+	BreedSize string     `json:"breed_size"` // toy, small, medium, large, giant; worked out from weight when empty
+	Birthdate *time.Time `gorm:"type:date" json:"birthdate,omitempty"`
+	WeightKg  float64    `json:"weight_kg"`
+	Activity  string     `gorm:"not null;default:'normal'" json:"activity_level"` // low, normal, high
+	Allergies []string   `gorm:"type:text;serializer:json" json:"allergies"`      // lower case, matched against product allergens
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// AgeMonths is the pet's age in whole months, or -1 without a birthdate
+func (p *Pet) AgeMonths(now time.Time) int {
+	if p.Birthdate == nil {
+		return -1
+	}
+	born := *p.Birthdate
+	months := (now.Year()-born.Year())*12 + int(now.Month()-born.Month())
+	if now.Day() < born.Day() {
+		months--
+	}
+	if months < 0 {
+		return 0
+	}
+	return months
+}
+
+// Size is the pet's breed size, from its weight for adult dogs when not set
+func (p *Pet) Size(now time.Time) string {
+	if p.BreedSize != "" || p.Species != SpeciesDog || p.WeightKg <= 0 {
+		return p.BreedSize
+	}
+	if age := p.AgeMonths(now); age >= 0 && age < 12 {
+		return "" // a puppy's weight says little about its adult size
+	}
+	switch {
+	case p.WeightKg < 4:
+		return BreedSizeToy
+	case p.WeightKg < 10:
+		return BreedSizeSmall
+	case p.WeightKg < 25:
+		return BreedSizeMedium
+	case p.WeightKg < 45:
+		return BreedSizeLarge
+	}
+	return BreedSizeGiant
+}
+
+// LifeStage is the pet's life stage from its age; large dogs grow up later and
+// age sooner. Empty when the birthdate or species does not tell.
+func (p *Pet) LifeStage(now time.Time) string {
+	age := p.AgeMonths(now)
+	if age < 0 {
+		return ""
+	}
+
+	switch p.Species {
+	case SpeciesDog:
+		size := p.Size(now)
+		big := size == BreedSizeLarge || size == BreedSizeGiant
+		switch {
+		case age < 12, big && age < 18:
+			return LifeStagePuppy
+		case age >= 7*12, big && age >= 6*12:
+			return LifeStageSenior
+		}
+		return LifeStageAdult
+	case SpeciesCat:
+		switch {
+		case age < 12:
+			return LifeStageKitten
+		case age >= 11*12:
+			return LifeStageSenior
+		}
+		return LifeStageAdult
+	}
+	return ""
+}
+
+// DailyCalories is the pet's daily energy need in kcal: the resting need
+// 70 x kg^0.75 times a factor for its life stage and activity. Zero for species
+// without a feeding guide or a pet without a weight.
+func (p *Pet) DailyCalories(now time.Time) float64 {
+	if p.WeightKg <= 0 {
+		return 0
+	}
+	resting := 70 * math.Pow(p.WeightKg, 0.75)
+
+	var factor float64
+	switch p.Species {
+	case SpeciesDog:
+		factor = map[string]float64{ActivityLow: 1.4, ActivityNormal: 1.6, ActivityHigh: 2.0}[p.activity()]
+		switch p.LifeStage(now) {
+		case LifeStagePuppy:
+			factor = 2.0
+			if p.AgeMonths(now) < 4 {
+				factor = 3.0
+			}
+		case LifeStageSenior:
+			factor = 1.4
+		}
+	case SpeciesCat:
+		factor = map[string]float64{ActivityLow: 1.0, ActivityNormal: 1.2, ActivityHigh: 1.6}[p.activity()]
+		switch p.LifeStage(now) {
+		case LifeStageKitten:
+			factor = 2.5
+		case LifeStageSenior:
+			factor = 1.1
+		}
+	}
+	return resting * factor
+}
+
+func (p *Pet) activity() string {
+	if p.Activity == "" {
+		return ActivityNormal
+	}
+	return p.Activity
+}
+
+func (p *Pet) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
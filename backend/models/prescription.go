@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Prescription review statuses
+const (
+	PrescriptionPending  = "pending"
+	PrescriptionApproved = "approved"
+	PrescriptionRejected = "rejected"
+)
+
+// Prescription is a vet prescription a customer uploaded for one of their pets.
+// Once approved it lets the customer buy prescription diets until ValidUntil:
+// the product it names, or any prescription diet when it names none.
+type Prescription struct {
+	ID          uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID      uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	User        User       `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	PetID       uuid.UUID  `gorm:"type:uuid;not null;index" json:"pet_id"`
+	Pet         Pet        `gorm:"foreignKey:PetID" json:"pet,omitempty"`
+	ProductID   *uuid.UUID `gorm:"type:uuid;index" json:"product_id,omitempty"`
+	Product     *Product   `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	VetName     string     `gorm:"not null" json:"vet_name"`
+	VetClinic   string     `json:"vet_clinic"`
+	Notes       string     `json:"notes"`
+	FileName    string     `gorm:"not null" json:"file_name"` // name the file was uploaded with
+	FilePath    string     `gorm:"not null" json:"-"`         // where it is stored, never exposed
+	ContentType string     `gorm:"not null" json:"content_type"`
+	FileSize    int64      `gorm:"not null" json:"file_size"`
+	Status      string     `gorm:"not null;default:'pending';index" json:"status"`
+	ValidUntil  *time.Time `gorm:"type:date" json:"valid_until,omitempty"` // last day the approval can be used
+	ReviewedBy  *uuid.UUID `gorm:"type:uuid" json:"reviewed_by,omitempty"`
+	ReviewedAt  *time.Time `json:"reviewed_at,omitempty"`
+	ReviewNote  string     `json:"review_note,omitempty"` // rejection reason or note for the customer
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+func (p *Prescription) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
@@ -7,24 +7,83 @@ import (
 	"gorm.io/gorm"
 )
 
+// Product publication statuses. Published products are listed, scheduled ones
+// from PublishAt on; unlisted ones open and sell by direct link only.
+const (
+	ProductDraft     = "draft"
+	ProductPublished = "published"
+	ProductScheduled = "scheduled"
+	ProductUnlisted  = "unlisted"
+)
+
+// ProductStatuses lists every publication status
+var ProductStatuses = []string{ProductDraft, ProductPublished, ProductScheduled, ProductUnlisted}
+
+// Product is the parent of one or more variants. Price is the lowest variant
+// price and Stock the total across variants; both are kept in sync when
+// variants change so listings and filters can keep reading them.
+// Deleting a product archives it: it leaves the catalog but order history
+// still points at it.
 type Product struct {
-	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	Price       float64   `gorm:"not null" json:"price"`
-	Stock       int       `gorm:"not null;default:0" json:"stock"`
-	CategoryID  uuid.UUID `gorm:"type:uuid;not null" json:"category_id"`
-	Category    Category  `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
-	ImageURL    string    `json:"image_url"`
-	Brand       string    `json:"brand"`
-	Weight      string    `json:"weight"` // e.g., "1kg", "500g"
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID             uuid.UUID          `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name           string             `gorm:"not null" json:"name"`
+	Description    string             `json:"description"`
+	Price          Money              `gorm:"not null" json:"price"`
+	Currency       string             `gorm:"size:3;not null;default:'THB'" json:"currency"`
+	Stock          int                `gorm:"not null;default:0" json:"stock"`
+	CategoryID     uuid.UUID          `gorm:"type:uuid;not null" json:"category_id"`
+	Category       Category           `gorm:"foreignKey:CategoryID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT" json:"category,omitempty"`
+	ImageURL       string             `json:"image_url"` // cover image, the first of Images once any are uploaded
+	Images         []Image            `gorm:"polymorphic:Owner;polymorphicValue:products" json:"images,omitempty"`
+	Brand          string             `json:"brand"`
+	Weight         string             `json:"weight"`                  // e.g., "1kg", "500g"
+	Species        string             `gorm:"index" json:"species"`    // dog, cat, ...
+	LifeStage      string             `gorm:"index" json:"life_stage"` // puppy, adult, senior, ...
+	BreedSize      string             `gorm:"index" json:"breed_size"` // toy, small, medium, large, giant
+	GrainFree      bool               `gorm:"not null;default:false" json:"grain_free"`
+	Hypoallergenic bool               `gorm:"not null;default:false" json:"hypoallergenic"`
+	Prescription   bool               `gorm:"not null;default:false" json:"prescription"`   // veterinary diet
+	Ingredients    []string           `gorm:"type:text;serializer:json" json:"ingredients"` // in label order
+	Allergens      []string           `gorm:"type:text;serializer:json" json:"allergens"`   // lower case, e.g. chicken, beef, wheat
+	Analysis       GuaranteedAnalysis `gorm:"embedded;embeddedPrefix:analysis_" json:"guaranteed_analysis"`
+	KcalPerKg      float64            `gorm:"not null;default:0" json:"kcal_per_kg"` // metabolisable energy, for feeding guides
+	Variants       []ProductVariant   `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"variants,omitempty"`
+	TierPrices     []TierPrice        `gorm:"foreignKey:ProductID" json:"tier_prices,omitempty"` // member-only prices
+	RatingAverage  float64            `gorm:"not null;default:0" json:"rating_average"`          // kept on the product for sorting
+	RatingCount    int                `gorm:"not null;default:0" json:"rating_count"`
+	Status         string             `gorm:"not null;default:'published';index" json:"status"` // draft, published, scheduled, unlisted
+	PublishAt      *time.Time         `gorm:"index" json:"publish_at,omitempty"`                // when a scheduled product goes live
+	CreatedAt      time.Time          `json:"created_at"`
+	UpdatedAt      time.Time          `json:"updated_at"`
+	ArchivedAt     gorm.DeletedAt     `gorm:"index" json:"archived_at"` // set when deleted; archived products are left out of queries
+
+	// Resolved from a running flash sale when the product is read, not stored
+	SalePrice  *Money     `gorm:"-" json:"sale_price,omitempty"`
+	SaleEndsAt *time.Time `gorm:"-" json:"sale_ends_at,omitempty"`
 }
 
 func (p *Product) BeforeCreate(tx *gorm.DB) error {
 	if p.ID == uuid.Nil {
 		p.ID = uuid.New()
 	}
+	if p.Currency == "" {
+		p.Currency = DefaultCurrency
+	}
 	return nil
 }
+
+// Listed reports whether the storefront lists the product at now
+func (p *Product) Listed(now time.Time) bool {
+	switch p.Status {
+	case ProductPublished:
+		return true
+	case ProductScheduled:
+		return p.PublishAt != nil && !p.PublishAt.After(now)
+	}
+	return false
+}
+
+// Purchasable reports whether customers can open and buy the product at now
+func (p *Product) Purchasable(now time.Time) bool {
+	return p.Status == ProductUnlisted || p.Listed(now)
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Species a product is made for
+const (
+	SpeciesDog      = "dog"
+	SpeciesCat      = "cat"
+	SpeciesBird     = "bird"
+	SpeciesFish     = "fish"
+	SpeciesSmallPet = "small_pet" // rabbits, hamsters, guinea pigs, ...
+	SpeciesReptile  = "reptile"
+)
+
+// Life stages; all_stages is for food suitable at any age
+const (
+	LifeStagePuppy     = "puppy"
+	LifeStageKitten    = "kitten"
+	LifeStageAdult     = "adult"
+	LifeStageSenior    = "senior"
+	LifeStageAllStages = "all_stages"
+)
+
+// Breed sizes, mostly for dog food
+const (
+	BreedSizeToy    = "toy"
+	BreedSizeSmall  = "small"
+	BreedSizeMedium = "medium"
+	BreedSizeLarge  = "large"
+	BreedSizeGiant  = "giant"
+)
+
+var (
+	SpeciesValues   = []string{SpeciesDog, SpeciesCat, SpeciesBird, SpeciesFish, SpeciesSmallPet, SpeciesReptile}
+	LifeStageValues = []string{LifeStagePuppy, LifeStageKitten, LifeStageAdult, LifeStageSenior, LifeStageAllStages}
+	BreedSizeValues = []string{BreedSizeToy, BreedSizeSmall, BreedSizeMedium, BreedSizeLarge, BreedSizeGiant}
+)
+
+// GuaranteedAnalysis is the label's nutrient table in percent. Protein and fat
+// are minimums, fibre and moisture maximums. Nil means not stated.
+type GuaranteedAnalysis struct {
+	Protein  *float64 `json:"protein,omitempty" example:"26"`
+	Fat      *float64 `json:"fat,omitempty" example:"15"`
+	Fibre    *float64 `json:"fibre,omitempty" example:"3.5"`
+	Moisture *float64 `json:"moisture,omitempty" example:"10"`
+}
+
+// WeightGrams reads a pack weight such as "4kg", "500 g" or "2.5 lb" in grams
+func WeightGrams(weight string) (float64, bool) {
+	weight = strings.ToLower(strings.ReplaceAll(weight, " ", ""))
+	units := []struct {
+		suffix string
+		grams  float64
+	}{
+		{"kg", 1000}, {"g", 1}, {"lbs", 453.592}, {"lb", 453.592}, {"oz", 28.3495},
+	}
+	for _, unit := range units {
+		if number, ok := strings.CutSuffix(weight, unit.suffix); ok {
+			value, err := strconv.ParseFloat(number, 64)
+			if err != nil || value <= 0 {
+				return 0, false
+			}
+			return value * unit.grams, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ProductVariant is one purchasable pack size or flavour of a product. Cart and
+// order items point at a variant; the parent product carries the shared details.
+type ProductVariant struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ProductID uuid.UUID `gorm:"type:uuid;not null;index" json:"product_id"`
+	Name      string    `gorm:"not null" json:"name"` // e.g. "4kg" or "Chicken 4kg"
+	SKU       string    `gorm:"uniqueIndex;not null" json:"sku"`
+	Barcode   string    `gorm:"index" json:"barcode,omitempty"`
+	Weight    string    `json:"weight"` // e.g., "1kg", "500g"
+	Flavour   string    `json:"flavour,omitempty"`
+	Price     Money     `gorm:"not null" json:"price"`
+	Stock     int       `gorm:"not null;default:0" json:"stock"`
+	Position  int       `gorm:"not null;default:0" json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Resolved from a running flash sale when the product is read, not stored
+	SalePrice *Money `gorm:"-" json:"sale_price,omitempty"`
+}
+
+func (v *ProductVariant) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	if v.SKU == "" {
+		v.SKU = DefaultVariantSKU(v.ProductID, v.ID)
+	}
+	return nil
+}
+
+// DefaultVariantSKU builds a placeholder SKU for variants created without one
+func DefaultVariantSKU(productID, variantID uuid.UUID) string {
+	return strings.ToUpper(fmt.Sprintf("P%s-%s", productID.String()[:8], variantID.String()[:4]))
+}
+
+// DefaultVariant builds the single variant a product without variants is sold as
+func DefaultVariant(product *Product) ProductVariant {
+	name := product.Weight
+	if name == "" {
+		name = "Standard"
+	}
+	return ProductVariant{
+		ProductID: product.ID,
+		Name:      name,
+		Weight:    product.Weight,
+		Price:     product.Price,
+		Stock:     product.Stock,
+	}
+}
+
+// MigrateProductVariants moves catalogs from before variants onto them: every
+// product without variants gets a default one carrying its price, stock and
+// weight, and existing cart and order items are pointed at it. Safe to run on
+// every start.
+func MigrateProductVariants(db *gorm.DB) error {
+	// Tier prices used to be unique per product; they are now unique per variant
+	if db.Migrator().HasIndex(&TierPrice{}, "idx_tier_price_product_tier") {
+		if err := db.Migrator().DropIndex(&TierPrice{}, "idx_tier_price_product_tier"); err != nil {
+			return err
+		}
+	}
+
+	var products []Product
+	if err := db.Where("NOT EXISTS (SELECT 1 FROM product_variants WHERE product_variants.product_id = products.id)").
+		Find(&products).Error; err != nil {
+		return err
+	}
+
+	for i := range products {
+		product := &products[i]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			variant := DefaultVariant(product)
+			if err := tx.Create(&variant).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&Cart{}).Where("product_id = ? AND variant_id IS NULL", product.ID).
+				Update("variant_id", variant.ID).Error; err != nil {
+				return err
+			}
+			return tx.Model(&OrderItem{}).Where("product_id = ? AND variant_id IS NULL", product.ID).
+				Updates(map[string]interface{}{
+					"variant_id":   variant.ID,
+					"sku":          variant.SKU,
+					"variant_name": variant.Name,
+				}).Error
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Promotion types
+const (
+	PromotionTypeBuyXGetY = "buy_x_get_y" // buy BuyQuantity, get GetQuantity at GetPercentOff
+	PromotionTypeBundle   = "bundle"      // BundleQuantity units for BundlePrice
+	PromotionTypeTiered   = "tiered"      // percent off by quantity tier
+)
+
+// Promotion is an automatic, code-less discount evaluated per cart line
+type Promotion struct {
+	ID             uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Name           string          `gorm:"not null" json:"name"`
+	Description    string          `json:"description"`
+	Type           string          `gorm:"not null" json:"type"`                      // buy_x_get_y, bundle, tiered
+	Priority       int             `gorm:"not null;default:0" json:"priority"`        // higher is evaluated first
+	Stackable      bool            `gorm:"not null;default:false" json:"stackable"`   // may combine with other stackable promotions on a line
+	BuyQuantity    int             `gorm:"not null;default:0" json:"buy_quantity"`    // buy_x_get_y
+	GetQuantity    int             `gorm:"not null;default:0" json:"get_quantity"`    // buy_x_get_y
+	GetPercentOff  int             `gorm:"not null;default:0" json:"get_percent_off"` // buy_x_get_y, 100 = free
+	BundleQuantity int             `gorm:"not null;default:0" json:"bundle_quantity"` // bundle
+	BundlePrice    Money           `gorm:"not null;default:0" json:"bundle_price"`    // bundle
+	Tiers          []PromotionTier `gorm:"foreignKey:PromotionID" json:"tiers,omitempty"`
+	StartsAt       *time.Time      `json:"starts_at"`
+	EndsAt         *time.Time      `json:"ends_at"`
+	Active         bool            `gorm:"not null" json:"active"`
+	Categories     []Category      `gorm:"many2many:promotion_categories" json:"categories,omitempty"` // restrict to these categories
+	Products       []Product       `gorm:"many2many:promotion_products" json:"products,omitempty"`     // restrict to these products
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// PromotionTier is one quantity break of a tiered promotion
+type PromotionTier struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	PromotionID uuid.UUID `gorm:"type:uuid;not null;index" json:"promotion_id"`
+	MinQuantity int       `gorm:"not null" json:"min_quantity"`
+	PercentOff  int       `gorm:"not null" json:"percent_off"`
+}
+
+// IsRunning reports whether the promotion is active at the given time
+func (p *Promotion) IsRunning(now time.Time) bool {
+	if !p.Active {
+		return false
+	}
+	if p.StartsAt != nil && now.Before(*p.StartsAt) {
+		return false
+	}
+	if p.EndsAt != nil && !now.Before(*p.EndsAt) {
+		return false
+	}
+	return true
+}
+
+// AppliesTo reports whether a product falls under the promotion's restrictions.
+// categoryIDs holds the product's category and every category above it, so a
+// promotion on a parent category covers the products in its subcategories.
+func (p *Promotion) AppliesTo(product *Product, categoryIDs []uuid.UUID) bool {
+	if len(p.Products) == 0 && len(p.Categories) == 0 {
+		return true
+	}
+	for _, pr := range p.Products {
+		if pr.ID == product.ID {
+			return true
+		}
+	}
+	for _, cat := range p.Categories {
+		if slices.Contains(categoryIDs, cat.ID) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Promotion) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+func (t *PromotionTier) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
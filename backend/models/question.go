@@ -0,0 +1,86 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Q&A moderation statuses, shared by questions and answers. Only approved
+// ones are shown on the product page.
+const (
+	QuestionPending  = "pending"
+	QuestionApproved = "approved"
+	QuestionHidden   = "hidden"
+)
+
+// ProductQuestion is a customer's question about a product
+type ProductQuestion struct {
+	ID          uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ProductID   uuid.UUID       `gorm:"type:uuid;not null;index:idx_question_product_status" json:"product_id"`
+	Product     *Product        `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"product,omitempty"`
+	UserID      uuid.UUID       `gorm:"type:uuid;not null;index" json:"user_id"`
+	User        User            `gorm:"foreignKey:UserID" json:"-"`
+	Body        string          `gorm:"not null" json:"body"`
+	Status      string          `gorm:"not null;default:'pending';index:idx_question_product_status" json:"status"` // pending, approved, hidden
+	Answers     []ProductAnswer `gorm:"foreignKey:QuestionID;constraint:OnDelete:CASCADE" json:"answers"`
+	ModeratedBy *uuid.UUID      `gorm:"type:uuid" json:"moderated_by,omitempty"`
+	ModeratedAt *time.Time      `json:"moderated_at,omitempty"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+
+	// Filled in from User when the question is read, not stored
+	AskerName string `gorm:"-" json:"asker_name"`
+}
+
+func (q *ProductQuestion) BeforeCreate(tx *gorm.DB) error {
+	if q.ID == uuid.Nil {
+		q.ID = uuid.New()
+	}
+	return nil
+}
+
+func (q *ProductQuestion) AfterFind(tx *gorm.DB) error {
+	if q.User.Name != "" {
+		q.AskerName = ShortName(q.User.Name)
+	}
+	return nil
+}
+
+// ProductAnswer answers a question, from the store or from a customer who
+// received the product. The store's answers are approved as they are posted.
+type ProductAnswer struct {
+	ID            uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	QuestionID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"question_id"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null" json:"user_id"`
+	User          User       `gorm:"foreignKey:UserID" json:"-"`
+	Body          string     `gorm:"not null" json:"body"`
+	FromStore     bool       `gorm:"not null;default:false" json:"from_store"`
+	VerifiedBuyer bool       `gorm:"not null;default:false" json:"verified_buyer"`
+	Status        string     `gorm:"not null;default:'pending';index" json:"status"` // pending, approved, hidden
+	ModeratedBy   *uuid.UUID `gorm:"type:uuid" json:"moderated_by,omitempty"`
+	ModeratedAt   *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+
+	// The store, or the customer's short name; not stored
+	AnswererName string `gorm:"-" json:"answerer_name"`
+}
+
+func (a *ProductAnswer) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
+
+func (a *ProductAnswer) AfterFind(tx *gorm.DB) error {
+	switch {
+	case a.FromStore:
+		a.AnswererName = "Store"
+	case a.User.Name != "":
+		a.AnswererName = ShortName(a.User.Name)
+	}
+	return nil
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Product pairing kinds
+const (
+	PairingBoughtTogether = "bought_together" // in the same order
+	PairingAlsoBought     = "also_bought"     // by the same customer, in any of their orders
+)
+
+// ProductPairing records how often customers bought RelatedID along with
+// ProductID. The recommendation job rebuilds the table from order history.
+type ProductPairing struct {
+	ProductID  uuid.UUID `gorm:"type:uuid;primaryKey" json:"product_id"`
+	Kind       string    `gorm:"primaryKey" json:"kind"` // bought_together, also_bought
+	RelatedID  uuid.UUID `gorm:"type:uuid;primaryKey" json:"related_id"`
+	Count      int       `gorm:"not null" json:"count"`      // orders, or customers for also_bought
+	Confidence float64   `gorm:"not null" json:"confidence"` // share of the product's orders or customers
+	ComputedAt time.Time `gorm:"not null" json:"computed_at"`
+}
@@ -0,0 +1,62 @@
+package models
+
+import (
+	"crypto/rand"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Referral statuses
+const (
+	ReferralPending  = "pending"  // referee registered, first order not delivered yet
+	ReferralReview   = "review"   // first order delivered but the referral was flagged
+	ReferralRewarded = "rewarded" // referrer reward issued
+	ReferralRejected = "rejected" // refused by an admin; no discount or reward
+)
+
+// referralCodeAlphabet leaves out 0/O and 1/I so codes are easy to read out
+const referralCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// Referral links a referee to the customer whose code they registered with
+type Referral struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ReferrerID   uuid.UUID  `gorm:"type:uuid;not null;index" json:"referrer_id"`
+	Referrer     User       `gorm:"foreignKey:ReferrerID" json:"referrer,omitempty"`
+	RefereeID    uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex" json:"referee_id"`
+	Referee      User       `gorm:"foreignKey:RefereeID" json:"referee,omitempty"`
+	Code         string     `gorm:"not null" json:"code"`
+	Status       string     `gorm:"not null;default:'pending';index" json:"status"`
+	Flagged      bool       `gorm:"not null;default:false" json:"flagged"`
+	FlagReason   string     `json:"flag_reason,omitempty"`
+	FirstOrderID *uuid.UUID `gorm:"type:uuid;index" json:"first_order_id,omitempty"`
+	RewardCredit Money      `gorm:"not null;default:0" json:"reward_credit"`
+	RewardPoints int        `gorm:"not null;default:0" json:"reward_points"`
+	RewardedAt   *time.Time `json:"rewarded_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
+
+// NewReferralCode generates a random 8-character referral code
+func NewReferralCode() string {
+	bytes := make([]byte, 8)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(err)
+	}
+	code := make([]byte, len(bytes))
+	for i, b := range bytes {
+		code[i] = referralCodeAlphabet[int(b)%len(referralCodeAlphabet)]
+	}
+	return string(code)
+}
+
+func (r *Referral) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	if r.Status == "" {
+		r.Status = ReferralPending
+	}
+	return nil
+}
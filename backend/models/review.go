@@ -0,0 +1,91 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Review moderation statuses. Only approved reviews are shown and count
+// towards the product's rating.
+const (
+	ReviewPending  = "pending"
+	ReviewApproved = "approved"
+	ReviewHidden   = "hidden"
+)
+
+// ReviewStatuses lists every moderation status
+var ReviewStatuses = []string{ReviewPending, ReviewApproved, ReviewHidden}
+
+// Review is a customer's rating of a product they received. Each customer
+// reviews a product once; OrderItemID is the delivered purchase that let them.
+type Review struct {
+	ID           uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ProductID    uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_review_product_user;index:idx_review_product_status" json:"product_id"`
+	Product      *Product   `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"product,omitempty"`
+	UserID       uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_review_product_user" json:"user_id"`
+	User         User       `gorm:"foreignKey:UserID" json:"-"`
+	OrderItemID  uuid.UUID  `gorm:"type:uuid;not null" json:"order_item_id"`
+	Rating       int        `gorm:"not null" json:"rating"` // 1 to 5 stars
+	Title        string     `json:"title"`
+	Body         string     `json:"body"`
+	Photos       []Image    `gorm:"polymorphic:Owner;polymorphicValue:reviews" json:"photos"`
+	Status       string     `gorm:"not null;default:'pending';index:idx_review_product_status" json:"status"` // pending, approved, hidden
+	HelpfulCount int        `gorm:"not null;default:0" json:"helpful_count"`
+	AdminReply   string     `json:"admin_reply,omitempty"`
+	RepliedAt    *time.Time `json:"replied_at,omitempty"`
+	ModeratedBy  *uuid.UUID `gorm:"type:uuid" json:"moderated_by,omitempty"`
+	ModeratedAt  *time.Time `json:"moderated_at,omitempty"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+
+	// Filled in from User when the review is read, not stored
+	ReviewerName string `gorm:"-" json:"reviewer_name"`
+}
+
+func (r *Review) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// AfterFind shows the reviewer by first name and last initial, e.g. "Somchai J."
+func (r *Review) AfterFind(tx *gorm.DB) error {
+	if r.User.Name != "" {
+		r.ReviewerName = ShortName(r.User.Name)
+	}
+	return nil
+}
+
+// ShortName cuts a full name down to the first name and the initial of the last
+func ShortName(name string) string {
+	parts := strings.Fields(name)
+	if len(parts) == 0 {
+		return ""
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	initial, _ := utf8.DecodeRuneInString(parts[len(parts)-1])
+	return parts[0] + " " + string(initial) + "."
+}
+
+// ReviewVote is a customer marking a review as helpful, once per review
+type ReviewVote struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	ReviewID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_review_vote" json:"review_id"`
+	Review    Review    `gorm:"foreignKey:ReviewID;constraint:OnDelete:CASCADE" json:"-"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_review_vote" json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (v *ReviewVote) BeforeCreate(tx *gorm.DB) error {
+	if v.ID == uuid.Nil {
+		v.ID = uuid.New()
+	}
+	return nil
+}
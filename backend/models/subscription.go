@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Subscription statuses
+const (
+	SubscriptionActive    = "active"
+	SubscriptionPaused    = "paused"
+	SubscriptionCancelled = "cancelled"
+)
+
+// Subscription run outcomes
+const (
+	SubscriptionRunSucceeded = "succeeded"
+	SubscriptionRunFailed    = "failed"  // retried until the attempts run out
+	SubscriptionRunGaveUp    = "gave_up" // last failed attempt; the delivery moved to the next cycle
+	SubscriptionRunSkipped   = "skipped" // skipped by the customer
+)
+
+// Subscription is an autoship: a product the scheduler orders for the customer
+// every IntervalDays
+type Subscription struct {
+	ID              uuid.UUID      `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID          uuid.UUID      `gorm:"type:uuid;not null;index" json:"user_id"`
+	ProductID       uuid.UUID      `gorm:"type:uuid;not null;index" json:"product_id"`
+	Product         Product        `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	VariantID       uuid.UUID      `gorm:"type:uuid;not null" json:"variant_id"`
+	Variant         ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
+	Quantity        int            `gorm:"not null;default:1" json:"quantity"`
+	IntervalDays    int            `gorm:"not null" json:"interval_days"`
+	NextRunAt       time.Time      `gorm:"not null;index" json:"next_run_at"` // next scheduled delivery
+	ShippingAddress string         `gorm:"not null" json:"shipping_address"`
+	PaymentMethod   string         `gorm:"not null;default:'cod'" json:"payment_method"`
+	UseStoreCredit  bool           `gorm:"not null;default:false" json:"use_store_credit"`
+	Status          string         `gorm:"not null;default:'active';index" json:"status"`
+	PausedUntil     *time.Time     `json:"paused_until,omitempty"`                    // resumed by the scheduler; nil = until resumed
+	FailedAttempts  int            `gorm:"not null;default:0" json:"failed_attempts"` // for the current delivery
+	RetryAt         *time.Time     `json:"retry_at,omitempty"`
+	LastError       string         `json:"last_error,omitempty"`
+	LastRunAt       *time.Time     `json:"last_run_at,omitempty"`
+	LastOrderID     *uuid.UUID     `gorm:"type:uuid" json:"last_order_id,omitempty"`
+	CancelledAt     *time.Time     `json:"cancelled_at,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// SubscriptionRun records one scheduled delivery attempt, or a skipped delivery
+type SubscriptionRun struct {
+	ID             uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	SubscriptionID uuid.UUID  `gorm:"type:uuid;not null;index" json:"subscription_id"`
+	UserID         uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	ScheduledFor   time.Time  `gorm:"not null" json:"scheduled_for"`
+	Attempt        int        `gorm:"not null;default:1" json:"attempt"`
+	Status         string     `gorm:"not null;index" json:"status"`
+	OrderID        *uuid.UUID `gorm:"type:uuid" json:"order_id,omitempty"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+func (s *Subscription) BeforeCreate(tx *gorm.DB) error {
+	if s.ID == uuid.Nil {
+		s.ID = uuid.New()
+	}
+	return nil
+}
+
+func (r *SubscriptionRun) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -8,17 +8,19 @@ import (
 )
 
 type User struct {
-	ID               uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
-	Email            string    `gorm:"uniqueIndex;not null" json:"email"`
-	PasswordHash     string    `gorm:"not null" json:"-"`
-	Name             string    `gorm:"not null" json:"name"`
-	Phone            string    `json:"phone"`
-	Address          string    `json:"address"`
-	Role             string    `gorm:"default:'customer'" json:"role"` // customer or admin
-	ResetToken       string    `json:"-"`
-	ResetTokenExpiry time.Time `json:"-"`
-	CreatedAt        time.Time `json:"created_at"`
-	UpdatedAt        time.Time `json:"updated_at"`
+	ID               uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	Email            string     `gorm:"uniqueIndex;not null" json:"email"`
+	PasswordHash     string     `gorm:"not null" json:"-"`
+	Name             string     `gorm:"not null" json:"name"`
+	Phone            string     `json:"phone"`
+	Address          string     `json:"address"`
+	Role             string     `gorm:"default:'customer'" json:"role"` // customer, vet or admin
+	ReferralCode     string     `gorm:"uniqueIndex" json:"referral_code,omitempty"`
+	ReferredByID     *uuid.UUID `gorm:"type:uuid" json:"referred_by_id,omitempty"`
+	ResetToken       string     `json:"-"`
+	ResetTokenExpiry time.Time  `json:"-"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
@@ -27,3 +29,12 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// BeforeSave gives users created before referral codes existed a code the first
+// time they are saved, so empty codes never collide on the unique index
+func (u *User) BeforeSave(tx *gorm.DB) error {
+	if u.ReferralCode == "" {
+		u.ReferralCode = NewReferralCode()
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Wallet entry types
+const (
+	WalletEntryCredit = "credit"
+	WalletEntryDebit  = "debit"
+	WalletEntryExpiry = "expiry"
+)
+
+// WalletEntry is one line of a user's store credit ledger. Credits keep track of
+// their unspent Remaining amount so debits can consume them soonest-expiring first.
+type WalletEntry struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID    uuid.UUID  `gorm:"type:uuid;not null;index" json:"user_id"`
+	Type      string     `gorm:"not null" json:"type"`   // credit, debit, expiry
+	Amount    Money      `gorm:"not null" json:"amount"` // always positive
+	Remaining Money      `gorm:"not null;default:0" json:"remaining"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Reason    string     `json:"reason"`
+	OrderID   *uuid.UUID `gorm:"type:uuid;index" json:"order_id,omitempty"`
+	ActorID   *uuid.UUID `gorm:"type:uuid" json:"actor_id,omitempty"` // admin who issued or adjusted it
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+func (w *WalletEntry) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
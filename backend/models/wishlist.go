@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// WishlistItem is a product a customer saved for later
+type WishlistItem struct {
+	ID             uuid.UUID       `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID         uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_wishlist_user_product" json:"user_id"`
+	ProductID      uuid.UUID       `gorm:"type:uuid;not null;uniqueIndex:idx_wishlist_user_product" json:"product_id"`
+	Product        Product         `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"product"`
+	VariantID      *uuid.UUID      `gorm:"type:uuid" json:"variant_id,omitempty"` // pack size picked, if any
+	Variant        *ProductVariant `gorm:"foreignKey:VariantID;constraint:OnDelete:SET NULL" json:"variant,omitempty"`
+	PriceWhenAdded Money           `gorm:"not null;default:0" json:"price_when_added"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+func (w *WishlistItem) BeforeCreate(tx *gorm.DB) error {
+	if w.ID == uuid.Nil {
+		w.ID = uuid.New()
+	}
+	return nil
+}
+
+// Product alert types
+const (
+	ProductAlertBackInStock = "back_in_stock" // once, when an out of stock product is restocked
+	ProductAlertPriceDrop   = "price_drop"    // each time the price falls below the last one alerted
+)
+
+// ProductAlert is a customer's request to be told when a product is back in
+// stock or its price drops. Back in stock alerts are removed once sent.
+type ProductAlert struct {
+	ID         uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	UserID     uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_product_alert" json:"user_id"`
+	ProductID  uuid.UUID  `gorm:"type:uuid;not null;uniqueIndex:idx_product_alert;index" json:"product_id"`
+	Product    Product    `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"product"`
+	Type       string     `gorm:"not null;uniqueIndex:idx_product_alert" json:"type"` // back_in_stock, price_drop
+	Price      Money      `gorm:"not null;default:0" json:"price"`                    // price drops are measured from here
+	NotifiedAt *time.Time `json:"notified_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (a *ProductAlert) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == uuid.Nil {
+		a.ID = uuid.New()
+	}
+	return nil
+}
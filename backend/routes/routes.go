@@ -8,6 +8,10 @@ import (
 )
 
 func SetupRoutes(router *gin.Engine) {
+	// Uploaded images, when not linked from a CDN
+	router.GET("/uploads/*filepath", controllers.ServeUpload)
+	router.HEAD("/uploads/*filepath", controllers.ServeUpload)
+
 	// Public routes
 	api := router.Group("/api")
 	{
@@ -31,13 +35,19 @@ func SetupRoutes(router *gin.Engine) {
 			products.GET("", controllers.GetProducts)
 			products.GET("/:id", controllers.GetProduct)
 			products.GET("/category/:categoryId", controllers.GetProductsByCategory)
+			products.GET("/:id/reviews", controllers.GetProductReviews)
 		}
 
+		// Public flash sale routes
+		api.GET("/flash-sales", controllers.GetActiveFlashSales)
+
 		// Public category routes
 		categories := api.Group("/categories")
 		{
 			categories.GET("", controllers.GetCategories)
+			categories.GET("/tree", controllers.GetCategoryTree)
 			categories.GET("/:id", controllers.GetCategory)
+			categories.GET("/:id/breadcrumbs", controllers.GetCategoryBreadcrumbs)
 		}
 	}
 
@@ -57,6 +67,94 @@ func SetupRoutes(router *gin.Engine) {
 			cart.PUT("/:id", controllers.UpdateCartItem)
 			cart.DELETE("/:id", controllers.RemoveFromCart)
 			cart.DELETE("", controllers.ClearCart)
+			cart.POST("/coupon", controllers.ApplyCoupon)
+			cart.DELETE("/coupon", controllers.RemoveCoupon)
+		}
+
+		// Wishlist and product alerts
+		wishlist := protected.Group("/wishlist")
+		{
+			wishlist.GET("", controllers.GetWishlist)
+			wishlist.POST("", controllers.AddToWishlist)
+			wishlist.DELETE("/:productId", controllers.RemoveFromWishlist)
+			wishlist.POST("/:productId/move-to-cart", controllers.MoveWishlistItemToCart)
+		}
+		alerts := protected.Group("/alerts")
+		{
+			alerts.GET("", controllers.GetProductAlerts)
+			alerts.POST("", controllers.CreateProductAlert)
+			alerts.DELETE("/:id", controllers.DeleteProductAlert)
+		}
+
+		// Store credit and gift cards
+		protected.GET("/wallet", controllers.GetWallet)
+		protected.GET("/gift-cards/:code", controllers.CheckGiftCard)
+
+		// Loyalty points
+		protected.GET("/loyalty", controllers.GetLoyalty)
+
+		// Referrals
+		protected.GET("/referrals", controllers.GetReferrals)
+		protected.POST("/referrals/apply", controllers.ApplyReferralCode)
+
+		// Pet profiles and recommendations
+		pets := protected.Group("/pets")
+		{
+			pets.GET("", controllers.GetPets)
+			pets.POST("", controllers.CreatePet)
+			pets.GET("/:id", controllers.GetPet)
+			pets.PUT("/:id", controllers.UpdatePet)
+			pets.DELETE("/:id", controllers.DeletePet)
+			pets.GET("/:id/feeding/:productId", controllers.GetFeedingGuide)
+		}
+		protected.GET("/recommendations", controllers.GetRecommendations)
+		protected.GET("/reorders", controllers.GetReorderPredictions)
+
+		// Prescriptions for veterinary diets
+		prescriptions := protected.Group("/prescriptions")
+		{
+			prescriptions.GET("", controllers.GetPrescriptions)
+			prescriptions.POST("", controllers.UploadPrescription)
+			prescriptions.GET("/:id/file", controllers.GetPrescriptionFile)
+		}
+
+		// Autoship subscriptions
+		subscriptions := protected.Group("/subscriptions")
+		{
+			subscriptions.GET("", controllers.GetSubscriptions)
+			subscriptions.POST("", controllers.CreateSubscription)
+			subscriptions.GET("/:id", controllers.GetSubscription)
+			subscriptions.PUT("/:id", controllers.UpdateSubscription)
+			subscriptions.POST("/:id/skip", controllers.SkipSubscription)
+			subscriptions.POST("/:id/reschedule", controllers.RescheduleSubscription)
+			subscriptions.POST("/:id/pause", controllers.PauseSubscription)
+			subscriptions.POST("/:id/resume", controllers.ResumeSubscription)
+			subscriptions.POST("/:id/cancel", controllers.CancelSubscription)
+		}
+
+		// Product reviews
+		protected.POST("/products/:id/reviews", controllers.CreateReview)
+		reviews := protected.Group("/reviews")
+		{
+			reviews.GET("", controllers.GetMyReviews)
+			reviews.POST("/:id/helpful", controllers.MarkReviewHelpful)
+			reviews.DELETE("/:id/helpful", controllers.UnmarkReviewHelpful)
+		}
+
+		// Product Q&A
+		protected.POST("/products/:id/questions", controllers.AskQuestion)
+		questions := protected.Group("/questions")
+		{
+			questions.GET("", controllers.GetMyQuestions)
+			questions.POST("/:id/answers", controllers.AnswerQuestion)
+		}
+
+		// Notifications
+		notifications := protected.Group("/notifications")
+		{
+			notifications.GET("", controllers.GetNotifications)
+			notifications.PUT("/read", controllers.MarkAllNotificationsRead)
+			notifications.PUT("/:id/read", controllers.MarkNotificationRead)
 		}
 
 		// Order routes
@@ -68,6 +166,15 @@ func SetupRoutes(router *gin.Engine) {
 		}
 	}
 
+	// Vet routes: prescription review, also open to admins
+	vet := api.Group("/vet")
+	vet.Use(middleware.AuthMiddleware(), middleware.VetMiddleware())
+	{
+		vet.GET("/prescriptions", controllers.GetPrescriptionQueue)
+		vet.POST("/prescriptions/:id/approve", controllers.ApprovePrescription)
+		vet.POST("/prescriptions/:id/reject", controllers.RejectPrescription)
+	}
+
 	// Admin routes
 	admin := api.Group("/admin")
 	admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
@@ -75,17 +182,109 @@ func SetupRoutes(router *gin.Engine) {
 		// Product management
 		products := admin.Group("/products")
 		{
+			products.GET("", controllers.GetAdminProducts)
 			products.POST("", controllers.CreateProduct)
+			products.POST("/import", controllers.ImportProducts)
+			products.GET("/export", controllers.ExportProducts)
+			products.PATCH("/:id", controllers.UpdateProduct)
 			products.PUT("/:id", controllers.UpdateProduct)
 			products.DELETE("/:id", controllers.DeleteProduct)
+			products.GET("/archived", controllers.GetArchivedProducts)
+			products.POST("/:id/restore", controllers.RestoreProduct)
+			products.GET("/:id/preview", controllers.PreviewProduct)
+			products.POST("/:id/images", controllers.UploadProductImage)
+			products.PUT("/:id/images/order", controllers.ReorderProductImages)
+			products.PUT("/:id/images/:imageId", controllers.UpdateProductImage)
+			products.DELETE("/:id/images/:imageId", controllers.DeleteProductImage)
+			products.PUT("/:id/tier-prices", controllers.SetTierPrices)
+			products.POST("/:id/variants", controllers.CreateProductVariant)
+			products.PUT("/:id/variants/:variantId", controllers.UpdateProductVariant)
+			products.DELETE("/:id/variants/:variantId", controllers.DeleteProductVariant)
 		}
+		admin.POST("/recommendations/run", controllers.RunRecommendations)
 
 		// Category management
 		categories := admin.Group("/categories")
 		{
 			categories.POST("", controllers.CreateCategory)
+			categories.PATCH("/:id", controllers.UpdateCategory)
 			categories.PUT("/:id", controllers.UpdateCategory)
 			categories.DELETE("/:id", controllers.DeleteCategory)
+			categories.GET("/archived", controllers.GetArchivedCategories)
+			categories.POST("/:id/restore", controllers.RestoreCategory)
+			categories.POST("/:id/images", controllers.UploadCategoryImage)
+			categories.PUT("/:id/images/order", controllers.ReorderCategoryImages)
+			categories.PUT("/:id/images/:imageId", controllers.UpdateCategoryImage)
+			categories.DELETE("/:id/images/:imageId", controllers.DeleteCategoryImage)
+		}
+
+		// Review moderation
+		reviews := admin.Group("/reviews")
+		{
+			reviews.GET("", controllers.GetReviewQueue)
+			reviews.PUT("/:id/status", controllers.ModerateReview)
+			reviews.PUT("/:id/reply", controllers.ReplyToReview)
+		}
+
+		// Q&A moderation
+		admin.GET("/questions", controllers.GetQuestionQueue)
+		admin.PUT("/questions/:id/status", controllers.ModerateQuestion)
+		admin.GET("/answers", controllers.GetAnswerQueue)
+		admin.PUT("/answers/:id/status", controllers.ModerateAnswer)
+
+		// Coupon management
+		coupons := admin.Group("/coupons")
+		{
+			coupons.GET("", controllers.GetCoupons)
+			coupons.POST("", controllers.CreateCoupon)
+			coupons.PUT("/:id", controllers.UpdateCoupon)
+			coupons.DELETE("/:id", controllers.DeleteCoupon)
+			coupons.GET("/:id/redemptions", controllers.GetCouponRedemptions)
+		}
+
+		// Promotion management
+		promotions := admin.Group("/promotions")
+		{
+			promotions.GET("", controllers.GetPromotions)
+			promotions.POST("", controllers.CreatePromotion)
+			promotions.PUT("/:id", controllers.UpdatePromotion)
+			promotions.DELETE("/:id", controllers.DeletePromotion)
+		}
+
+		// Flash sale management
+		flashSales := admin.Group("/flash-sales")
+		{
+			flashSales.GET("", controllers.GetFlashSales)
+			flashSales.POST("", controllers.CreateFlashSale)
+			flashSales.PUT("/:id", controllers.UpdateFlashSale)
+			flashSales.DELETE("/:id", controllers.DeleteFlashSale)
+		}
+
+		// Store credit management
+		admin.PUT("/users/:id/role", controllers.SetUserRole)
+		admin.GET("/users/:id/wallet", controllers.GetUserWallet)
+		admin.POST("/users/:id/wallet/adjustments", controllers.AdjustUserWallet)
+
+		// Loyalty management
+		admin.GET("/users/:id/loyalty", controllers.GetUserLoyalty)
+		admin.POST("/users/:id/loyalty/adjustments", controllers.AdjustUserLoyalty)
+
+		// Referral management
+		referrals := admin.Group("/referrals")
+		{
+			referrals.GET("", controllers.GetReferralReport)
+			referrals.POST("/:id/approve", controllers.ApproveReferral)
+			referrals.POST("/:id/reject", controllers.RejectReferral)
+		}
+
+		// Gift card management
+		giftCards := admin.Group("/gift-cards")
+		{
+			giftCards.GET("", controllers.GetGiftCards)
+			giftCards.POST("", controllers.IssueGiftCard)
+			giftCards.GET("/:id", controllers.GetGiftCard)
+			giftCards.PUT("/:id", controllers.UpdateGiftCard)
+			giftCards.POST("/:id/adjustments", controllers.AdjustGiftCard)
 		}
 
 		// Order management
@@ -94,5 +293,14 @@ func SetupRoutes(router *gin.Engine) {
 			orders.GET("", controllers.GetAllOrders)
 			orders.PUT("/:id/status", controllers.UpdateOrderStatus)
 		}
+		admin.GET("/reorder-reminders", controllers.GetReorderReminders)
+
+		// Autoship subscriptions
+		adminSubscriptions := admin.Group("/subscriptions")
+		{
+			adminSubscriptions.GET("", controllers.GetSubscriptionReport)
+			adminSubscriptions.GET("/runs", controllers.GetSubscriptionRuns)
+			adminSubscriptions.POST("/run", controllers.RunSubscriptions)
+		}
 	}
 }
@@ -0,0 +1,149 @@
+package search
+
+import (
+	"encoding/hex"
+	"strings"
+	"unicode"
+)
+
+// analyzerVersion is stored on every indexed document. Bump it whenever the
+// analyzer changes so Setup re-indexes documents written by an older version.
+const analyzerVersion = 1
+
+const (
+	maxWordRunes = 64 // longer runs are cut, Postgres caps lexemes at 2K bytes
+	gramSize     = 3  // Latin/number n-gram size used for typo tolerance
+	thaiGramSize = 2  // Thai has no spaces between words, so runs are split into bigrams
+)
+
+// term is one word of a query or document. Grams are the overlapping n-grams
+// used to match it when the exact word does not appear.
+type term struct {
+	Word  string
+	Grams []string
+	Thai  bool
+}
+
+func isThai(r rune) bool {
+	return r >= 0x0E00 && r <= 0x0E7F
+}
+
+// isThaiMark reports tone marks and the silent/shortening marks that are often
+// left out or typed wrongly; they are dropped so spellings still match
+func isThaiMark(r rune) bool {
+	return r >= 0x0E47 && r <= 0x0E4C
+}
+
+// isThaiSeparator reports Thai punctuation that ends a word
+func isThaiSeparator(r rune) bool {
+	switch r {
+	case 0x0E2F, 0x0E46, 0x0E4F, 0x0E5A, 0x0E5B: // paiyannoi, mai yamok, fongman, angkhankhu, khomut
+		return true
+	}
+	return false
+}
+
+// analyze splits text into terms. Latin words and numbers are split on anything
+// that is not a letter or digit. Thai runs are kept whole and also cut into
+// character bigrams, which matches Thai words without a dictionary.
+func analyze(text string) []term {
+	var terms []term
+	var run []rune
+	runThai := false
+
+	flush := func() {
+		if len(run) == 0 {
+			return
+		}
+		if len(run) > maxWordRunes {
+			run = run[:maxWordRunes]
+		}
+		size := gramSize
+		if runThai {
+			size = thaiGramSize
+		}
+		terms = append(terms, term{Word: encode(string(run)), Grams: grams(run, size), Thai: runThai})
+		run = run[:0]
+	}
+
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case r >= 0x0E50 && r <= 0x0E59: // Thai digits
+			r = '0' + (r - 0x0E50)
+			fallthrough
+		case !isThai(r) && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			if runThai {
+				flush()
+			}
+			runThai = false
+			run = append(run, r)
+		case isThai(r) && !isThaiSeparator(r):
+			if isThaiMark(r) {
+				continue
+			}
+			if !runThai {
+				flush()
+			}
+			runThai = true
+			run = append(run, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return terms
+}
+
+// grams returns the overlapping n-grams of a word. Words of exactly n runes are
+// their own single gram; shorter words have none and only match exactly.
+func grams(word []rune, n int) []string {
+	if len(word) < n {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var out []string
+	for i := 0; i+n <= len(word); i++ {
+		gram := encode(string(word[i : i+n]))
+		if !seen[gram] {
+			seen[gram] = true
+			out = append(out, gram)
+		}
+	}
+	return out
+}
+
+// encode turns a token into plain [a-z0-9] so Postgres' parser, the FTS5
+// tokenizer and LIKE all see the same single token
+func encode(token string) string {
+	for i := 0; i < len(token); i++ {
+		c := token[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return "u" + hex.EncodeToString([]byte(token))
+		}
+	}
+	return token
+}
+
+// tokens flattens terms into the unique words and grams written to the index
+func tokens(terms []term) []string {
+	seen := make(map[string]bool)
+	var out []string
+	add := func(token string) {
+		if token != "" && !seen[token] {
+			seen[token] = true
+			out = append(out, token)
+		}
+	}
+	for _, t := range terms {
+		add(t.Word)
+		for _, gram := range t.Grams {
+			add(gram)
+		}
+	}
+	return out
+}
+
+// indexText analyzes a field into the space separated tokens that are stored
+func indexText(text string) string {
+	return strings.Join(tokens(analyze(text)), " ")
+}
@@ -0,0 +1,141 @@
+package search
+
+import (
+	"strings"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// maxQueryTokens keeps very long queries from building huge match expressions
+const maxQueryTokens = 64
+
+func capTokens(tokens []string) []string {
+	if len(tokens) > maxQueryTokens {
+		return tokens[:maxQueryTokens]
+	}
+	return tokens
+}
+
+// postgresEngine keeps a weighted tsvector as a generated column of
+// product_search_documents, with a GIN index. The 'simple' configuration is
+// used because the tokens are already analyzed and must not be stemmed.
+type postgresEngine struct{}
+
+func (postgresEngine) Name() string { return "Postgres tsvector" }
+
+func (postgresEngine) Migrate(db *gorm.DB) error {
+	if err := db.Exec(`ALTER TABLE product_search_documents ADD COLUMN IF NOT EXISTS document tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', name), 'A') ||
+			setweight(to_tsvector('simple', brand), 'B') ||
+			setweight(to_tsvector('simple', category), 'C') ||
+			setweight(to_tsvector('simple', description), 'D')
+		) STORED`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`CREATE INDEX IF NOT EXISTS idx_product_search_document
+		ON product_search_documents USING GIN (document)`).Error
+}
+
+// Index is a no-op: the tsvector column is generated from the document row
+func (postgresEngine) Index(db *gorm.DB, doc *Document) error { return nil }
+
+func (postgresEngine) Remove(db *gorm.DB, productID uuid.UUID) error { return nil }
+
+func (postgresEngine) Candidates(db *gorm.DB, tokens []string, limit int) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	err := db.Raw(`SELECT product_id FROM product_search_documents, to_tsquery('simple', ?) AS query
+		WHERE document @@ query
+		ORDER BY ts_rank(document, query) DESC
+		LIMIT ?`, strings.Join(capTokens(tokens), " | "), limit).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// fts5Engine mirrors product_search_documents into an FTS5 table ranked with
+// bm25. The ascii tokenizer splits on the spaces between analyzed tokens.
+type fts5Engine struct{}
+
+func (fts5Engine) Name() string { return "SQLite FTS5" }
+
+func (fts5Engine) Migrate(db *gorm.DB) error {
+	if err := db.Exec(`CREATE VIRTUAL TABLE IF NOT EXISTS product_search_fts USING fts5(
+		product_id UNINDEXED, name, brand, category, description, tokenize = 'ascii')`).Error; err != nil {
+		return err
+	}
+
+	// Rebuild the mirror when it drifted, e.g. the binary was built without FTS5 for a while
+	var documents, indexed int64
+	if err := db.Model(&Document{}).Count(&documents).Error; err != nil {
+		return err
+	}
+	if err := db.Raw("SELECT COUNT(*) FROM product_search_fts").Scan(&indexed).Error; err != nil {
+		return err
+	}
+	if documents == indexed {
+		return nil
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM product_search_fts").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`INSERT INTO product_search_fts (product_id, name, brand, category, description)
+			SELECT product_id, name, brand, category, description FROM product_search_documents`).Error
+	})
+}
+
+func (e fts5Engine) Index(db *gorm.DB, doc *Document) error {
+	if err := e.Remove(db, doc.ProductID); err != nil {
+		return err
+	}
+	return db.Exec(`INSERT INTO product_search_fts (product_id, name, brand, category, description)
+		VALUES (?, ?, ?, ?, ?)`, doc.ProductID, doc.Name, doc.Brand, doc.Category, doc.Description).Error
+}
+
+func (fts5Engine) Remove(db *gorm.DB, productID uuid.UUID) error {
+	return db.Exec("DELETE FROM product_search_fts WHERE product_id = ?", productID).Error
+}
+
+func (fts5Engine) Candidates(db *gorm.DB, tokens []string, limit int) ([]uuid.UUID, error) {
+	terms := capTokens(tokens)
+	quoted := make([]string, len(terms))
+	for i, token := range terms {
+		quoted[i] = `"` + token + `"`
+	}
+
+	var ids []uuid.UUID
+	err := db.Raw(`SELECT product_id FROM product_search_fts
+		WHERE product_search_fts MATCH ?
+		ORDER BY bm25(product_search_fts, 0.0, 10.0, 5.0, 3.0, 1.0)
+		LIMIT ?`, strings.Join(quoted, " OR "), limit).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// likeEngine matches whole tokens with LIKE on product_search_documents. It
+// works on any database and is used when SQLite has no FTS5.
+type likeEngine struct{}
+
+func (likeEngine) Name() string { return "LIKE matching" }
+
+func (likeEngine) Migrate(db *gorm.DB) error { return nil }
+
+func (likeEngine) Index(db *gorm.DB, doc *Document) error { return nil }
+
+func (likeEngine) Remove(db *gorm.DB, productID uuid.UUID) error { return nil }
+
+func (likeEngine) Candidates(db *gorm.DB, tokens []string, limit int) ([]uuid.UUID, error) {
+	terms := capTokens(tokens)
+	conditions := make([]string, len(terms))
+	args := make([]interface{}, len(terms))
+	for i, token := range terms {
+		conditions[i] = "(' ' || name || ' ' || brand || ' ' || category || ' ' || description || ' ') LIKE ?"
+		args[i] = "% " + token + " %"
+	}
+
+	var ids []uuid.UUID
+	err := db.Model(&Document{}).Where(strings.Join(conditions, " OR "), args...).
+		Limit(limit).Pluck("product_id", &ids).Error
+	return ids, err
+}
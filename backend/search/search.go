@@ -0,0 +1,281 @@
+// Package search is the product full-text search. Products are analyzed in Go
+// into the same tokens for every database, then indexed with tsvector on
+// Postgres, FTS5 on SQLite, or plain LIKE matching when neither is available.
+package search
+
+import (
+	"errors"
+	"log"
+	"pet-food-ecommerce/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// MaxResults caps how many matches a search returns
+const MaxResults = 500
+
+// minCoverage is the share of a query that must match a product. Below 1 so a
+// typo that breaks a few n-grams still finds the product. Thai words share
+// more bigrams with unrelated words, so they need a closer match.
+const (
+	minCoverage     = 0.5
+	minThaiCoverage = 0.7
+)
+
+// Field weights: a hit in the name ranks above one in the description
+var fieldWeights = [...]float64{1.0, 0.8, 0.6, 0.3} // name, brand, category, description
+
+var errNotSetup = errors.New("search is not set up")
+
+// Document holds a product's analyzed tokens, one column per field
+type Document struct {
+	ProductID   uuid.UUID `gorm:"type:uuid;primary_key"`
+	Name        string    `gorm:"type:text;not null;default:''"`
+	Brand       string    `gorm:"type:text;not null;default:''"`
+	Category    string    `gorm:"type:text;not null;default:''"`
+	Description string    `gorm:"type:text;not null;default:''"`
+	Version     int       `gorm:"not null;default:0"`
+	UpdatedAt   time.Time
+}
+
+// TableName keeps the index next to the products table
+func (Document) TableName() string {
+	return "product_search_documents"
+}
+
+func (d *Document) fields() [4]string {
+	return [4]string{d.Name, d.Brand, d.Category, d.Description}
+}
+
+// Hit is one matching product and its relevance; higher is better
+type Hit struct {
+	ProductID uuid.UUID
+	Score     float64
+}
+
+// Engine is the database specific part of search: keeping the index in step
+// with product_search_documents and finding candidate products for tokens
+type Engine interface {
+	Name() string
+	Migrate(db *gorm.DB) error
+	Index(db *gorm.DB, doc *Document) error
+	Remove(db *gorm.DB, productID uuid.UUID) error
+	Candidates(db *gorm.DB, tokens []string, limit int) ([]uuid.UUID, error)
+}
+
+var engine Engine
+
+// Setup picks the engine for the database, creates its tables and indexes
+// products that are missing or were analyzed by an older version
+func Setup(db *gorm.DB) error {
+	if err := db.AutoMigrate(&Document{}); err != nil {
+		return err
+	}
+
+	selected, err := engineFor(db)
+	if err != nil {
+		return err
+	}
+	if err := selected.Migrate(db); err != nil {
+		return err
+	}
+	engine = selected
+	log.Printf("Product search using %s", engine.Name())
+
+	var stale []uuid.UUID
+	if err := db.Model(&models.Product{}).
+		Joins("LEFT JOIN product_search_documents d ON d.product_id = products.id").
+		Where("d.product_id IS NULL OR d.version <> ?", analyzerVersion).
+		Pluck("products.id", &stale).Error; err != nil {
+		return err
+	}
+	for _, id := range stale {
+		if err := IndexProduct(db, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func engineFor(db *gorm.DB) (Engine, error) {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return postgresEngine{}, nil
+	case "sqlite":
+		var fts5 int
+		if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error; err != nil {
+			return nil, err
+		}
+		if fts5 == 1 {
+			return fts5Engine{}, nil
+		}
+		log.Println("⚠️  SQLite was built without FTS5 (build with -tags sqlite_fts5); product search falls back to LIKE")
+	}
+	return likeEngine{}, nil
+}
+
+// IndexProduct (re)indexes a product's name, brand, description and category
+func IndexProduct(db *gorm.DB, productID uuid.UUID) error {
+	if engine == nil {
+		return errNotSetup
+	}
+
+	var product models.Product
+	if err := db.Preload("Category").Where("id = ?", productID).First(&product).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return RemoveProduct(db, productID)
+		}
+		return err
+	}
+
+	doc := Document{
+		ProductID:   product.ID,
+		Name:        indexText(product.Name),
+		Brand:       indexText(product.Brand),
+		Category:    indexText(product.Category.Name),
+		Description: indexText(product.Description),
+		Version:     analyzerVersion,
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(&doc).Error; err != nil {
+			return err
+		}
+		return engine.Index(tx, &doc)
+	})
+}
+
+// IndexCategory reindexes every product in a category after it is renamed
+func IndexCategory(db *gorm.DB, categoryID uuid.UUID) error {
+	var productIDs []uuid.UUID
+	if err := db.Model(&models.Product{}).Where("category_id = ?", categoryID).Pluck("id", &productIDs).Error; err != nil {
+		return err
+	}
+	for _, id := range productIDs {
+		if err := IndexProduct(db, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RemoveProduct drops a product from the index
+func RemoveProduct(db *gorm.DB, productID uuid.UUID) error {
+	if engine == nil {
+		return errNotSetup
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", productID).Delete(&Document{}).Error; err != nil {
+			return err
+		}
+		return engine.Remove(tx, productID)
+	})
+}
+
+// Search returns the products matching query, best match first. The engine
+// finds candidates sharing any token; they are then scored here so ranking and
+// typo tolerance are the same on every database.
+func Search(db *gorm.DB, query string, limit int) ([]Hit, error) {
+	if engine == nil {
+		return nil, errNotSetup
+	}
+	if limit <= 0 || limit > MaxResults {
+		limit = MaxResults
+	}
+
+	terms := analyze(query)
+	queryTokens := tokens(terms)
+	if len(queryTokens) == 0 {
+		return nil, nil
+	}
+
+	candidates, err := engine.Candidates(db, queryTokens, limit*4)
+	if err != nil || len(candidates) == 0 {
+		return nil, err
+	}
+
+	var docs []Document
+	if err := db.Where("product_id IN ?", candidates).Find(&docs).Error; err != nil {
+		return nil, err
+	}
+
+	hits := make([]Hit, 0, len(docs))
+	for i := range docs {
+		if score, ok := scoreDocument(terms, &docs[i]); ok {
+			hits = append(hits, Hit{ProductID: docs[i].ProductID, Score: score})
+		}
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].ProductID.String() < hits[j].ProductID.String()
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits, nil
+}
+
+// scoreDocument scores each query term by its best field: 1 for the exact
+// word, otherwise the share of its n-grams found. The product matches when the
+// terms are covered, on average, at least as well as they require.
+func scoreDocument(terms []term, doc *Document) (float64, bool) {
+	var fieldTokens [4]map[string]bool
+	for i, field := range doc.fields() {
+		fieldTokens[i] = make(map[string]bool)
+		for _, token := range strings.Fields(field) {
+			fieldTokens[i][token] = true
+		}
+	}
+
+	var score, coverage, required float64
+	for _, t := range terms {
+		if t.Thai {
+			required += minThaiCoverage
+		} else {
+			required += minCoverage
+		}
+
+		var best, covered float64
+		for i, set := range fieldTokens {
+			c, exact := termCoverage(t, set)
+			if c > covered {
+				covered = c
+			}
+			w := c * fieldWeights[i]
+			if !exact {
+				w *= 0.9 // a partial hit never ranks quite as high as the word itself
+			}
+			if w > best {
+				best = w
+			}
+		}
+		score += best
+		coverage += covered
+	}
+
+	if coverage < required {
+		return 0, false
+	}
+	return score / float64(len(terms)), true
+}
+
+func termCoverage(t term, set map[string]bool) (float64, bool) {
+	if set[t.Word] {
+		return 1, true
+	}
+	if len(t.Grams) == 0 {
+		return 0, false
+	}
+	matched := 0
+	for _, gram := range t.Grams {
+		if set[gram] {
+			matched++
+		}
+	}
+	return float64(matched) / float64(len(t.Grams)), false
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Config connects to AWS S3 or a compatible service such as MinIO, R2 or Spaces
+type S3Config struct {
+	Endpoint  string // host[:port], defaults to AWS
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	UseSSL    bool
+}
+
+// S3 keeps files in an S3-compatible bucket
+type S3 struct {
+	client *minio.Client
+	bucket string
+}
+
+// NewS3 connects to the bucket, checking that it exists
+func NewS3(cfg S3Config) (*S3, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("S3_BUCKET is required")
+	}
+	endpoint := cfg.Endpoint
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+	// Accept a URL as well as a bare host
+	if rest, ok := strings.CutPrefix(endpoint, "https://"); ok {
+		endpoint, cfg.UseSSL = rest, true
+	} else if rest, ok := strings.CutPrefix(endpoint, "http://"); ok {
+		endpoint, cfg.UseSSL = rest, false
+	}
+
+	client, err := minio.New(strings.TrimSuffix(endpoint, "/"), &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	exists, err := client.BucketExists(context.Background(), cfg.Bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("S3 bucket " + cfg.Bucket + " does not exist")
+	}
+	return &S3{client: client, bucket: cfg.Bucket}, nil
+}
+
+func (s *S3) Name() string { return "S3 bucket " + s.bucket + " at " + s.client.EndpointURL().Host }
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	key, ok := CleanKey(key)
+	if !ok {
+		return ErrNotFound
+	}
+	_, err := s.client.PutObject(ctx, s.bucket, key, r, size, minio.PutObjectOptions{
+		ContentType:  contentType,
+		CacheControl: CacheControl,
+	})
+	return err
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadSeekCloser, Info, error) {
+	key, ok := CleanKey(key)
+	if !ok {
+		return nil, Info{}, ErrNotFound
+	}
+	object, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, Info{}, s3Error(err)
+	}
+	stat, err := object.Stat()
+	if err != nil {
+		object.Close()
+		return nil, Info{}, s3Error(err)
+	}
+	return object, Info{Size: stat.Size, ContentType: stat.ContentType, ModTime: stat.LastModified}, nil
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	key, ok := CleanKey(key)
+	if !ok {
+		return ErrNotFound
+	}
+	return s3Error(s.client.RemoveObject(ctx, s.bucket, key, minio.RemoveObjectOptions{}))
+}
+
+func s3Error(err error) error {
+	if err != nil && minio.ToErrorResponse(err).Code == "NoSuchKey" {
+		return ErrNotFound
+	}
+	return err
+}
@@ -0,0 +1,126 @@
+// Package storage keeps public uploads such as product images, on local disk
+// or in an S3-compatible bucket. Files are addressed by slash separated keys.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// CacheControl lets clients and CDNs keep a file for good: a key never gets
+// new content, so a changed file is stored under a new key
+const CacheControl = "public, max-age=31536000, immutable"
+
+// ErrNotFound is returned when no file is stored under a key
+var ErrNotFound = errors.New("file not found")
+
+var errNotSetup = errors.New("storage is not set up")
+
+// Info describes a stored file
+type Info struct {
+	Size        int64
+	ContentType string
+	ModTime     time.Time
+}
+
+// Backend is where files are kept
+type Backend interface {
+	Name() string
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, Info, error)
+	Delete(ctx context.Context, key string) error
+}
+
+var (
+	backend   Backend
+	publicURL string
+)
+
+// Setup picks the backend from STORAGE_DRIVER: local (the default) keeps
+// files under STORAGE_LOCAL_DIR, s3 in the S3_BUCKET bucket. Files are linked
+// through STORAGE_PUBLIC_URL, such as a CDN, or else served by the API under /uploads.
+func Setup() error {
+	var err error
+	switch driver := os.Getenv("STORAGE_DRIVER"); driver {
+	case "", "local":
+		dir := os.Getenv("STORAGE_LOCAL_DIR")
+		if dir == "" {
+			dir = "uploads/public"
+		}
+		backend, err = NewLocal(dir)
+	case "s3":
+		backend, err = NewS3(S3Config{
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Region:    os.Getenv("S3_REGION"),
+			Bucket:    os.Getenv("S3_BUCKET"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+			UseSSL:    os.Getenv("S3_USE_SSL") != "false",
+		})
+	default:
+		return errors.New("unknown STORAGE_DRIVER " + driver)
+	}
+	if err != nil {
+		return err
+	}
+
+	publicURL = strings.TrimSuffix(os.Getenv("STORAGE_PUBLIC_URL"), "/")
+	log.Printf("File storage using %s", backend.Name())
+	return nil
+}
+
+// CleanKey normalizes a key, reporting false for one that is empty or tries to
+// climb out of the storage root
+func CleanKey(key string) (string, bool) {
+	if key == "" || strings.Contains(key, "\\") {
+		return "", false
+	}
+	cleaned := path.Clean("/" + key)[1:]
+	if cleaned == "" || cleaned != strings.TrimPrefix(key, "/") {
+		return "", false
+	}
+	return cleaned, true
+}
+
+// URL is where clients fetch the file stored under key
+func URL(key string) string {
+	if publicURL != "" {
+		return publicURL + "/" + key
+	}
+	return "/uploads/" + key
+}
+
+// Put stores a file under key, replacing any file already there
+func Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if backend == nil {
+		return errNotSetup
+	}
+	return backend.Put(ctx, key, r, size, contentType)
+}
+
+// Open reads the file stored under key; the caller closes it
+func Open(ctx context.Context, key string) (io.ReadSeekCloser, Info, error) {
+	if backend == nil {
+		return nil, Info{}, errNotSetup
+	}
+	return backend.Open(ctx, key)
+}
+
+// Delete removes the files stored under keys, ignoring ones already gone
+func Delete(ctx context.Context, keys ...string) error {
+	if backend == nil {
+		return errNotSetup
+	}
+	for _, key := range keys {
+		if err := backend.Delete(ctx, key); err != nil && !errors.Is(err, ErrNotFound) {
+			return err
+		}
+	}
+	return nil
+}